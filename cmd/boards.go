@@ -0,0 +1,268 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"kantext/internal/config"
+	"kantext/internal/handlers"
+	"kantext/internal/services"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// board is one project served by the web server. Each board has its own
+// TASKS.md, test runner settings, AI queue and WebSocket clients.
+type board struct {
+	name         string
+	workDir      string
+	tasksFile    string
+	wsHub        *services.WSHub
+	taskStore    *services.TaskStore
+	claudeRunner *services.ClaudeRunner
+	testJobs     *services.TestJobManager
+	fileWatcher  *services.FileWatcher
+	router       chi.Router
+}
+
+// boardFlags collects repeated -board name=path flags
+type boardFlags []config.BoardConfig
+
+func (f *boardFlags) String() string {
+	names := make([]string, len(*f))
+	for i, b := range *f {
+		names[i] = b.Name + "=" + b.WorkDir
+	}
+	return strings.Join(names, ",")
+}
+
+func (f *boardFlags) Set(value string) error {
+	b, err := config.ParseBoardFlag(value)
+	if err != nil {
+		return err
+	}
+	*f = append(*f, b)
+	return nil
+}
+
+// expandWorkDir expands a leading ~ and makes a working directory absolute
+func expandWorkDir(workDir string) (string, error) {
+	if len(workDir) > 0 && workDir[0] == '~' {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		workDir = filepath.Join(home, workDir[1:])
+	}
+	return filepath.Abs(workDir)
+}
+
+// startBoard creates the services for a board, starts watching its TASKS.md
+// and builds its routes
+func startBoard(cfg config.BoardConfig, durable bool) (*board, error) {
+	workDir, err := expandWorkDir(cfg.WorkDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve working directory for board %s: %w", cfg.Name, err)
+	}
+	b := &board{
+		name:    cfg.Name,
+		workDir: workDir,
+	}
+
+	// Initialize WebSocket hub (must be before other services)
+	b.wsHub = services.NewWSHub()
+	go b.wsHub.Run()
+
+	// Initialize services
+	b.taskStore = services.NewTaskStoreWithFile(workDir, cfg.TasksFile)
+	b.taskStore.SetDurableWrites(durable)
+	b.tasksFile = b.taskStore.GetTasksFile()
+	testRunner := services.NewTestRunnerWithStore(b.taskStore)
+	b.claudeRunner = services.NewClaudeRunner(b.wsHub, workDir)
+	b.testJobs = services.NewTestJobManager(b.taskStore, testRunner, b.wsHub)
+
+	// When Claude finishes a task, clean up the queue
+	b.claudeRunner.SetOnComplete(func() {
+		log.Printf("[%s] Claude completed task, cleaning up queue...", b.name)
+		if err := b.taskStore.StopCurrentTask(); err != nil {
+			log.Printf("[%s] Error cleaning up queue on task completion: %v", b.name, err)
+		}
+	})
+
+	// Initialize file watcher for real-time updates
+	b.fileWatcher, err = services.NewFileWatcher(b.tasksFile, b.wsHub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize file watcher for board %s: %w", b.name, err)
+	}
+	// When file changes, merge it into the TaskStore before notifying clients.
+	// Edits that conflict with unsaved in-memory changes are broadcast to clients.
+	b.taskStore.SetOnMergeConflicts(b.wsHub.NotifyMergeConflicts)
+	b.taskStore.SetOnUndoRedo(b.wsHub.NotifyUndoRedo)
+	b.fileWatcher.SetOnFileChange(func() {
+		log.Printf("[%s] Merging external changes into TaskStore...", b.name)
+		if _, err := b.taskStore.ReloadFromDisk(); err != nil {
+			log.Printf("[%s] Failed to reload tasks: %v", b.name, err)
+		}
+		// The include setting may have changed
+		b.watchBoardFiles()
+	})
+	if err := b.fileWatcher.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start file watcher for board %s: %w", b.name, err)
+	}
+	b.watchBoardFiles()
+
+	go b.pollCommits()
+
+	// Initialize handlers
+	apiHandler := handlers.NewAPIHandler(b.taskStore, b.testJobs, b.claudeRunner)
+	wsHandler := handlers.NewWSHandler(b.wsHub)
+	pageHandler, err := handlers.NewPageHandler(b.taskStore)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize page handler: %w", err)
+	}
+	b.router = boardRoutes(apiHandler, wsHandler, pageHandler)
+	return b, nil
+}
+
+// watchBoardFiles watches the files holding the board besides the tasks
+// file, such as included files, so edits to them are merged like edits to
+// TASKS.md
+func (b *board) watchBoardFiles() {
+	for _, path := range b.taskStore.Files() {
+		if err := b.fileWatcher.WatchFile(path); err != nil {
+			log.Printf("[%s] Failed to watch %s: %v", b.name, path, err)
+		}
+	}
+}
+
+// pollCommits checks git for new commits and branches, which are linked to
+// the tasks they mention and may close them (see git.close_column). Commits
+// land in git without touching TASKS.md, so the file watcher does not see them.
+func (b *board) pollCommits() {
+	ticker := time.NewTicker(commitPollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		moved, err := b.taskStore.SyncCommits()
+		if err != nil {
+			log.Printf("[%s] Failed to close tasks from commits: %v", b.name, err)
+			continue
+		}
+		if len(moved) > 0 {
+			log.Printf("[%s] Closed %d task(s) from commits: %s", b.name, len(moved), strings.Join(moved, ", "))
+			b.wsHub.NotifyTasksUpdated()
+		}
+	}
+}
+
+// stop stops the board's Claude subprocess, test jobs and file watcher
+func (b *board) stop() {
+	b.claudeRunner.Stop() // Stop Claude subprocess if running
+	b.testJobs.Stop()
+	b.fileWatcher.Stop()
+	// Save and commit what is still pending once nothing else can change the board
+	if err := b.taskStore.Close(); err != nil {
+		log.Printf("Failed to save board %s: %v", b.name, err)
+	}
+}
+
+// boardRoutes builds the page, WebSocket and API routes of a board. They are
+// mounted under /b/{board}/, and the first board is also served from /.
+func boardRoutes(apiHandler *handlers.APIHandler, wsHandler *handlers.WSHandler, pageHandler *handlers.PageHandler) chi.Router {
+	r := chi.NewRouter()
+
+	// Page routes
+	r.Get("/", pageHandler.ServeBoard)
+
+	// WebSocket endpoint
+	r.Get("/ws", wsHandler.ServeWS)
+
+	// API routes
+	r.Route("/api", func(r chi.Router) {
+		// Health route
+		r.Get("/health", apiHandler.GetHealth)
+
+		// Config routes
+		r.Get("/config", apiHandler.GetConfig)
+		r.Put("/config", apiHandler.UpdateConfig)
+
+		// Undo routes
+		r.Post("/undo", apiHandler.Undo)
+		r.Post("/redo", apiHandler.Redo)
+
+		// Archive routes
+		r.Get("/archive", apiHandler.ListArchive)
+		r.Post("/archive/purge", apiHandler.PurgeArchive)
+		r.Delete("/archive/{id}", apiHandler.PurgeArchivedTask)
+
+		// Branch routes
+		r.Get("/branches", apiHandler.ListBranches)
+		r.Get("/branches/board", apiHandler.GetBranchBoard)
+		r.Get("/branches/compare", apiHandler.CompareBranches)
+		r.Get("/branches/merge-preview", apiHandler.PreviewMerge)
+
+		// Task routes
+		r.Get("/tasks", apiHandler.ListTasks)
+		r.Post("/tasks", apiHandler.CreateTask)
+		r.Get("/tasks/{id}", apiHandler.GetTask)
+		r.Put("/tasks/{id}", apiHandler.UpdateTask)
+		r.Delete("/tasks/{id}", apiHandler.DeleteTask)
+		r.Post("/tasks/{id}/restore", apiHandler.RestoreTask)
+		r.Post("/tasks/{id}/run", apiHandler.RunTest)
+		r.Get("/tasks/{id}/status", apiHandler.GetTaskStatus)
+		r.Put("/tasks/{id}/reorder", apiHandler.ReorderTask)
+		r.Get("/tasks/{id}/children", apiHandler.GetChildren)
+		r.Get("/tasks/{id}/history", apiHandler.GetHistory)
+		r.Get("/tasks/{id}/test-history", apiHandler.GetTestHistory)
+		r.Get("/tasks/{id}/commits", apiHandler.GetCommits)
+		r.Get("/tasks/{id}/criteria", apiHandler.ListCriteria)
+		r.Post("/tasks/{id}/criteria", apiHandler.AddCriterion)
+		r.Put("/tasks/{id}/criteria/{item}", apiHandler.UpdateCriterion)
+		r.Delete("/tasks/{id}/criteria/{item}", apiHandler.DeleteCriterion)
+
+		// Test job routes
+		r.Get("/jobs/{id}", apiHandler.GetTestJob)
+		r.Delete("/jobs/{id}", apiHandler.CancelTestJob)
+
+		// Column routes
+		r.Get("/columns", apiHandler.ListColumns)
+		r.Post("/columns", apiHandler.CreateColumn)
+		r.Put("/columns/{slug}", apiHandler.UpdateColumn)
+		r.Delete("/columns/{slug}", apiHandler.DeleteColumn)
+		r.Put("/columns/reorder", apiHandler.ReorderColumns)
+
+		// AI Queue routes
+		r.Get("/ai-queue", apiHandler.GetAIQueue)
+		r.Post("/ai-queue", apiHandler.AddToAIQueue)
+		r.Delete("/ai-queue/{taskId}", apiHandler.RemoveFromAIQueue)
+		r.Put("/ai-queue/reorder", apiHandler.ReorderAIQueue)
+		r.Post("/ai-queue/start", apiHandler.StartAITask)
+		r.Post("/ai-queue/stop", apiHandler.StopAITask)
+		r.Get("/ai-session", apiHandler.GetAISession)
+		r.Post("/ai-session/message", apiHandler.SendAIMessage)
+	})
+
+	return r
+}
+
+// serveBoardList lists the boards hosted by the server
+func serveBoardList(boards []*board) http.HandlerFunc {
+	type boardInfo struct {
+		Name    string `json:"name"`
+		WorkDir string `json:"working_directory"`
+		URL     string `json:"url"`
+	}
+	list := make([]boardInfo, len(boards))
+	for i, b := range boards {
+		list[i] = boardInfo{Name: b.name, WorkDir: b.workDir, URL: "/b/" + b.name + "/"}
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+	}
+}
@@ -9,10 +9,12 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
-	"kantext/internal/handlers"
+	"kantext/internal/config"
 	"kantext/internal/mcp"
 	"kantext/internal/services"
 
@@ -20,6 +22,9 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 )
 
+// commitPollInterval is how often git is checked for commits that close tasks
+const commitPollInterval = 30 * time.Second
+
 func main() {
 	// Check if running in MCP mode (first argument is "mcp")
 	if len(os.Args) > 1 && os.Args[0] != "-" && os.Args[1] == "mcp" {
@@ -29,79 +34,54 @@ func main() {
 
 	// Parse command line flags for web server mode
 	workDirFlag := flag.String("workdir", "", "Working directory containing TASKS.md (default: current directory)")
+	tasksFileFlag := flag.String("tasks-file", config.DefaultTasksFileName, "Tasks file of each board, relative to its working directory unless absolute")
 	port := flag.String("port", "8081", "Port to run the server on")
+	durable := flag.Bool("durable", false, "Write TASKS.md synchronously and fail requests whose changes cannot be saved")
+	boardsFile := flag.String("boards", "", "YAML file listing boards to serve (see README)")
+	var extraBoards boardFlags
+	flag.Var(&extraBoards, "board", "Serve another board, as name=path (repeatable)")
 	flag.Parse()
 
-	// Determine working directory
-	var workDir string
-	var err error
-	if *workDirFlag != "" {
-		workDir = *workDirFlag
-		// Expand ~ to home directory if present
-		if len(workDir) > 0 && workDir[0] == '~' {
-			home, err := os.UserHomeDir()
-			if err != nil {
-				log.Fatalf("Failed to get home directory: %v", err)
-			}
-			workDir = filepath.Join(home, workDir[1:])
+	// Collect boards: -workdir (or the current directory when no boards are
+	// configured) comes first, then the boards file, then -board flags
+	var boardConfigs []config.BoardConfig
+	if *boardsFile != "" {
+		fromFile, err := config.LoadBoardsFile(*boardsFile)
+		if err != nil {
+			log.Fatalf("Failed to load boards: %v", err)
 		}
-		// Convert to absolute path if relative
-		if !filepath.IsAbs(workDir) {
-			workDir, err = filepath.Abs(workDir)
+		boardConfigs = append(boardConfigs, fromFile...)
+	}
+	boardConfigs = append(boardConfigs, extraBoards...)
+	if *workDirFlag != "" || len(boardConfigs) == 0 {
+		workDir := *workDirFlag
+		if workDir == "" {
+			var err error
+			workDir, err = os.Getwd()
 			if err != nil {
-				log.Fatalf("Failed to resolve working directory: %v", err)
+				log.Fatalf("Failed to get working directory: %v", err)
 			}
+			log.Printf("Warning: No -workdir provided, using current directory: %s", workDir)
 		}
-	} else {
-		// Fall back to current working directory
-		workDir, err = os.Getwd()
-		if err != nil {
-			log.Fatalf("Failed to get working directory: %v", err)
-		}
-		log.Printf("Warning: No -workdir provided, using current directory: %s", workDir)
+		boardConfigs = append([]config.BoardConfig{{Name: config.BoardNameFor(workDir), WorkDir: workDir}}, boardConfigs...)
 	}
-
-	tasksFile := filepath.Join(workDir, "TASKS.md")
-
-	// Initialize WebSocket hub (must be before other services)
-	wsHub := services.NewWSHub()
-	go wsHub.Run()
-
-	// Initialize services
-	taskStore := services.NewTaskStore(workDir)
-	testRunner := services.NewTestRunnerWithStore(taskStore)
-	claudeRunner := services.NewClaudeRunner(wsHub, workDir)
-
-	// When Claude finishes a task, clean up the queue
-	claudeRunner.SetOnComplete(func() {
-		log.Println("Claude completed task, cleaning up queue...")
-		if err := taskStore.StopCurrentTask(); err != nil {
-			log.Printf("Error cleaning up queue on task completion: %v", err)
-		}
-	})
-
-	// Initialize file watcher for real-time updates
-	fileWatcher, err := services.NewFileWatcher(tasksFile, wsHub)
-	if err != nil {
-		log.Fatalf("Failed to initialize file watcher: %v", err)
+	if err := config.ValidateBoards(boardConfigs); err != nil {
+		log.Fatalf("Invalid boards: %v", err)
 	}
-	// When file changes, reload TaskStore before notifying clients
-	fileWatcher.SetOnFileChange(func() {
-		log.Println("Reloading TaskStore from file...")
-		if err := taskStore.Load(); err != nil {
-			log.Printf("Failed to reload tasks: %v", err)
+	for i := range boardConfigs {
+		if boardConfigs[i].TasksFile == "" {
+			boardConfigs[i].TasksFile = *tasksFileFlag
 		}
-	})
-	if err := fileWatcher.Start(); err != nil {
-		log.Fatalf("Failed to start file watcher: %v", err)
 	}
 
-	// Initialize handlers
-	apiHandler := handlers.NewAPIHandler(taskStore, testRunner, claudeRunner)
-	wsHandler := handlers.NewWSHandler(wsHub)
-	pageHandler, err := handlers.NewPageHandler(taskStore)
-	if err != nil {
-		log.Fatalf("Failed to initialize page handler: %v", err)
+	// Start each board's services
+	boards := make([]*board, 0, len(boardConfigs))
+	for _, cfg := range boardConfigs {
+		b, err := startBoard(cfg, *durable)
+		if err != nil {
+			log.Fatalf("Failed to start board: %v", err)
+		}
+		boards = append(boards, b)
 	}
 
 	// Setup router
@@ -116,45 +96,12 @@ func main() {
 	fileServer := http.FileServer(http.Dir("web/static"))
 	r.Handle("/static/*", http.StripPrefix("/static/", fileServer))
 
-	// Page routes
-	r.Get("/", pageHandler.ServeBoard)
-
-	// WebSocket endpoint
-	r.Get("/ws", wsHandler.ServeWS)
-
-	// API routes
-	r.Route("/api", func(r chi.Router) {
-		// Config routes
-		r.Get("/config", apiHandler.GetConfig)
-		r.Put("/config", apiHandler.UpdateConfig)
-
-		// Task routes
-		r.Get("/tasks", apiHandler.ListTasks)
-		r.Post("/tasks", apiHandler.CreateTask)
-		r.Get("/tasks/{id}", apiHandler.GetTask)
-		r.Put("/tasks/{id}", apiHandler.UpdateTask)
-		r.Delete("/tasks/{id}", apiHandler.DeleteTask)
-		r.Post("/tasks/{id}/run", apiHandler.RunTest)
-		r.Get("/tasks/{id}/status", apiHandler.GetTaskStatus)
-		r.Put("/tasks/{id}/reorder", apiHandler.ReorderTask)
-
-		// Column routes
-		r.Get("/columns", apiHandler.ListColumns)
-		r.Post("/columns", apiHandler.CreateColumn)
-		r.Put("/columns/{slug}", apiHandler.UpdateColumn)
-		r.Delete("/columns/{slug}", apiHandler.DeleteColumn)
-		r.Put("/columns/reorder", apiHandler.ReorderColumns)
-
-		// AI Queue routes
-		r.Get("/ai-queue", apiHandler.GetAIQueue)
-		r.Post("/ai-queue", apiHandler.AddToAIQueue)
-		r.Delete("/ai-queue/{taskId}", apiHandler.RemoveFromAIQueue)
-		r.Put("/ai-queue/reorder", apiHandler.ReorderAIQueue)
-		r.Post("/ai-queue/start", apiHandler.StartAITask)
-		r.Post("/ai-queue/stop", apiHandler.StopAITask)
-		r.Get("/ai-session", apiHandler.GetAISession)
-		r.Post("/ai-session/message", apiHandler.SendAIMessage)
-	})
+	// Board routes: every board under /b/{board}/, the first also at /
+	r.Get("/boards", serveBoardList(boards))
+	for _, b := range boards {
+		r.Mount("/b/"+b.name, b.router)
+	}
+	r.Mount("/", boards[0].router)
 
 	// Create server
 	server := &http.Server{
@@ -172,12 +119,17 @@ func main() {
 		<-sigChan
 
 		log.Println("Shutting down server...")
-		claudeRunner.Stop() // Stop Claude subprocess if running
-		fileWatcher.Stop()
+		for _, b := range boards {
+			b.stop()
+		}
 		server.Close()
 	}()
 
 	// Start server
+	var boardLines strings.Builder
+	for _, b := range boards {
+		fmt.Fprintf(&boardLines, "║    %s: http://localhost:%s/b/%s/ (%s)\n", b.name, *port, b.name, b.tasksFile)
+	}
 	fmt.Printf(`
 ╔════════════════════════════════════════════════════════════════╗
 ║                       Kantext Web Server                       ║
@@ -187,9 +139,10 @@ func main() {
 ║  WebSocket endpoint: ws://localhost:%s/ws
 ║  Working directory: %s
 ║  Tasks file: %s
-║  Real-time updates: ENABLED
+║  Boards:
+%s║  Real-time updates: ENABLED
 ╚════════════════════════════════════════════════════════════════╝
-`, *port, *port, workDir, tasksFile)
+`, *port, *port, boards[0].workDir, boards[0].tasksFile, boardLines.String())
 
 	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		log.Fatalf("Server error: %v", err)
@@ -201,6 +154,8 @@ func runMCPServer() {
 	// Parse MCP-specific flags (skip "mcp" argument)
 	mcpFlags := flag.NewFlagSet("mcp", flag.ExitOnError)
 	workDirFlag := mcpFlags.String("workdir", "", "Working directory containing TASKS.md (required)")
+	tasksFileFlag := mcpFlags.String("tasks-file", config.DefaultTasksFileName, "Tasks file, relative to the working directory unless absolute")
+	durable := mcpFlags.Bool("durable", true, "Write TASKS.md synchronously before reporting tool success")
 	mcpFlags.Parse(os.Args[2:])
 
 	if *workDirFlag == "" {
@@ -226,28 +181,42 @@ func runMCPServer() {
 		workDir = absPath
 	}
 
-	tasksFile := filepath.Join(workDir, "TASKS.md")
-
-	// Check if tasks file exists, create if not
-	if _, err := os.Stat(tasksFile); os.IsNotExist(err) {
-		initialContent := `---
----
-# Kantext Tasks
-
-## Inbox
+	tasksFile := *tasksFileFlag
+	if !filepath.IsAbs(tasksFile) {
+		tasksFile = filepath.Join(workDir, tasksFile)
+	}
 
-## In Progress
+	// Initialize services (the tasks file is created if it does not exist)
+	taskStore := services.NewTaskStoreWithFile(workDir, tasksFile)
+	taskStore.SetDurableWrites(*durable)
+	testRunner := services.NewTestRunnerWithStore(taskStore)
 
-## Done
-`
-		if err := os.WriteFile(tasksFile, []byte(initialContent), 0644); err != nil {
-			log.Fatalf("Failed to create tasks file: %v", err)
-		}
+	// Save and commit what is still pending when the client goes away, by
+	// closing stdin or with a signal
+	var closeOnce sync.Once
+	closeStore := func() {
+		closeOnce.Do(func() {
+			if err := taskStore.Close(); err != nil {
+				log.Printf("Failed to save tasks: %v", err)
+			}
+		})
 	}
+	go func() {
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+		<-sigChan
+		closeStore()
+		os.Exit(0)
+	}()
 
-	// Initialize services
-	taskStore := services.NewTaskStore(workDir)
-	testRunner := services.NewTestRunnerWithStore(taskStore)
+	// Commits land in git without touching TASKS.md, so check for them
+	go func() {
+		for range time.Tick(commitPollInterval) {
+			if _, err := taskStore.SyncCommits(); err != nil {
+				log.Printf("Failed to sync commits: %v", err)
+			}
+		}
+	}()
 
 	// Initialize tool handler
 	toolHandler := mcp.NewToolHandler(taskStore, testRunner)
@@ -289,7 +258,9 @@ func runMCPServer() {
 	})
 
 	// Run the MCP server
-	if err := mcpServer.Run(); err != nil {
+	err := mcpServer.Run()
+	closeStore()
+	if err != nil {
 		log.Fatalf("MCP Server error: %v", err)
 	}
 }
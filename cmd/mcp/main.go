@@ -5,18 +5,28 @@ import (
 	"flag"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
 
+	"kantext/internal/config"
 	"kantext/internal/mcp"
 	"kantext/internal/services"
 )
 
+// commitPollInterval is how often git is checked for commits that mention tasks
+const commitPollInterval = 30 * time.Second
+
 func main() {
 	// Disable logging to stderr as it interferes with MCP protocol
 	log.SetOutput(os.Stderr)
 
 	// Parse command line flags
 	workDirFlag := flag.String("workdir", "", "Working directory containing TASKS.md (required)")
+	tasksFileFlag := flag.String("tasks-file", config.DefaultTasksFileName, "Tasks file, relative to the working directory unless absolute")
+	durable := flag.Bool("durable", true, "Write TASKS.md synchronously before reporting tool success")
 	flag.Parse()
 
 	if *workDirFlag == "" {
@@ -42,30 +52,43 @@ func main() {
 		workDir = absPath
 	}
 
-	tasksFile := filepath.Join(workDir, "TASKS.md")
-
-	// Check if tasks file exists, create if not
-	if _, err := os.Stat(tasksFile); os.IsNotExist(err) {
-		// Create initial tasks file with YAML front matter
-		initialContent := `---
----
-# Kantext Tasks
-
-## Inbox
-
-## In Progress
-
-## Done
-`
-		if err := os.WriteFile(tasksFile, []byte(initialContent), 0644); err != nil {
-			log.Fatalf("Failed to create tasks file: %v", err)
-		}
+	tasksFile := *tasksFileFlag
+	if !filepath.IsAbs(tasksFile) {
+		tasksFile = filepath.Join(workDir, tasksFile)
 	}
 
-	// Initialize services
-	taskStore := services.NewTaskStore(workDir)
+	// Initialize services (the tasks file is created if it does not exist)
+	taskStore := services.NewTaskStoreWithFile(workDir, tasksFile)
+	taskStore.SetDurableWrites(*durable)
 	testRunner := services.NewTestRunnerWithStore(taskStore)
 
+	// Save and commit what is still pending when the client goes away, by
+	// closing stdin or with a signal
+	var closeOnce sync.Once
+	closeStore := func() {
+		closeOnce.Do(func() {
+			if err := taskStore.Close(); err != nil {
+				log.Printf("Failed to save tasks: %v", err)
+			}
+		})
+	}
+	go func() {
+		sigChan := make(chan os.Signal, 1)
+		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+		<-sigChan
+		closeStore()
+		os.Exit(0)
+	}()
+
+	// Commits land in git without touching TASKS.md, so check for them
+	go func() {
+		for range time.Tick(commitPollInterval) {
+			if _, err := taskStore.SyncCommits(); err != nil {
+				log.Printf("Failed to sync commits: %v", err)
+			}
+		}
+	}()
+
 	// Initialize tool handler
 	toolHandler := mcp.NewToolHandler(taskStore, testRunner)
 
@@ -108,7 +131,9 @@ func main() {
 	})
 
 	// Run the server
-	if err := server.Run(); err != nil {
+	err := server.Run()
+	closeStore()
+	if err != nil {
 		log.Fatalf("Server error: %v", err)
 	}
 }
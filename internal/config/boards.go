@@ -0,0 +1,101 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// boardNameRegex matches names that can be used as a URL path segment
+var boardNameRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// BoardConfig describes one project served by the web server: a working
+// directory with its own TASKS.md, test runner settings and AI queue
+type BoardConfig struct {
+	Name      string `yaml:"name"`       // Used in URLs: /b/{name}/
+	WorkDir   string `yaml:"workdir"`    // Directory containing TASKS.md
+	TasksFile string `yaml:"tasks_file"` // Tasks file, relative to WorkDir unless absolute (default: -tasks-file)
+}
+
+// boardsFile is the layout of a boards config file:
+//
+//	boards:
+//	  - name: api
+//	    workdir: services/api
+//	  - name: web
+//	    workdir: web
+//	    tasks_file: docs/TASKS.md
+type boardsFile struct {
+	Boards []BoardConfig `yaml:"boards"`
+}
+
+// LoadBoardsFile reads the boards listed in a YAML config file. Relative
+// working directories are resolved against the directory of the file.
+func LoadBoardsFile(path string) ([]BoardConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read boards file: %w", err)
+	}
+	var file boardsFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse boards file %s: %w", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	for i, board := range file.Boards {
+		if board.WorkDir == "" {
+			return nil, fmt.Errorf("board %q in %s has no workdir", board.Name, path)
+		}
+		if !filepath.IsAbs(board.WorkDir) && !strings.HasPrefix(board.WorkDir, "~") {
+			file.Boards[i].WorkDir = filepath.Join(dir, board.WorkDir)
+		}
+	}
+	return file.Boards, nil
+}
+
+// ParseBoardFlag parses a -board flag value of the form name=path
+func ParseBoardFlag(value string) (BoardConfig, error) {
+	name, workDir, ok := strings.Cut(value, "=")
+	if !ok || name == "" || workDir == "" {
+		return BoardConfig{}, fmt.Errorf("invalid board %q: expected name=path", value)
+	}
+	return BoardConfig{Name: name, WorkDir: workDir}, nil
+}
+
+// ValidateBoards checks that every board has a URL-safe name and that no
+// two boards share a name
+func ValidateBoards(boards []BoardConfig) error {
+	seen := make(map[string]bool)
+	for _, board := range boards {
+		if !boardNameRegex.MatchString(board.Name) {
+			return fmt.Errorf("invalid board name %q: use letters, digits, '-' and '_'", board.Name)
+		}
+		if seen[board.Name] {
+			return fmt.Errorf("duplicate board name %q", board.Name)
+		}
+		seen[board.Name] = true
+	}
+	return nil
+}
+
+// BoardNameFor derives a board name from a working directory, e.g.
+// "/src/My Project" becomes "my-project"
+func BoardNameFor(workDir string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(filepath.Base(workDir)) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_' && sb.Len() > 0:
+			sb.WriteRune(r)
+		case sb.Len() > 0 && !strings.HasSuffix(sb.String(), "-"):
+			sb.WriteRune('-')
+		}
+	}
+	if name := strings.TrimRight(sb.String(), "-"); name != "" {
+		return name
+	}
+	return "default"
+}
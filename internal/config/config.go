@@ -10,7 +10,7 @@ const (
 	DefaultStaleThresholdDays = 7
 
 	// DefaultTestCommand is the default test command template
-	DefaultTestCommand = "go test -v -count=1 -run ^{testFunc}$ {testPath}"
+	DefaultTestCommand = "go test -json -count=1 -run ^{testFunc}$ {testPath}"
 
 	// DefaultPassString is the default string indicating test passed
 	DefaultPassString = "PASS"
@@ -3,8 +3,10 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"kantext/internal/models"
@@ -16,15 +18,15 @@ import (
 // APIHandler handles REST API requests
 type APIHandler struct {
 	store        *services.TaskStore
-	runner       *services.TestRunner
+	testJobs     *services.TestJobManager
 	claudeRunner *services.ClaudeRunner
 }
 
 // NewAPIHandler creates a new APIHandler
-func NewAPIHandler(store *services.TaskStore, runner *services.TestRunner, claudeRunner *services.ClaudeRunner) *APIHandler {
+func NewAPIHandler(store *services.TaskStore, testJobs *services.TestJobManager, claudeRunner *services.ClaudeRunner) *APIHandler {
 	return &APIHandler{
 		store:        store,
-		runner:       runner,
+		testJobs:     testJobs,
 		claudeRunner: claudeRunner,
 	}
 }
@@ -43,10 +45,114 @@ func respondError(w http.ResponseWriter, status int, message string) {
 	respondJSON(w, status, map[string]string{"error": message})
 }
 
-// ListTasks returns all tasks
+// storeErrorStatus maps a TaskStore error to an HTTP status code. Persistence
+// failures in durable-write mode are always reported as 500 so callers never
+// mistake an unsaved change for a successful one.
+func storeErrorStatus(err error, defaultStatus int) int {
+	switch {
+	case errors.Is(err, services.ErrSaveFailed):
+		return http.StatusInternalServerError
+	case errors.Is(err, services.ErrInvalidDependency), errors.Is(err, services.ErrInvalidParent),
+		errors.Is(err, services.ErrInvalidQuery), errors.Is(err, services.ErrNoTests):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrTaskBlocked), errors.Is(err, services.ErrUndoConflict),
+		errors.Is(err, services.ErrNothingToUndo), errors.Is(err, services.ErrNothingToRedo),
+		errors.Is(err, services.ErrTestJobFinished):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrNotArchived), errors.Is(err, services.ErrUnknownRef),
+		errors.Is(err, services.ErrTestJobNotFound):
+		return http.StatusNotFound
+	}
+	return defaultStatus
+}
+
+// etagFor formats a task's revision as a strong HTTP entity tag
+func etagFor(task *models.Task) string {
+	return `"` + task.Revision() + `"`
+}
+
+// parseIfMatch extracts the revision from an If-Match header.
+// Returns an empty string when the header is absent or "*" (match any).
+func parseIfMatch(header string) string {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return ""
+	}
+	// Only a single entity tag is meaningful for a single task
+	if idx := strings.Index(header, ","); idx >= 0 {
+		header = strings.TrimSpace(header[:idx])
+	}
+	header = strings.TrimPrefix(header, "W/")
+	return strings.Trim(header, `"`)
+}
+
+// GetHealth reports whether TASKS.md is being saved successfully
+func (h *APIHandler) GetHealth(w http.ResponseWriter, r *http.Request) {
+	saveStatus := h.store.GetSaveStatus()
+
+	status := "ok"
+	httpStatus := http.StatusOK
+	if !saveStatus.Healthy() {
+		status = "degraded"
+		httpStatus = http.StatusServiceUnavailable
+	}
+
+	respondJSON(w, httpStatus, map[string]interface{}{
+		"status": status,
+		"save":   saveStatus,
+	})
+}
+
+// ListTasks returns all tasks, or the tasks matching a search when query
+// parameters are given. q takes the full query language (see
+// services.ParseTaskQuery); tag, priority, column, status, stale, blocked,
+// parent and author are shorthands for the matching filters. offset and
+// limit page through the results, and X-Total-Count reports the number of
+// matches across all pages.
 func (h *APIHandler) ListTasks(w http.ResponseWriter, r *http.Request) {
-	tasks := h.store.GetAll()
-	respondJSON(w, http.StatusOK, tasks)
+	params := r.URL.Query()
+	if len(params) == 0 {
+		respondJSON(w, http.StatusOK, h.store.GetAll())
+		return
+	}
+
+	// Filter parameters are added as they are, so values need no quoting
+	query, err := services.ParseTaskQuery(params.Get("q"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	for _, key := range []string{"tag", "priority", "column", "status", "stale", "blocked", "parent", "author"} {
+		for _, value := range params[key] {
+			if err := query.AddFilter(key, value); err != nil {
+				respondError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+	}
+
+	offset, limit := 0, 0
+	if v := params.Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil {
+			respondError(w, http.StatusBadRequest, "offset must be a number")
+			return
+		}
+	}
+	if v := params.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil {
+			respondError(w, http.StatusBadRequest, "limit must be a number")
+			return
+		}
+	}
+
+	result, err := h.store.SearchTasks(query, offset, limit)
+	if err != nil {
+		respondError(w, storeErrorStatus(err, http.StatusBadRequest), err.Error())
+		return
+	}
+
+	w.Header().Set("X-Total-Count", strconv.Itoa(result.Total))
+	respondJSON(w, http.StatusOK, result.Tasks)
 }
 
 // GetTask returns a single task by ID
@@ -59,6 +165,7 @@ func (h *APIHandler) GetTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Set("ETag", etagFor(task))
 	respondJSON(w, http.StatusOK, task)
 }
 
@@ -85,7 +192,7 @@ func (h *APIHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 
 	task, err := h.store.Create(req)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, err.Error())
+		respondError(w, storeErrorStatus(err, http.StatusInternalServerError), err.Error())
 		return
 	}
 
@@ -102,29 +209,233 @@ func (h *APIHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// If-Match takes precedence over an expected_revision in the body
+	if revision := parseIfMatch(r.Header.Get("If-Match")); revision != "" {
+		req.ExpectedRevision = revision
+	}
+
 	task, err := h.store.Update(id, req)
 	if err != nil {
-		respondError(w, http.StatusNotFound, err.Error())
+		if errors.Is(err, services.ErrRevisionConflict) {
+			h.respondConflict(w, id, err)
+			return
+		}
+		respondError(w, storeErrorStatus(err, http.StatusNotFound), err.Error())
 		return
 	}
 
+	w.Header().Set("ETag", etagFor(task))
 	respondJSON(w, http.StatusOK, task)
 }
 
+// respondConflict writes a 409 response containing the task's current version
+func (h *APIHandler) respondConflict(w http.ResponseWriter, id string, err error) {
+	current, getErr := h.store.Get(id)
+	if getErr != nil {
+		respondError(w, http.StatusNotFound, getErr.Error())
+		return
+	}
+
+	w.Header().Set("ETag", etagFor(current))
+	respondJSON(w, http.StatusConflict, map[string]interface{}{
+		"error":   err.Error(),
+		"current": current,
+	})
+}
+
 // DeleteTask deletes a task
 func (h *APIHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
 	if err := h.store.Delete(id); err != nil {
+		respondError(w, storeErrorStatus(err, http.StatusNotFound), err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetChildren returns the subtasks of a task
+func (h *APIHandler) GetChildren(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	children, err := h.store.GetChildren(id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, children)
+}
+
+// Undo reverts the most recent change to the board. Connected clients are
+// notified over the WebSocket.
+func (h *APIHandler) Undo(w http.ResponseWriter, r *http.Request) {
+	result, err := h.store.Undo()
+	if err != nil {
+		respondError(w, storeErrorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, result)
+}
+
+// Redo re-applies the most recently undone change
+func (h *APIHandler) Redo(w http.ResponseWriter, r *http.Request) {
+	result, err := h.store.Redo()
+	if err != nil {
+		respondError(w, storeErrorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, result)
+}
+
+// GetHistory returns the recorded history of a task, oldest first
+func (h *APIHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	events, err := h.store.GetHistory(id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, events)
+}
+
+// GetTestHistory returns the recorded test runs of a task with per-test
+// statistics, including which tests are flaky
+func (h *APIHandler) GetTestHistory(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	history, err := h.store.GetTestHistory(id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, history)
+}
+
+// GetCommits returns the git commits that mention a task, newest first
+func (h *APIHandler) GetCommits(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	commits, err := h.store.GetCommits(id)
+	if err != nil {
 		respondError(w, http.StatusNotFound, err.Error())
 		return
 	}
 
+	respondJSON(w, http.StatusOK, commits)
+}
+
+// ListBranches returns the local git branches and their worktrees
+func (h *APIHandler) ListBranches(w http.ResponseWriter, r *http.Request) {
+	branches, err := h.store.ListBranches()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, branches)
+}
+
+// GetBranchBoard returns the board on the branch, commit or worktree given by
+// the ref query parameter (e.g. ?ref=feature/login or ?ref=worktree:feature/login)
+func (h *APIHandler) GetBranchBoard(w http.ResponseWriter, r *http.Request) {
+	ref := r.URL.Query().Get("ref")
+	if ref == "" {
+		respondError(w, http.StatusBadRequest, "ref is required")
+		return
+	}
+
+	board, err := h.store.GetBranchBoard(ref)
+	if err != nil {
+		respondError(w, storeErrorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, board)
+}
+
+// CompareBranches returns how the tasks on the head board differ from the base board
+func (h *APIHandler) CompareBranches(w http.ResponseWriter, r *http.Request) {
+	base, head := r.URL.Query().Get("base"), r.URL.Query().Get("head")
+	if base == "" || head == "" {
+		respondError(w, http.StatusBadRequest, "base and head are required")
+		return
+	}
+
+	comparison, err := h.store.CompareBranches(base, head)
+	if err != nil {
+		respondError(w, storeErrorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, comparison)
+}
+
+// PreviewMerge returns what merging the ref branch would do to the current board
+func (h *APIHandler) PreviewMerge(w http.ResponseWriter, r *http.Request) {
+	ref := r.URL.Query().Get("ref")
+	if ref == "" {
+		respondError(w, http.StatusBadRequest, "ref is required")
+		return
+	}
+
+	preview, err := h.store.PreviewMerge(ref)
+	if err != nil {
+		respondError(w, storeErrorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, preview)
+}
+
+// ListArchive returns the archived (deleted) tasks, most recently archived first
+func (h *APIHandler) ListArchive(w http.ResponseWriter, r *http.Request) {
+	tasks, err := h.store.GetArchive()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, tasks)
+}
+
+// RestoreTask moves an archived task back onto the board
+func (h *APIHandler) RestoreTask(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	task, err := h.store.RestoreTask(id, r.URL.Query().Get("author"))
+	if err != nil {
+		respondError(w, storeErrorStatus(err, http.StatusConflict), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, task)
+}
+
+// PurgeArchive permanently removes archived tasks older than the retention period
+func (h *APIHandler) PurgeArchive(w http.ResponseWriter, r *http.Request) {
+	purged, err := h.store.PurgeArchive()
+	if err != nil {
+		respondError(w, storeErrorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{"purged": purged})
+}
+
+// PurgeArchivedTask permanently removes a single task from the archive
+func (h *APIHandler) PurgeArchivedTask(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if err := h.store.PurgeArchivedTask(id); err != nil {
+		respondError(w, storeErrorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// RunTest executes all tests associated with a task
-func (h *APIHandler) RunTest(w http.ResponseWriter, r *http.Request) {
+// ListCriteria returns a task's acceptance-criteria checklist and its progress
+func (h *APIHandler) ListCriteria(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
 	task, err := h.store.Get(id)
@@ -133,34 +444,162 @@ func (h *APIHandler) RunTest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if task has tests associated
-	if !task.HasTest() {
-		respondError(w, http.StatusBadRequest, "Task does not have any tests associated with it")
+	done, total := task.ChecklistProgress()
+	items := task.Checklist
+	if items == nil {
+		items = []models.ChecklistItem{}
+	}
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"items": items,
+		"done":  done,
+		"total": total,
+	})
+}
+
+// AddCriterion appends an item to a task's checklist
+func (h *APIHandler) AddCriterion(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	var req struct {
+		Text   string `json:"text"`
+		Author string `json:"author,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if strings.TrimSpace(req.Text) == "" {
+		respondError(w, http.StatusBadRequest, "Text is required")
 		return
 	}
 
-	// Mark as running
-	h.store.SetTestRunning(id)
+	task, err := h.store.AddChecklistItem(id, req.Text, req.Author)
+	if err != nil {
+		respondError(w, storeErrorStatus(err, http.StatusNotFound), err.Error())
+		return
+	}
 
-	// Run all tests synchronously
-	results := h.runner.RunAll(r.Context(), task.Tests)
+	w.Header().Set("ETag", etagFor(task))
+	respondJSON(w, http.StatusCreated, task)
+}
 
-	// Update the task with the aggregated results
-	updatedTask, err := h.store.UpdateTestResults(id, results)
+// UpdateCriterion checks, unchecks or rewords a checklist item (numbered from 1)
+func (h *APIHandler) UpdateCriterion(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	item, err := strconv.Atoi(chi.URLParam(r, "item"))
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, err.Error())
+		respondError(w, http.StatusBadRequest, "Item must be a number")
+		return
+	}
+
+	var req models.UpdateChecklistItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if req.Text != nil && strings.TrimSpace(*req.Text) == "" {
+		respondError(w, http.StatusBadRequest, "Text cannot be empty")
+		return
+	}
+
+	task, err := h.store.UpdateChecklistItem(id, item, req)
+	if err != nil {
+		respondError(w, storeErrorStatus(err, http.StatusNotFound), err.Error())
+		return
+	}
+
+	w.Header().Set("ETag", etagFor(task))
+	respondJSON(w, http.StatusOK, task)
+}
+
+// DeleteCriterion removes a checklist item (numbered from 1)
+func (h *APIHandler) DeleteCriterion(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	item, err := strconv.Atoi(chi.URLParam(r, "item"))
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Item must be a number")
+		return
+	}
+
+	task, err := h.store.RemoveChecklistItem(id, item, "")
+	if err != nil {
+		respondError(w, storeErrorStatus(err, http.StatusNotFound), err.Error())
+		return
+	}
+
+	w.Header().Set("ETag", etagFor(task))
+	respondJSON(w, http.StatusOK, task)
+}
+
+// RunTest starts running a task's tests in the background and returns the
+// job right away. Output is streamed to WebSocket clients as test_output
+// messages, and the job can be followed with GET /api/jobs/{id}. With
+// ?wait=true the request waits for the tests and returns the task and results.
+func (h *APIHandler) RunTest(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	job, err := h.testJobs.Start(id)
+	if err != nil {
+		respondError(w, storeErrorStatus(err, http.StatusNotFound), err.Error())
+		return
+	}
+
+	if r.URL.Query().Get("wait") != "true" {
+		respondJSON(w, http.StatusAccepted, job)
+		return
+	}
+
+	job, err = h.testJobs.Wait(r.Context(), job.ID)
+	if err != nil {
+		respondError(w, storeErrorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+	if job.Results == nil {
+		respondError(w, http.StatusConflict, "Test run was canceled")
+		return
+	}
+	task, err := h.store.Get(id)
+	if err != nil {
+		respondError(w, http.StatusNotFound, err.Error())
 		return
 	}
 
 	// Return both the task and results
 	response := map[string]interface{}{
-		"task":    updatedTask,
-		"results": results,
+		"task":    task,
+		"results": job.Results,
 	}
 
 	respondJSON(w, http.StatusOK, response)
 }
 
+// GetTestJob returns a test job with its output so far
+func (h *APIHandler) GetTestJob(w http.ResponseWriter, r *http.Request) {
+	job, err := h.testJobs.Get(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, storeErrorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, job)
+}
+
+// CancelTestJob stops a running test job. Its task's test results are left
+// as they were before the job started.
+func (h *APIHandler) CancelTestJob(w http.ResponseWriter, r *http.Request) {
+	job, err := h.testJobs.Cancel(chi.URLParam(r, "id"))
+	if err != nil {
+		respondError(w, storeErrorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, job)
+}
+
 // GetTaskStatus returns the current status of a task (useful for polling)
 func (h *APIHandler) GetTaskStatus(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
@@ -204,7 +643,7 @@ func (h *APIHandler) CreateColumn(w http.ResponseWriter, r *http.Request) {
 
 	column, err := h.store.CreateColumn(req.Name)
 	if err != nil {
-		respondError(w, http.StatusBadRequest, err.Error())
+		respondError(w, storeErrorStatus(err, http.StatusBadRequest), err.Error())
 		return
 	}
 
@@ -230,7 +669,7 @@ func (h *APIHandler) UpdateColumn(w http.ResponseWriter, r *http.Request) {
 
 	column, err := h.store.UpdateColumn(slug, req.Name)
 	if err != nil {
-		respondError(w, http.StatusNotFound, err.Error())
+		respondError(w, storeErrorStatus(err, http.StatusNotFound), err.Error())
 		return
 	}
 
@@ -242,7 +681,7 @@ func (h *APIHandler) DeleteColumn(w http.ResponseWriter, r *http.Request) {
 	slug := chi.URLParam(r, "slug")
 
 	if err := h.store.DeleteColumn(slug); err != nil {
-		respondError(w, http.StatusBadRequest, err.Error())
+		respondError(w, storeErrorStatus(err, http.StatusBadRequest), err.Error())
 		return
 	}
 
@@ -265,7 +704,7 @@ func (h *APIHandler) ReorderColumns(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.store.ReorderColumns(req.Slugs); err != nil {
-		respondError(w, http.StatusBadRequest, err.Error())
+		respondError(w, storeErrorStatus(err, http.StatusBadRequest), err.Error())
 		return
 	}
 
@@ -293,7 +732,7 @@ func (h *APIHandler) ReorderTask(w http.ResponseWriter, r *http.Request) {
 
 	task, err := h.store.Reorder(id, models.Column(req.Column), req.Position)
 	if err != nil {
-		respondError(w, http.StatusNotFound, err.Error())
+		respondError(w, storeErrorStatus(err, http.StatusNotFound), err.Error())
 		return
 	}
 
@@ -305,13 +744,30 @@ func (h *APIHandler) GetConfig(w http.ResponseWriter, r *http.Request) {
 	settings := h.store.GetSettings()
 
 	configData := map[string]interface{}{
-		"stale_threshold_days": settings.GetStaleThresholdDays(),
-		"working_directory":    h.store.GetWorkingDir(),
-		"test_runner": map[string]string{
+		"stale_threshold_days":   settings.GetStaleThresholdDays(),
+		"archive_retention_days": settings.GetArchiveRetentionDays(),
+		"working_directory":      h.store.GetWorkingDir(),
+		"tasks_file":             h.store.GetTasksFile(),
+		"include":                settings.Include,
+		"test_runner": map[string]interface{}{
 			"command":         settings.GetTestCommand(),
 			"pass_string":     settings.GetPassString(),
 			"fail_string":     settings.GetFailString(),
 			"no_tests_string": settings.GetNoTestsString(),
+			"format":          settings.GetTestFormat(),
+			"report":          settings.TestRunner.Report,
+			"batch":           settings.GetTestBatch(),
+			"parallel":        settings.GetTestParallel(),
+			"dir":             settings.TestRunner.Dir,
+			"env":             settings.TestRunner.Env,
+		},
+		"test_runners": settings.TestRunners,
+		"git": map[string]interface{}{
+			"auto_commit":  settings.Git.AutoCommit,
+			"author_name":  settings.Git.AuthorName,
+			"author_email": settings.Git.AuthorEmail,
+			"main_branch":  settings.Git.MainBranch,
+			"close_column": settings.Git.CloseColumn,
 		},
 	}
 
@@ -320,16 +776,34 @@ func (h *APIHandler) GetConfig(w http.ResponseWriter, r *http.Request) {
 
 // UpdateConfigRequest defines the structure for config update requests
 type UpdateConfigRequest struct {
-	StaleThresholdDays *int                     `json:"stale_threshold_days,omitempty"`
-	TestRunner         *TestRunnerUpdateRequest `json:"test_runner,omitempty"`
+	StaleThresholdDays   *int                                   `json:"stale_threshold_days,omitempty"`
+	ArchiveRetentionDays *int                                   `json:"archive_retention_days,omitempty"`
+	TestRunner           *TestRunnerUpdateRequest               `json:"test_runner,omitempty"`
+	TestRunners          *map[string]services.TestRunnerProfile `json:"test_runners,omitempty"` // Replaces all runner profiles
+	Git                  *GitUpdateRequest                      `json:"git,omitempty"`
 }
 
 // TestRunnerUpdateRequest defines test runner config updates
 type TestRunnerUpdateRequest struct {
-	Command       *string `json:"command,omitempty"`
-	PassString    *string `json:"pass_string,omitempty"`
-	FailString    *string `json:"fail_string,omitempty"`
-	NoTestsString *string `json:"no_tests_string,omitempty"`
+	Command       *string            `json:"command,omitempty"`
+	PassString    *string            `json:"pass_string,omitempty"`
+	FailString    *string            `json:"fail_string,omitempty"`
+	NoTestsString *string            `json:"no_tests_string,omitempty"`
+	Format        *string            `json:"format,omitempty"`   // go-json, junit, tap, or empty for pass/fail strings
+	Report        *string            `json:"report,omitempty"`   // Report file written by the command
+	Batch         *bool              `json:"batch,omitempty"`    // Run a task's tests in one command per package
+	Parallel      *int               `json:"parallel,omitempty"` // Packages tested at once
+	Dir           *string            `json:"dir,omitempty"`      // Directory the command runs in
+	Env           *map[string]string `json:"env,omitempty"`      // Extra environment variables
+}
+
+// GitUpdateRequest defines git integration config updates
+type GitUpdateRequest struct {
+	AutoCommit  *bool   `json:"auto_commit,omitempty"`
+	AuthorName  *string `json:"author_name,omitempty"`
+	AuthorEmail *string `json:"author_email,omitempty"`
+	MainBranch  *string `json:"main_branch,omitempty"`
+	CloseColumn *string `json:"close_column,omitempty"`
 }
 
 // UpdateConfig updates the application configuration
@@ -345,6 +819,24 @@ func (h *APIHandler) UpdateConfig(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusBadRequest, "stale_threshold_days must be at least 1")
 		return
 	}
+	if req.ArchiveRetentionDays != nil && *req.ArchiveRetentionDays < 1 {
+		respondError(w, http.StatusBadRequest, "archive_retention_days must be at least 1")
+		return
+	}
+	if req.TestRunner != nil && req.TestRunner.Format != nil && !services.ValidTestFormat(*req.TestRunner.Format) {
+		respondError(w, http.StatusBadRequest, "test_runner.format must be go-json, junit, tap or empty")
+		return
+	}
+	if req.TestRunner != nil && req.TestRunner.Parallel != nil && *req.TestRunner.Parallel < 1 {
+		respondError(w, http.StatusBadRequest, "test_runner.parallel must be at least 1")
+		return
+	}
+	if req.TestRunners != nil {
+		if err := services.ValidateTestRunners(*req.TestRunners); err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
 
 	// Get current settings and update
 	settings := h.store.GetSettings()
@@ -352,6 +844,9 @@ func (h *APIHandler) UpdateConfig(w http.ResponseWriter, r *http.Request) {
 	if req.StaleThresholdDays != nil {
 		settings.StaleThresholdDays = *req.StaleThresholdDays
 	}
+	if req.ArchiveRetentionDays != nil {
+		settings.ArchiveRetentionDays = *req.ArchiveRetentionDays
+	}
 	if req.TestRunner != nil {
 		if req.TestRunner.Command != nil {
 			settings.TestRunner.Command = *req.TestRunner.Command
@@ -365,6 +860,44 @@ func (h *APIHandler) UpdateConfig(w http.ResponseWriter, r *http.Request) {
 		if req.TestRunner.NoTestsString != nil {
 			settings.TestRunner.NoTestsString = *req.TestRunner.NoTestsString
 		}
+		if req.TestRunner.Format != nil {
+			settings.TestRunner.Format = *req.TestRunner.Format
+		}
+		if req.TestRunner.Report != nil {
+			settings.TestRunner.Report = *req.TestRunner.Report
+		}
+		if req.TestRunner.Batch != nil {
+			settings.TestRunner.Batch = *req.TestRunner.Batch
+		}
+		if req.TestRunner.Parallel != nil {
+			settings.TestRunner.Parallel = *req.TestRunner.Parallel
+		}
+		if req.TestRunner.Dir != nil {
+			settings.TestRunner.Dir = *req.TestRunner.Dir
+		}
+		if req.TestRunner.Env != nil {
+			settings.TestRunner.Env = *req.TestRunner.Env
+		}
+	}
+	if req.TestRunners != nil {
+		settings.TestRunners = *req.TestRunners
+	}
+	if req.Git != nil {
+		if req.Git.AutoCommit != nil {
+			settings.Git.AutoCommit = *req.Git.AutoCommit
+		}
+		if req.Git.AuthorName != nil {
+			settings.Git.AuthorName = *req.Git.AuthorName
+		}
+		if req.Git.AuthorEmail != nil {
+			settings.Git.AuthorEmail = *req.Git.AuthorEmail
+		}
+		if req.Git.MainBranch != nil {
+			settings.Git.MainBranch = *req.Git.MainBranch
+		}
+		if req.Git.CloseColumn != nil {
+			settings.Git.CloseColumn = *req.Git.CloseColumn
+		}
 	}
 
 	// Save to TASKS.md via TaskStore
@@ -446,7 +979,7 @@ func (h *APIHandler) ReorderAIQueue(w http.ResponseWriter, r *http.Request) {
 func (h *APIHandler) StartAITask(w http.ResponseWriter, r *http.Request) {
 	taskID, err := h.store.StartNextTask()
 	if err != nil {
-		respondError(w, http.StatusBadRequest, err.Error())
+		respondError(w, storeErrorStatus(err, http.StatusBadRequest), err.Error())
 		return
 	}
 
@@ -487,10 +1020,26 @@ func buildClaudePrompt(task *models.Task) string {
 	sb.WriteString(fmt.Sprintf("**ID:** %s\n", task.ID))
 	sb.WriteString(fmt.Sprintf("**Priority:** %s\n", task.Priority))
 
+	if task.Description != "" {
+		sb.WriteString(fmt.Sprintf("\n**Description:**\n%s\n", task.Description))
+	}
+
 	if task.AcceptanceCriteria != "" {
 		sb.WriteString(fmt.Sprintf("\n**Acceptance Criteria:**\n%s\n", task.AcceptanceCriteria))
 	}
 
+	if len(task.Checklist) > 0 {
+		done, total := task.ChecklistProgress()
+		sb.WriteString(fmt.Sprintf("\n**Checklist (%d/%d done):**\n", done, total))
+		for i, item := range task.Checklist {
+			check := " "
+			if item.Done {
+				check = "x"
+			}
+			sb.WriteString(fmt.Sprintf("%d. [%s] %s\n", i+1, check, item.Text))
+		}
+	}
+
 	if len(task.Tags) > 0 {
 		sb.WriteString(fmt.Sprintf("\n**Tags:** %s\n", strings.Join(task.Tags, ", ")))
 	}
@@ -505,7 +1054,12 @@ func buildClaudePrompt(task *models.Task) string {
 	sb.WriteString("\n## Instructions\n")
 	sb.WriteString("1. Implement the task according to the acceptance criteria\n")
 	sb.WriteString("2. Use the Kantext MCP tools to update task status when complete\n")
-	sb.WriteString("3. Move the task to 'in_review' column when finished\n")
+	if len(task.Checklist) > 0 {
+		sb.WriteString("3. Tick off each checklist item with the check_criterion tool as you complete it\n")
+		sb.WriteString("4. Move the task to 'in_review' column when finished\n")
+	} else {
+		sb.WriteString("3. Move the task to 'in_review' column when finished\n")
+	}
 
 	return sb.String()
 }
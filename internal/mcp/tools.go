@@ -2,6 +2,7 @@ package mcp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -32,15 +33,36 @@ func (h *ToolHandler) GetTools() []Tool {
 	return []Tool{
 		{
 			Name:        "list_tasks",
-			Description: "List all tasks on the Kantext board. Returns tasks organized by column (inbox, in_progress, done) with their priority and test status.",
+			Description: "List all tasks on the Kantext board. Returns tasks organized by column (inbox, in_progress, done) with their priority and test status. On large boards use search_tasks instead.",
 			InputSchema: InputSchema{
 				Type:       "object",
 				Properties: map[string]Property{},
 			},
 		},
+		{
+			Name:        "search_tasks",
+			Description: "Search tasks with a query and return one page of matches. Prefer this over list_tasks on large boards. Filters: tag:, priority:, column:, status: (pending, running, passed, failed), stale:true|false, blocked:true|false, parent:, author:. Comma-separated values match any of them, e.g. priority:high,medium. Other words and \"quoted phrases\" are matched against the title, criteria, description and tags. Example: tag:backend status:failed \"login\"",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]Property{
+					"query": {
+						Type:        "string",
+						Description: "Search query. Leave empty to page through all tasks.",
+					},
+					"offset": {
+						Type:        "integer",
+						Description: "Number of matching tasks to skip. Defaults to 0.",
+					},
+					"limit": {
+						Type:        "integer",
+						Description: "Maximum number of tasks to return. Defaults to 20.",
+					},
+				},
+			},
+		},
 		{
 			Name:        "get_task",
-			Description: "Get details of a specific task by ID, including its acceptance criteria, priority, test status, and last test output.",
+			Description: "Get details of a specific task by ID, including its acceptance criteria, priority, test status, last test output, and the git commits and branches that mention it.",
 			InputSchema: InputSchema{
 				Type: "object",
 				Properties: map[string]Property{
@@ -64,7 +86,11 @@ func (h *ToolHandler) GetTools() []Tool {
 					},
 					"acceptance_criteria": {
 						Type:        "string",
-						Description: "Clear criteria that define when this task is complete.",
+						Description: "Clear criteria that define when this task is complete. May span multiple lines (e.g. a checklist).",
+					},
+					"description": {
+						Type:        "string",
+						Description: "Free-form description with background, context or notes. May span multiple lines.",
 					},
 					"priority": {
 						Type:        "string",
@@ -81,10 +107,65 @@ func (h *ToolHandler) GetTools() []Tool {
 						Type:        "boolean",
 						Description: "Whether a passing test is required to complete this task. Defaults to false.",
 					},
+					"blocked_by": {
+						Type:        "array",
+						Description: "IDs of tasks that must be done before this task can be started",
+						Items: &PropertyItems{
+							Type: "string",
+						},
+					},
+					"checklist": {
+						Type:        "array",
+						Description: "Acceptance-criteria checklist items, each ticked off individually with check_criterion (e.g., ['Login form renders', 'Invalid password shows an error'])",
+						Items: &PropertyItems{
+							Type: "string",
+						},
+					},
 				},
 				Required: []string{"title"},
 			},
 		},
+		{
+			Name:        "create_subtask",
+			Description: "Create a subtask under an existing task. Use this when breaking a large task into smaller pieces so they stay linked to the parent. A parent without tests of its own reports the combined test status of its subtasks.",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]Property{
+					"parent_id": {
+						Type:        "string",
+						Description: "The unique ID of the parent task",
+					},
+					"title": {
+						Type:        "string",
+						Description: "Short title describing the subtask",
+					},
+					"acceptance_criteria": {
+						Type:        "string",
+						Description: "Clear criteria that define when this subtask is complete.",
+					},
+					"description": {
+						Type:        "string",
+						Description: "Free-form description with background, context or notes.",
+					},
+					"priority": {
+						Type:        "string",
+						Description: "Subtask priority: 'high', 'medium', or 'low'. Defaults to 'medium' if not specified.",
+					},
+					"tags": {
+						Type:        "array",
+						Description: "Array of tags for categorization",
+						Items: &PropertyItems{
+							Type: "string",
+						},
+					},
+					"requires_test": {
+						Type:        "boolean",
+						Description: "Whether a passing test is required to complete this subtask. Defaults to false.",
+					},
+				},
+				Required: []string{"parent_id", "title"},
+			},
+		},
 		{
 			Name:        "update_task",
 			Description: "Update an existing task's properties including test configuration. Use this to set the tests array after task creation.",
@@ -103,6 +184,10 @@ func (h *ToolHandler) GetTools() []Tool {
 						Type:        "string",
 						Description: "New acceptance criteria for the task",
 					},
+					"description": {
+						Type:        "string",
+						Description: "New description for the task",
+					},
 					"priority": {
 						Type:        "string",
 						Description: "Task priority: 'high', 'medium', or 'low'",
@@ -118,6 +203,17 @@ func (h *ToolHandler) GetTools() []Tool {
 						Type:        "boolean",
 						Description: "Whether a passing test is required to complete this task",
 					},
+					"parent": {
+						Type:        "string",
+						Description: "ID of the parent task, making this a subtask. Pass an empty string to make it a top-level task.",
+					},
+					"blocked_by": {
+						Type:        "array",
+						Description: "IDs of tasks that must be done before this task can be started. Replaces the existing list; pass an empty array to clear it.",
+						Items: &PropertyItems{
+							Type: "string",
+						},
+					},
 					"tests": {
 						Type:        "array",
 						Description: "Array of test specifications. Each test has 'file' (path relative to working directory) and 'func' (test function name)",
@@ -132,10 +228,18 @@ func (h *ToolHandler) GetTools() []Tool {
 									Type:        "string",
 									Description: "Test function name (e.g., 'TestLogin')",
 								},
+								"runner": {
+									Type:        "string",
+									Description: "Optional test runner profile from the board's test_runners setting. By default the profile is chosen by file name.",
+								},
 							},
 							Required: []string{"file", "func"},
 						},
 					},
+					"expected_revision": {
+						Type:        "string",
+						Description: "Optional revision from get_task. If the task has changed since then, the update is rejected instead of overwriting the other change.",
+					},
 				},
 				Required: []string{"task_id"},
 			},
@@ -174,7 +278,7 @@ func (h *ToolHandler) GetTools() []Tool {
 		},
 		{
 			Name:        "delete_task",
-			Description: "Delete a task from the Kantext board. This action cannot be undone.",
+			Description: "Delete a task from the Kantext board. The task is moved to the archive, where it can be restored with restore_task until it is purged after the archive retention period.",
 			InputSchema: InputSchema{
 				Type: "object",
 				Properties: map[string]Property{
@@ -186,6 +290,163 @@ func (h *ToolHandler) GetTools() []Tool {
 				Required: []string{"task_id"},
 			},
 		},
+		{
+			Name:        "list_archive",
+			Description: "List deleted tasks kept in the archive, most recently archived first, with the column each was deleted from.",
+			InputSchema: InputSchema{
+				Type:       "object",
+				Properties: map[string]Property{},
+			},
+		},
+		{
+			Name:        "restore_task",
+			Description: "Restore an archived (deleted) task to the board, at the end of the column it was deleted from. Use list_archive to find task IDs.",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]Property{
+					"task_id": {
+						Type:        "string",
+						Description: "The unique ID of the archived task",
+					},
+				},
+				Required: []string{"task_id"},
+			},
+		},
+		{
+			Name:        "list_criteria",
+			Description: "List a task's acceptance-criteria checklist with the done state of each item and overall progress (e.g. 3/5). Items are numbered from 1.",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]Property{
+					"task_id": {
+						Type:        "string",
+						Description: "The unique ID of the task",
+					},
+				},
+				Required: []string{"task_id"},
+			},
+		},
+		{
+			Name:        "check_criterion",
+			Description: "Tick off (or untick) a single acceptance-criteria checklist item. Use list_criteria to find item numbers.",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]Property{
+					"task_id": {
+						Type:        "string",
+						Description: "The unique ID of the task",
+					},
+					"item": {
+						Type:        "integer",
+						Description: "Checklist item number, starting at 1",
+					},
+					"done": {
+						Type:        "boolean",
+						Description: "Whether the item is complete. Defaults to true.",
+					},
+				},
+				Required: []string{"task_id", "item"},
+			},
+		},
+		{
+			Name:        "add_criterion",
+			Description: "Add an unchecked item to a task's acceptance-criteria checklist.",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]Property{
+					"task_id": {
+						Type:        "string",
+						Description: "The unique ID of the task",
+					},
+					"text": {
+						Type:        "string",
+						Description: "The criterion to add (a single line)",
+					},
+				},
+				Required: []string{"task_id", "text"},
+			},
+		},
+		{
+			Name:        "get_task_history",
+			Description: "Show the audit history of a task: when it was created, each field change and column move, test runs, and AI work starting and stopping, with author and time. History is kept after a task is deleted.",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]Property{
+					"task_id": {
+						Type:        "string",
+						Description: "The unique ID of the task",
+					},
+				},
+				Required: []string{"task_id"},
+			},
+		},
+		{
+			Name:        "get_test_history",
+			Description: "Show the recent outcomes of each of a task's tests, with pass counts, durations, and flakiness: a test that both passed and failed on the same code (same commit and uncommitted changes) is flagged as flaky. Check this before debugging a failure that may not be caused by your change.",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]Property{
+					"task_id": {
+						Type:        "string",
+						Description: "The unique ID of the task",
+					},
+				},
+				Required: []string{"task_id"},
+			},
+		},
+		{
+			Name:        "undo_last_change",
+			Description: "Undo the most recent change to the board (a task created, edited, moved or deleted, or a checklist edit). Call it again to undo earlier changes, or pass redo=true to re-apply a change that was undone. Test runs are not undone.",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]Property{
+					"redo": {
+						Type:        "boolean",
+						Description: "Re-apply the last undone change instead. Defaults to false.",
+					},
+				},
+			},
+		},
+		{
+			Name:        "compare_branches",
+			Description: "Compare the TASKS.md board on two git branches or worktrees: tasks added, removed, and the fields changed on each task. Prefix a branch with 'worktree:' to read the file on disk in the worktree that has it checked out, including uncommitted edits.",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]Property{
+					"base": {
+						Type:        "string",
+						Description: "Branch, commit or worktree:<branch> to compare from",
+					},
+					"head": {
+						Type:        "string",
+						Description: "Branch, commit or worktree:<branch> to compare to",
+					},
+				},
+				Required: []string{"base", "head"},
+			},
+		},
+		{
+			Name:        "preview_merge",
+			Description: "Preview what merging a git branch into the current branch would do to the board, without changing anything: tasks added, removed or changed, and fields changed on both sides.",
+			InputSchema: InputSchema{
+				Type: "object",
+				Properties: map[string]Property{
+					"ref": {
+						Type:        "string",
+						Description: "Branch, commit or worktree:<branch> to merge",
+					},
+				},
+				Required: []string{"ref"},
+			},
+		},
+		{
+			Name:        "get_health",
+			Description: "Check whether the Kantext board is being saved to TASKS.md successfully. Reports the last save error (if any) and the time of the last successful save.",
+			InputSchema: InputSchema{
+				Type:       "object",
+				Properties: map[string]Property{},
+			},
+		},
 	}
 }
 
@@ -202,10 +463,14 @@ func (h *ToolHandler) CallTool(name string, args map[string]interface{}) ToolRes
 	switch name {
 	case "list_tasks":
 		return h.listTasks()
+	case "search_tasks":
+		return h.searchTasks(args)
 	case "get_task":
 		return h.getTask(args)
 	case "create_task":
 		return h.createTask(args)
+	case "create_subtask":
+		return h.createSubtask(args)
 	case "update_task":
 		return h.updateTask(args)
 	case "run_test":
@@ -214,6 +479,28 @@ func (h *ToolHandler) CallTool(name string, args map[string]interface{}) ToolRes
 		return h.moveTask(args)
 	case "delete_task":
 		return h.deleteTask(args)
+	case "list_archive":
+		return h.listArchive()
+	case "restore_task":
+		return h.restoreTask(args)
+	case "list_criteria":
+		return h.listCriteria(args)
+	case "check_criterion":
+		return h.checkCriterion(args)
+	case "add_criterion":
+		return h.addCriterion(args)
+	case "get_task_history":
+		return h.getTaskHistory(args)
+	case "get_test_history":
+		return h.getTestHistory(args)
+	case "undo_last_change":
+		return h.undoLastChange(args)
+	case "compare_branches":
+		return h.compareBranches(args)
+	case "preview_merge":
+		return h.previewMerge(args)
+	case "get_health":
+		return h.getHealth()
 	default:
 		return ToolResult{
 			Content: []ContentBlock{{Type: "text", Text: fmt.Sprintf("Unknown tool: %s", name)}},
@@ -261,6 +548,66 @@ func (h *ToolHandler) listTasks() ToolResult {
 	}
 }
 
+// defaultSearchLimit is the page size for search_tasks when no limit is given
+const defaultSearchLimit = 20
+
+func (h *ToolHandler) searchTasks(args map[string]interface{}) ToolResult {
+	query, _ := args["query"].(string)
+
+	offset, limit := 0, defaultSearchLimit
+	if o, ok := args["offset"].(float64); ok {
+		offset = int(o)
+	}
+	if l, ok := args["limit"].(float64); ok && l > 0 {
+		limit = int(l)
+	}
+
+	result, err := h.store.Search(query, offset, limit)
+	if err != nil {
+		return ToolResult{
+			Content: []ContentBlock{{Type: "text", Text: fmt.Sprintf("Search failed: %v", err)}},
+			IsError: true,
+		}
+	}
+
+	var sb strings.Builder
+	if query != "" {
+		sb.WriteString(fmt.Sprintf("# Search: %s\n\n", query))
+	} else {
+		sb.WriteString("# All Tasks\n\n")
+	}
+	if len(result.Tasks) == 0 {
+		sb.WriteString(fmt.Sprintf("No tasks found (%d matching in total).\n", result.Total))
+		return ToolResult{
+			Content: []ContentBlock{{Type: "text", Text: sb.String()}},
+		}
+	}
+
+	columnNames := make(map[string]string)
+	for _, col := range h.store.GetColumns() {
+		columnNames[col.Slug] = col.Name
+	}
+	for _, t := range result.Tasks {
+		sb.WriteString(formatTask(t))
+		name := columnNames[string(t.Column)]
+		if name == "" {
+			name = string(t.Column)
+		}
+		sb.WriteString(fmt.Sprintf("  Column: %s\n", name))
+	}
+
+	last := result.Offset + len(result.Tasks)
+	sb.WriteString(fmt.Sprintf("\nShowing %d-%d of %d matching tasks.", result.Offset+1, last, result.Total))
+	if last < result.Total {
+		sb.WriteString(fmt.Sprintf(" Use offset=%d to see more.", last))
+	}
+	sb.WriteString("\n")
+
+	return ToolResult{
+		Content: []ContentBlock{{Type: "text", Text: sb.String()}},
+	}
+}
+
 func formatTask(t *models.Task) string {
 	priorityEmoji := ""
 	switch t.Priority {
@@ -295,73 +642,222 @@ func formatTask(t *models.Task) string {
 		}
 		// Display all tests
 		if len(t.Tests) == 1 {
-			sb.WriteString(fmt.Sprintf("  Test: %s:%s\n", t.Tests[0].File, t.Tests[0].Func))
+			sb.WriteString(fmt.Sprintf("  Test: %s\n", t.Tests[0]))
 		} else {
 			sb.WriteString("  Tests:\n")
 			for _, test := range t.Tests {
-				sb.WriteString(fmt.Sprintf("    - %s:%s\n", test.File, test.Func))
+				sb.WriteString(fmt.Sprintf("    - %s\n", test))
 			}
 		}
 		sb.WriteString(fmt.Sprintf("  Status: %s\n", status))
 	}
 
+	if t.Description != "" {
+		sb.WriteString(fmt.Sprintf("  Description: %s\n", indentContinuation(t.Description, "    ")))
+	}
 	if t.AcceptanceCriteria != "" {
-		sb.WriteString(fmt.Sprintf("  Acceptance Criteria: %s\n", t.AcceptanceCriteria))
+		sb.WriteString(fmt.Sprintf("  Acceptance Criteria: %s\n", indentContinuation(t.AcceptanceCriteria, "    ")))
+	}
+	if len(t.Checklist) > 0 {
+		done, total := t.ChecklistProgress()
+		sb.WriteString(fmt.Sprintf("  Checklist: %d/%d\n", done, total))
+	}
+	if t.Parent != "" {
+		sb.WriteString(fmt.Sprintf("  Parent: %s\n", t.Parent))
+	}
+	if len(t.Children) > 0 {
+		sb.WriteString(fmt.Sprintf("  Subtasks: %d/%d done\n", t.SubtasksDone, len(t.Children)))
+	}
+	if len(t.BlockedBy) > 0 {
+		state := "all done"
+		if t.Blocked {
+			state = "blocked"
+		}
+		sb.WriteString(fmt.Sprintf("  Blocked By: %s (%s)\n", strings.Join(t.BlockedBy, ", "), state))
 	}
 
 	return sb.String()
 }
 
-func statusCheckbox(status models.TestStatus) string {
-	if status == models.TestStatusPassed {
-		return "x"
+// parseStringArray converts a JSON array argument to a non-nil slice of its
+// non-empty strings
+func parseStringArray(raw []interface{}) []string {
+	values := make([]string, 0, len(raw))
+	for _, item := range raw {
+		if value, ok := item.(string); ok && value != "" {
+			values = append(values, value)
+		}
 	}
-	return " "
+	return values
 }
 
-func (h *ToolHandler) getTask(args map[string]interface{}) ToolResult {
-	taskID, ok := args["task_id"].(string)
-	if !ok || taskID == "" {
-		return ToolResult{
-			Content: []ContentBlock{{Type: "text", Text: "task_id is required"}},
-			IsError: true,
-		}
+// formatCommits lists the git branches and commits that mention a task
+func formatCommits(task *models.Task) string {
+	var sb strings.Builder
+	if len(task.Branches) > 0 {
+		sb.WriteString(fmt.Sprintf("**Branches:** %s\n", strings.Join(task.Branches, ", ")))
 	}
-
-	task, err := h.store.Get(taskID)
-	if err != nil {
-		return ToolResult{
-			Content: []ContentBlock{{Type: "text", Text: fmt.Sprintf("Task not found: %s", taskID)}},
-			IsError: true,
+	if len(task.Commits) > 0 {
+		sb.WriteString("**Commits:**\n")
+		for _, commit := range task.Commits {
+			var flags []string
+			if commit.Closes {
+				flags = append(flags, "closes")
+			}
+			if commit.OnMainBranch {
+				flags = append(flags, "on main")
+			}
+			line := fmt.Sprintf("  - %.7s %s (%s, %s)", commit.Hash, commit.Subject, commit.Author, commit.Time.Format("2006-01-02"))
+			if len(flags) > 0 {
+				line += fmt.Sprintf(" [%s]", strings.Join(flags, ", "))
+			}
+			sb.WriteString(line + "\n")
 		}
 	}
+	return sb.String()
+}
 
+// formatTestCases lists the per-test results of a run, failures with their
+// messages
+func formatTestCases(cases []models.TestCaseResult) string {
 	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("# Task: %s\n\n", task.Title))
-	sb.WriteString(fmt.Sprintf("**ID:** %s\n", task.ID))
-	sb.WriteString(fmt.Sprintf("**Priority:** %s\n", task.Priority))
-	sb.WriteString(fmt.Sprintf("**Column:** %s\n", task.Column))
-	if len(task.Tags) > 0 {
-		sb.WriteString(fmt.Sprintf("**Tags:** %s\n", strings.Join(task.Tags, ", ")))
+	for _, c := range cases {
+		sb.WriteString(fmt.Sprintf("- %s: %s (%dms)\n", c.Name, strings.ToUpper(c.Status), c.Duration))
+		if c.Status != models.TestCasePassed && c.Message != "" {
+			for _, line := range strings.Split(c.Message, "\n") {
+				sb.WriteString("  > " + line + "\n")
+			}
+		}
 	}
-	sb.WriteString(fmt.Sprintf("**Requires Test:** %t\n", task.RequiresTest))
+	if len(cases) > 0 {
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
 
-	// Only show test info if task has tests
-	if task.HasTest() {
-		if len(task.Tests) == 1 {
-			sb.WriteString(fmt.Sprintf("**Test:** %s:%s\n", task.Tests[0].File, task.Tests[0].Func))
+// formatDependencies describes the tasks a task is blocked by and blocks
+func (h *ToolHandler) formatDependencies(task *models.Task) string {
+	var sb strings.Builder
+	if len(task.BlockedBy) > 0 {
+		if task.Blocked {
+			sb.WriteString("**Blocked By:** (blocked)\n")
 		} else {
-			sb.WriteString("**Tests:**\n")
-			for _, test := range task.Tests {
-				sb.WriteString(fmt.Sprintf("  - %s:%s\n", test.File, test.Func))
+			sb.WriteString("**Blocked By:** (all done)\n")
+		}
+		for _, id := range task.BlockedBy {
+			if blocker, err := h.store.Get(id); err == nil {
+				sb.WriteString(fmt.Sprintf("  - %s: %s [%s]\n", blocker.ID, blocker.Title, blocker.Column))
+			} else {
+				sb.WriteString(fmt.Sprintf("  - %s (not found)\n", id))
 			}
 		}
-		sb.WriteString(fmt.Sprintf("**Status:** %s\n", task.TestStatus))
 	}
-
+	if len(task.Blocks) > 0 {
+		sb.WriteString("**Blocks:**\n")
+		for _, id := range task.Blocks {
+			if blocked, err := h.store.Get(id); err == nil {
+				sb.WriteString(fmt.Sprintf("  - %s: %s [%s]\n", blocked.ID, blocked.Title, blocked.Column))
+			}
+		}
+	}
+	return sb.String()
+}
+
+// formatSubtasks describes a task's parent and subtasks
+func (h *ToolHandler) formatSubtasks(task *models.Task) string {
+	var sb strings.Builder
+	if task.Parent != "" {
+		if parent, err := h.store.Get(task.Parent); err == nil {
+			sb.WriteString(fmt.Sprintf("**Parent:** %s: %s\n", parent.ID, parent.Title))
+		}
+	}
+	if len(task.Children) > 0 {
+		sb.WriteString(fmt.Sprintf("**Subtasks (%d/%d done):**\n", task.SubtasksDone, len(task.Children)))
+		children, _ := h.store.GetChildren(task.ID)
+		for _, child := range children {
+			sb.WriteString(fmt.Sprintf("  - %s: %s [%s]\n", child.ID, child.Title, child.Column))
+		}
+	}
+	return sb.String()
+}
+
+// indentContinuation prefixes every line after the first with indent so
+// multi-line values stay nested under their label
+func indentContinuation(s, indent string) string {
+	return strings.ReplaceAll(s, "\n", "\n"+indent)
+}
+
+// textField formats a labelled free-form value, moving multi-line values
+// onto their own lines below the label
+func textField(label, value string) string {
+	if strings.Contains(value, "\n") {
+		return fmt.Sprintf("**%s:**\n%s\n", label, value)
+	}
+	return fmt.Sprintf("**%s:** %s\n", label, value)
+}
+
+func statusCheckbox(status models.TestStatus) string {
+	if status == models.TestStatusPassed {
+		return "x"
+	}
+	return " "
+}
+
+func (h *ToolHandler) getTask(args map[string]interface{}) ToolResult {
+	taskID, ok := args["task_id"].(string)
+	if !ok || taskID == "" {
+		return ToolResult{
+			Content: []ContentBlock{{Type: "text", Text: "task_id is required"}},
+			IsError: true,
+		}
+	}
+
+	task, err := h.store.Get(taskID)
+	if err != nil {
+		return ToolResult{
+			Content: []ContentBlock{{Type: "text", Text: fmt.Sprintf("Task not found: %s", taskID)}},
+			IsError: true,
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# Task: %s\n\n", task.Title))
+	sb.WriteString(fmt.Sprintf("**ID:** %s\n", task.ID))
+	sb.WriteString(fmt.Sprintf("**Priority:** %s\n", task.Priority))
+	sb.WriteString(fmt.Sprintf("**Column:** %s\n", task.Column))
+	sb.WriteString(fmt.Sprintf("**Revision:** %s\n", task.Revision()))
+	if len(task.Tags) > 0 {
+		sb.WriteString(fmt.Sprintf("**Tags:** %s\n", strings.Join(task.Tags, ", ")))
+	}
+	sb.WriteString(fmt.Sprintf("**Requires Test:** %t\n", task.RequiresTest))
+
+	// Only show test info if task has tests
+	if task.HasTest() {
+		if len(task.Tests) == 1 {
+			sb.WriteString(fmt.Sprintf("**Test:** %s\n", task.Tests[0]))
+		} else {
+			sb.WriteString("**Tests:**\n")
+			for _, test := range task.Tests {
+				sb.WriteString(fmt.Sprintf("  - %s\n", test))
+			}
+		}
+		sb.WriteString(fmt.Sprintf("**Status:** %s\n", task.TestStatus))
+	}
+
+	if task.Description != "" {
+		sb.WriteString(textField("Description", task.Description))
+	}
 	if task.AcceptanceCriteria != "" {
-		sb.WriteString(fmt.Sprintf("**Acceptance Criteria:** %s\n", task.AcceptanceCriteria))
+		sb.WriteString(textField("Acceptance Criteria", task.AcceptanceCriteria))
+	}
+	if len(task.Checklist) > 0 {
+		done, total := task.ChecklistProgress()
+		sb.WriteString(fmt.Sprintf("**Checklist (%d/%d):**\n", done, total))
+		sb.WriteString(formatChecklist(task.Checklist))
 	}
+	sb.WriteString(h.formatDependencies(task))
+	sb.WriteString(h.formatSubtasks(task))
+	sb.WriteString(formatCommits(task))
 
 	if task.HasTest() && task.LastOutput != "" {
 		sb.WriteString(fmt.Sprintf("\n## Last Test Output\n```\n%s\n```\n", task.LastOutput))
@@ -375,8 +871,10 @@ func (h *ToolHandler) getTask(args map[string]interface{}) ToolResult {
 func (h *ToolHandler) createTask(args map[string]interface{}) ToolResult {
 	title, _ := args["title"].(string)
 	acceptanceCriteria, _ := args["acceptance_criteria"].(string)
+	description, _ := args["description"].(string)
 	priorityStr, _ := args["priority"].(string)
 	requiresTest, hasRequiresTest := args["requires_test"].(bool)
+	parent, _ := args["parent"].(string)
 
 	if title == "" {
 		return ToolResult{
@@ -411,6 +909,22 @@ func (h *ToolHandler) createTask(args map[string]interface{}) ToolResult {
 		}
 	}
 
+	// Parse blocked_by array
+	var blockedBy []string
+	if blockedRaw, ok := args["blocked_by"].([]interface{}); ok {
+		blockedBy = parseStringArray(blockedRaw)
+	}
+
+	// Parse checklist items
+	var checklist []models.ChecklistItem
+	if itemsRaw, ok := args["checklist"].([]interface{}); ok {
+		for _, itemRaw := range itemsRaw {
+			if text, ok := itemRaw.(string); ok && text != "" {
+				checklist = append(checklist, models.ChecklistItem{Text: text})
+			}
+		}
+	}
+
 	// Set requires_test if provided
 	var requiresTestPtr *bool
 	if hasRequiresTest {
@@ -420,9 +934,13 @@ func (h *ToolHandler) createTask(args map[string]interface{}) ToolResult {
 	req := models.CreateTaskRequest{
 		Title:              title,
 		AcceptanceCriteria: acceptanceCriteria,
+		Description:        description,
 		Priority:           priority,
 		Tags:               tags,
 		RequiresTest:       requiresTestPtr,
+		Checklist:          checklist,
+		BlockedBy:          blockedBy,
+		Parent:             parent,
 	}
 
 	task, err := h.store.Create(req)
@@ -458,6 +976,12 @@ It can be moved to 'done' at any time without requiring a test.`,
 			task.ID, task.Title, task.Priority)
 	}
 
+	if task.Parent != "" {
+		if parentTask, err := h.store.Get(task.Parent); err == nil {
+			message += fmt.Sprintf("\n\nSubtask of: %s (%s)", parentTask.Title, parentTask.ID)
+		}
+	}
+
 	return ToolResult{
 		Content: []ContentBlock{{
 			Type: "text",
@@ -466,6 +990,33 @@ It can be moved to 'done' at any time without requiring a test.`,
 	}
 }
 
+func (h *ToolHandler) createSubtask(args map[string]interface{}) ToolResult {
+	parentID, _ := args["parent_id"].(string)
+	if parentID == "" {
+		return ToolResult{
+			Content: []ContentBlock{{Type: "text", Text: "parent_id is required"}},
+			IsError: true,
+		}
+	}
+
+	if _, err := h.store.Get(parentID); err != nil {
+		return ToolResult{
+			Content: []ContentBlock{{Type: "text", Text: fmt.Sprintf("Parent task not found: %s", parentID)}},
+			IsError: true,
+		}
+	}
+
+	// Same fields as create_task, linked to the parent
+	createArgs := make(map[string]interface{}, len(args))
+	for key, value := range args {
+		createArgs[key] = value
+	}
+	delete(createArgs, "parent_id")
+	createArgs["parent"] = parentID
+
+	return h.createTask(createArgs)
+}
+
 func (h *ToolHandler) updateTask(args map[string]interface{}) ToolResult {
 	taskID, ok := args["task_id"].(string)
 	if !ok || taskID == "" {
@@ -484,6 +1035,9 @@ func (h *ToolHandler) updateTask(args map[string]interface{}) ToolResult {
 	if criteria, ok := args["acceptance_criteria"].(string); ok {
 		req.AcceptanceCriteria = &criteria
 	}
+	if description, ok := args["description"].(string); ok {
+		req.Description = &description
+	}
 	if priorityStr, ok := args["priority"].(string); ok && priorityStr != "" {
 		var priority models.Priority
 		switch priorityStr {
@@ -504,6 +1058,9 @@ func (h *ToolHandler) updateTask(args map[string]interface{}) ToolResult {
 	if requiresTest, ok := args["requires_test"].(bool); ok {
 		req.RequiresTest = &requiresTest
 	}
+	if revision, ok := args["expected_revision"].(string); ok {
+		req.ExpectedRevision = revision
+	}
 	// Parse tags array
 	if tagsRaw, ok := args["tags"].([]interface{}); ok {
 		var tags []string
@@ -514,6 +1071,13 @@ func (h *ToolHandler) updateTask(args map[string]interface{}) ToolResult {
 		}
 		req.Tags = tags
 	}
+	if parent, ok := args["parent"].(string); ok {
+		req.Parent = &parent
+	}
+	// Parse blocked_by array (an empty array clears it)
+	if blockedRaw, ok := args["blocked_by"].([]interface{}); ok {
+		req.BlockedBy = parseStringArray(blockedRaw)
+	}
 	// Parse tests array
 	if testsRaw, ok := args["tests"].([]interface{}); ok {
 		tests := make([]models.TestSpec, 0, len(testsRaw))
@@ -521,8 +1085,9 @@ func (h *ToolHandler) updateTask(args map[string]interface{}) ToolResult {
 			if testMap, ok := testRaw.(map[string]interface{}); ok {
 				file, _ := testMap["file"].(string)
 				fn, _ := testMap["func"].(string)
+				runner, _ := testMap["runner"].(string)
 				if file != "" && fn != "" {
-					tests = append(tests, models.TestSpec{File: file, Func: fn})
+					tests = append(tests, models.TestSpec{File: file, Func: fn, Runner: runner})
 				}
 			}
 		}
@@ -533,6 +1098,12 @@ func (h *ToolHandler) updateTask(args map[string]interface{}) ToolResult {
 
 	task, err := h.store.Update(taskID, req)
 	if err != nil {
+		if errors.Is(err, services.ErrRevisionConflict) {
+			return ToolResult{
+				Content: []ContentBlock{{Type: "text", Text: fmt.Sprintf("Failed to update task: %v\n\nThe task was modified by someone else. Use get_task to read the current version, then retry with its revision.", err)}},
+				IsError: true,
+			}
+		}
 		return ToolResult{
 			Content: []ContentBlock{{Type: "text", Text: fmt.Sprintf("Failed to update task: %v", err)}},
 			IsError: true,
@@ -542,16 +1113,17 @@ func (h *ToolHandler) updateTask(args map[string]interface{}) ToolResult {
 	var sb strings.Builder
 	sb.WriteString("Task updated successfully!\n\n")
 	sb.WriteString(fmt.Sprintf("**ID:** %s\n", task.ID))
+	sb.WriteString(fmt.Sprintf("**Revision:** %s\n", task.Revision()))
 	sb.WriteString(fmt.Sprintf("**Title:** %s\n", task.Title))
 	sb.WriteString(fmt.Sprintf("**Priority:** %s\n", task.Priority))
 	sb.WriteString(fmt.Sprintf("**Requires Test:** %t\n", task.RequiresTest))
 	if task.HasTest() {
 		if len(task.Tests) == 1 {
-			sb.WriteString(fmt.Sprintf("**Test:** %s:%s\n", task.Tests[0].File, task.Tests[0].Func))
+			sb.WriteString(fmt.Sprintf("**Test:** %s\n", task.Tests[0]))
 		} else {
 			sb.WriteString("**Tests:**\n")
 			for _, test := range task.Tests {
-				sb.WriteString(fmt.Sprintf("  - %s:%s\n", test.File, test.Func))
+				sb.WriteString(fmt.Sprintf("  - %s\n", test))
 			}
 		}
 	}
@@ -619,7 +1191,7 @@ func (h *ToolHandler) runTest(args map[string]interface{}) ToolResult {
 	for i, result := range results.Results {
 		var testName string
 		if i < len(task.Tests) {
-			testName = fmt.Sprintf("%s:%s", task.Tests[i].File, task.Tests[i].Func)
+			testName = task.Tests[i].String()
 		} else {
 			testName = fmt.Sprintf("Test %d", i+1)
 		}
@@ -629,6 +1201,7 @@ func (h *ToolHandler) runTest(args map[string]interface{}) ToolResult {
 			status = "FAILED"
 		}
 		sb.WriteString(fmt.Sprintf("### %s - %s (%dms)\n", testName, status, result.RunTime))
+		sb.WriteString(formatTestCases(result.Cases))
 		sb.WriteString("```\n")
 		sb.WriteString(result.Output)
 		sb.WriteString("\n```\n")
@@ -638,6 +1211,17 @@ func (h *ToolHandler) runTest(args map[string]interface{}) ToolResult {
 		sb.WriteString("\n")
 	}
 
+	// Failures of known flaky tests may have nothing to do with the change
+	if !results.AllPassed {
+		if history, err := h.store.GetTestHistory(taskID); err == nil {
+			for _, stats := range history.Tests {
+				if stats.Flaky && !stats.LastPassed {
+					sb.WriteString(fmt.Sprintf("**Note:** %s is flaky: it flipped %d times in its last %d runs on unchanged code. Re-run it before debugging, and see get_test_history.\n", stats.Test, stats.Flips, stats.Runs))
+				}
+			}
+		}
+	}
+
 	return ToolResult{
 		Content: []ContentBlock{{Type: "text", Text: sb.String()}},
 	}
@@ -748,7 +1332,441 @@ func (h *ToolHandler) deleteTask(args map[string]interface{}) ToolResult {
 	return ToolResult{
 		Content: []ContentBlock{{
 			Type: "text",
-			Text: fmt.Sprintf("Task '%s' has been deleted.", title),
+			Text: fmt.Sprintf("Task '%s' has been deleted and moved to the archive. Use restore_task with ID %s to bring it back.", title, taskID),
 		}},
 	}
 }
+
+func (h *ToolHandler) listArchive() ToolResult {
+	tasks, err := h.store.GetArchive()
+	if err != nil {
+		return ToolResult{
+			Content: []ContentBlock{{Type: "text", Text: fmt.Sprintf("Failed to read archive: %v", err)}},
+			IsError: true,
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("# Archive\n\n")
+	if len(tasks) == 0 {
+		sb.WriteString("(no archived tasks)\n")
+	}
+	for _, t := range tasks {
+		sb.WriteString(fmt.Sprintf("- **%s** (ID: %s) from %s", t.Title, t.ID, t.Column))
+		if t.ArchivedAt != nil {
+			sb.WriteString(fmt.Sprintf(", archived %s", t.ArchivedAt.Format(time.RFC3339)))
+		}
+		sb.WriteString("\n")
+	}
+
+	return ToolResult{
+		Content: []ContentBlock{{Type: "text", Text: sb.String()}},
+	}
+}
+
+func (h *ToolHandler) restoreTask(args map[string]interface{}) ToolResult {
+	taskID, ok := args["task_id"].(string)
+	if !ok || taskID == "" {
+		return ToolResult{
+			Content: []ContentBlock{{Type: "text", Text: "task_id is required"}},
+			IsError: true,
+		}
+	}
+
+	task, err := h.store.RestoreTask(taskID, "")
+	if err != nil {
+		return ToolResult{
+			Content: []ContentBlock{{Type: "text", Text: fmt.Sprintf("Failed to restore task: %v", err)}},
+			IsError: true,
+		}
+	}
+
+	return ToolResult{
+		Content: []ContentBlock{{
+			Type: "text",
+			Text: fmt.Sprintf("Task '%s' has been restored to %s.", task.Title, task.Column),
+		}},
+	}
+}
+
+// formatChecklist renders checklist items as numbered checkboxes
+func formatChecklist(items []models.ChecklistItem) string {
+	var sb strings.Builder
+	for i, item := range items {
+		check := " "
+		if item.Done {
+			check = "x"
+		}
+		sb.WriteString(fmt.Sprintf("%d. [%s] %s\n", i+1, check, item.Text))
+	}
+	return sb.String()
+}
+
+func (h *ToolHandler) listCriteria(args map[string]interface{}) ToolResult {
+	taskID, ok := args["task_id"].(string)
+	if !ok || taskID == "" {
+		return ToolResult{
+			Content: []ContentBlock{{Type: "text", Text: "task_id is required"}},
+			IsError: true,
+		}
+	}
+
+	task, err := h.store.Get(taskID)
+	if err != nil {
+		return ToolResult{
+			Content: []ContentBlock{{Type: "text", Text: fmt.Sprintf("Task not found: %s", taskID)}},
+			IsError: true,
+		}
+	}
+
+	if len(task.Checklist) == 0 {
+		return ToolResult{
+			Content: []ContentBlock{{Type: "text", Text: fmt.Sprintf("Task '%s' has no checklist items. Use add_criterion to add some.", task.Title)}},
+		}
+	}
+
+	done, total := task.ChecklistProgress()
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# Checklist: %s (%d/%d done)\n\n", task.Title, done, total))
+	sb.WriteString(formatChecklist(task.Checklist))
+
+	return ToolResult{
+		Content: []ContentBlock{{Type: "text", Text: sb.String()}},
+	}
+}
+
+func (h *ToolHandler) checkCriterion(args map[string]interface{}) ToolResult {
+	taskID, ok := args["task_id"].(string)
+	if !ok || taskID == "" {
+		return ToolResult{
+			Content: []ContentBlock{{Type: "text", Text: "task_id is required"}},
+			IsError: true,
+		}
+	}
+
+	item, ok := args["item"].(float64)
+	if !ok {
+		return ToolResult{
+			Content: []ContentBlock{{Type: "text", Text: "item is required"}},
+			IsError: true,
+		}
+	}
+
+	done := true
+	if d, ok := args["done"].(bool); ok {
+		done = d
+	}
+
+	task, err := h.store.UpdateChecklistItem(taskID, int(item), models.UpdateChecklistItemRequest{Done: &done})
+	if err != nil {
+		return ToolResult{
+			Content: []ContentBlock{{Type: "text", Text: fmt.Sprintf("Failed to update checklist: %v", err)}},
+			IsError: true,
+		}
+	}
+
+	completed, total := task.ChecklistProgress()
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Checklist updated (%d/%d done).\n\n", completed, total))
+	sb.WriteString(formatChecklist(task.Checklist))
+
+	return ToolResult{
+		Content: []ContentBlock{{Type: "text", Text: sb.String()}},
+	}
+}
+
+func (h *ToolHandler) addCriterion(args map[string]interface{}) ToolResult {
+	taskID, ok := args["task_id"].(string)
+	if !ok || taskID == "" {
+		return ToolResult{
+			Content: []ContentBlock{{Type: "text", Text: "task_id is required"}},
+			IsError: true,
+		}
+	}
+
+	text, _ := args["text"].(string)
+	if strings.TrimSpace(text) == "" {
+		return ToolResult{
+			Content: []ContentBlock{{Type: "text", Text: "text is required"}},
+			IsError: true,
+		}
+	}
+
+	task, err := h.store.AddChecklistItem(taskID, text, "")
+	if err != nil {
+		return ToolResult{
+			Content: []ContentBlock{{Type: "text", Text: fmt.Sprintf("Failed to add checklist item: %v", err)}},
+			IsError: true,
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Added item %d to '%s'.\n\n", len(task.Checklist), task.Title))
+	sb.WriteString(formatChecklist(task.Checklist))
+
+	return ToolResult{
+		Content: []ContentBlock{{Type: "text", Text: sb.String()}},
+	}
+}
+
+func (h *ToolHandler) getTaskHistory(args map[string]interface{}) ToolResult {
+	taskID, ok := args["task_id"].(string)
+	if !ok || taskID == "" {
+		return ToolResult{
+			Content: []ContentBlock{{Type: "text", Text: "task_id is required"}},
+			IsError: true,
+		}
+	}
+
+	events, err := h.store.GetHistory(taskID)
+	if err != nil {
+		return ToolResult{
+			Content: []ContentBlock{{Type: "text", Text: fmt.Sprintf("Failed to get history: %v", err)}},
+			IsError: true,
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# History: %s\n\n", taskID))
+	if len(events) == 0 {
+		sb.WriteString("No history recorded yet.\n")
+	}
+	for _, event := range events {
+		sb.WriteString(fmt.Sprintf("- %s %s", event.Time.Format(time.RFC3339), formatHistoryEvent(event)))
+		if event.Author != "" {
+			sb.WriteString(fmt.Sprintf(" (by %s)", event.Author))
+		}
+		sb.WriteString("\n")
+	}
+
+	return ToolResult{
+		Content: []ContentBlock{{Type: "text", Text: sb.String()}},
+	}
+}
+
+func (h *ToolHandler) getTestHistory(args map[string]interface{}) ToolResult {
+	taskID, ok := args["task_id"].(string)
+	if !ok || taskID == "" {
+		return ToolResult{
+			Content: []ContentBlock{{Type: "text", Text: "task_id is required"}},
+			IsError: true,
+		}
+	}
+
+	history, err := h.store.GetTestHistory(taskID)
+	if err != nil {
+		return ToolResult{
+			Content: []ContentBlock{{Type: "text", Text: fmt.Sprintf("Failed to get test history: %v", err)}},
+			IsError: true,
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# Test History: %s\n\n", taskID))
+	if len(history.Runs) == 0 {
+		sb.WriteString("No test runs recorded yet.\n")
+	}
+	for _, stats := range history.Tests {
+		sb.WriteString(fmt.Sprintf("## %s\n", stats.Test))
+		if stats.Runs == 0 {
+			sb.WriteString("Not run yet.\n\n")
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("**Passed:** %d/%d recent runs (avg %dms)\n", stats.Passed, stats.Runs, stats.AvgDuration))
+		if stats.Flaky {
+			sb.WriteString(fmt.Sprintf("**FLAKY:** flipped %d times on unchanged code (flakiness %.0f%%)\n", stats.Flips, stats.Flakiness*100))
+		}
+		sb.WriteString("**All runs:** ")
+		for _, run := range history.Runs {
+			if run.Test != stats.Test {
+				continue
+			}
+			if run.Passed {
+				sb.WriteString("P")
+			} else {
+				sb.WriteString("F")
+			}
+		}
+		sb.WriteString(" (oldest first)\n\n")
+	}
+
+	return ToolResult{
+		Content: []ContentBlock{{Type: "text", Text: sb.String()}},
+	}
+}
+
+// formatHistoryEvent describes a history event in one line
+func formatHistoryEvent(event models.HistoryEvent) string {
+	switch event.Type {
+	case models.HistoryCreated:
+		return fmt.Sprintf("Created: %s", event.Summary)
+	case models.HistoryDeleted:
+		return fmt.Sprintf("Deleted: %s", event.Summary)
+	case models.HistoryArchived:
+		return fmt.Sprintf("Archived from %s: %s", event.From, event.Summary)
+	case models.HistoryRestored:
+		return fmt.Sprintf("Restored to %s: %s", event.To, event.Summary)
+	case models.HistoryMoved:
+		return fmt.Sprintf("Moved from %s to %s", event.From, event.To)
+	case models.HistoryTestRun:
+		return fmt.Sprintf("Tests %s (%s)", event.To, event.Summary)
+	case models.HistoryAIStarted:
+		return "AI started working on the task"
+	case models.HistoryAIStopped:
+		return "AI stopped working on the task"
+	case models.HistoryUpdated:
+		if event.Summary != "" {
+			return fmt.Sprintf("Updated %s: %s", event.Field, event.Summary)
+		}
+		return fmt.Sprintf("Updated %s: %s -> %s", event.Field, quoteHistoryValue(event.From), quoteHistoryValue(event.To))
+	}
+	return string(event.Type)
+}
+
+// quoteHistoryValue shortens a field value to fit on one history line
+func quoteHistoryValue(value string) string {
+	const maxLen = 80
+	if value == "" {
+		return "(empty)"
+	}
+	value = strings.ReplaceAll(value, "\n", " / ")
+	if runes := []rune(value); len(runes) > maxLen {
+		value = string(runes[:maxLen]) + "..."
+	}
+	return fmt.Sprintf("%q", value)
+}
+
+func (h *ToolHandler) undoLastChange(args map[string]interface{}) ToolResult {
+	action, undo := "undo", h.store.Undo
+	if redo, _ := args["redo"].(bool); redo {
+		action, undo = "redo", h.store.Redo
+	}
+
+	result, err := undo()
+	if err != nil {
+		return ToolResult{
+			Content: []ContentBlock{{Type: "text", Text: fmt.Sprintf("Could not %s: %v", action, err)}},
+			IsError: true,
+		}
+	}
+
+	verb := "Undid"
+	if result.Action == "redo" {
+		verb = "Redid"
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s: %s\n\n", verb, result.Description))
+	sb.WriteString(fmt.Sprintf("**Tasks restored:** %s\n", strings.Join(result.TaskIDs, ", ")))
+	sb.WriteString(fmt.Sprintf("**More to undo:** %t\n", result.CanUndo))
+	sb.WriteString(fmt.Sprintf("**Can redo:** %t\n", result.CanRedo))
+
+	return ToolResult{
+		Content: []ContentBlock{{Type: "text", Text: sb.String()}},
+	}
+}
+
+func (h *ToolHandler) compareBranches(args map[string]interface{}) ToolResult {
+	base, _ := args["base"].(string)
+	head, _ := args["head"].(string)
+	if base == "" || head == "" {
+		return ToolResult{
+			Content: []ContentBlock{{Type: "text", Text: "base and head are required"}},
+			IsError: true,
+		}
+	}
+
+	comparison, err := h.store.CompareBranches(base, head)
+	if err != nil {
+		return ToolResult{
+			Content: []ContentBlock{{Type: "text", Text: fmt.Sprintf("Failed to compare boards: %v", err)}},
+			IsError: true,
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# Board changes from %s to %s\n\n", base, head))
+	if len(comparison.Tasks) == 0 {
+		sb.WriteString("(no differences)\n")
+	}
+	sb.WriteString(formatTaskDiffs(comparison.Tasks))
+
+	return ToolResult{
+		Content: []ContentBlock{{Type: "text", Text: sb.String()}},
+	}
+}
+
+func (h *ToolHandler) previewMerge(args map[string]interface{}) ToolResult {
+	ref, _ := args["ref"].(string)
+	if ref == "" {
+		return ToolResult{
+			Content: []ContentBlock{{Type: "text", Text: "ref is required"}},
+			IsError: true,
+		}
+	}
+
+	preview, err := h.store.PreviewMerge(ref)
+	if err != nil {
+		return ToolResult{
+			Content: []ContentBlock{{Type: "text", Text: fmt.Sprintf("Failed to preview merge: %v", err)}},
+			IsError: true,
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("# Merge preview: %s\n\n", ref))
+	if len(preview.Changes) == 0 {
+		sb.WriteString("The merge would not change the board.\n")
+	}
+	sb.WriteString(formatTaskDiffs(preview.Changes))
+	if len(preview.Conflicts) > 0 {
+		sb.WriteString("\n## Conflicts\n")
+		sb.WriteString("Changed on both sides; these must be resolved by hand.\n")
+		for _, c := range preview.Conflicts {
+			sb.WriteString(fmt.Sprintf("- %s %s: base %q, current %q, %s %q\n", c.TaskID, c.Field, c.Base, c.Ours, ref, c.Theirs))
+		}
+	}
+
+	return ToolResult{
+		Content: []ContentBlock{{Type: "text", Text: sb.String()}},
+	}
+}
+
+// formatTaskDiffs lists added, removed and changed tasks with their changed fields
+func formatTaskDiffs(diffs []models.TaskDiff) string {
+	var sb strings.Builder
+	for _, d := range diffs {
+		sb.WriteString(fmt.Sprintf("- %s %s: %s\n", d.Change, d.TaskID, d.Title))
+		for _, f := range d.Fields {
+			sb.WriteString(fmt.Sprintf("  - %s: %q -> %q\n", f.Field, f.From, f.To))
+		}
+	}
+	return sb.String()
+}
+
+func (h *ToolHandler) getHealth() ToolResult {
+	status := h.store.GetSaveStatus()
+
+	var sb strings.Builder
+	if status.Healthy() {
+		sb.WriteString("# Board Health: OK\n\n")
+	} else {
+		sb.WriteString("# Board Health: DEGRADED\n\n")
+	}
+	sb.WriteString(fmt.Sprintf("**Durable Writes:** %t\n", status.Durable))
+	if status.LastSavedAt != nil {
+		sb.WriteString(fmt.Sprintf("**Last Successful Save:** %s\n", status.LastSavedAt.Format(time.RFC3339)))
+	} else {
+		sb.WriteString("**Last Successful Save:** never\n")
+	}
+	if !status.Healthy() {
+		sb.WriteString(fmt.Sprintf("**Last Save Error:** %s\n", status.LastError))
+		if status.LastErrorAt != nil {
+			sb.WriteString(fmt.Sprintf("**Last Error At:** %s\n", status.LastErrorAt.Format(time.RFC3339)))
+		}
+	}
+
+	return ToolResult{
+		Content: []ContentBlock{{Type: "text", Text: sb.String()}},
+		IsError: !status.Healthy(),
+	}
+}
@@ -1,6 +1,10 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
 	"strings"
 	"time"
 )
@@ -16,9 +20,19 @@ const (
 
 // ColumnDefinition represents a column with its display name and order
 type ColumnDefinition struct {
-	Slug  string `json:"slug"`
-	Name  string `json:"name"`
-	Order int    `json:"order"`
+	Slug       string       `json:"slug"`
+	Name       string       `json:"name"`
+	Order      int          `json:"order"`
+	ExtraLines []string     `json:"-"` // Unrecognised content between the column header and its first task, written back verbatim
+	Notes      []ColumnNote `json:"-"` // Unrecognised content between the column's tasks
+	Source     string       `json:"-"` // Included file the column comes from, if the main tasks file does not have it
+}
+
+// ColumnNote is unrecognised content that follows a task in a column. It is
+// written back after that task, and stays in the column if the task leaves.
+type ColumnNote struct {
+	After string   // ID of the task the note follows
+	Lines []string // The note, verbatim
 }
 
 // DefaultColumns defines the columns that must always exist
@@ -66,60 +80,133 @@ const (
 
 // Task represents a TDD task with an associated test
 type Task struct {
-	ID                 string     `json:"id"`
-	Title              string     `json:"title"`
-	AcceptanceCriteria string     `json:"acceptance_criteria"`
-	Priority           Priority   `json:"priority"`
-	Column             Column     `json:"column"`
-	Tags               []string   `json:"tags"`                // Array of tags for categorization
-	RequiresTest       bool       `json:"requires_test"`       // Whether task completion requires a passing test
-	Tests              []TestSpec `json:"tests"`               // Array of test specifications
-	TestStatus         TestStatus `json:"test_status"`
-	TestsPassed        int        `json:"tests_passed"`        // Number of tests that passed in last run
-	TestsTotal         int        `json:"tests_total"`         // Total number of tests in last run
-	LastRun            *time.Time `json:"last_run,omitempty"`
-	LastOutput         string     `json:"last_output,omitempty"`
-	Order              int        `json:"-"` // Internal order tracking, not exposed to JSON
-	CreatedAt          time.Time  `json:"created_at"`
-	CreatedBy          string     `json:"created_by"`
-	UpdatedAt          time.Time  `json:"updated_at"`
-	UpdatedBy          string     `json:"updated_by"`
+	ID                 string          `json:"id"`
+	Title              string          `json:"title"`
+	AcceptanceCriteria string          `json:"acceptance_criteria"`
+	Description        string          `json:"description"` // Free-form notes and context; may span multiple lines
+	Checklist          []ChecklistItem `json:"checklist"`   // Acceptance-criteria items, each ticked off individually
+	Priority           Priority        `json:"priority"`
+	Column             Column          `json:"column"`
+	Tags               []string        `json:"tags"`          // Array of tags for categorization
+	RequiresTest       bool            `json:"requires_test"` // Whether task completion requires a passing test
+	Tests              []TestSpec      `json:"tests"`         // Array of test specifications
+	BlockedBy          []string        `json:"blocked_by"`    // IDs of tasks that must be done before this one can start
+	Blocks             []string        `json:"blocks"`        // IDs of tasks blocked by this one (derived from their blocked_by)
+	Blocked            bool            `json:"blocked"`       // True while any blocked_by task is not done (derived)
+	Parent             string          `json:"parent"`        // ID of the parent task, empty for top-level tasks
+	Children           []string        `json:"children"`      // IDs of subtasks in board order (derived from their parent)
+	SubtasksDone       int             `json:"subtasks_done"` // Number of subtasks that are done (derived)
+	TestStatus         TestStatus      `json:"test_status"`
+	TestsPassed        int             `json:"tests_passed"` // Number of tests that passed in last run
+	TestsTotal         int             `json:"tests_total"`  // Total number of tests in last run
+	LastRun            *time.Time      `json:"last_run,omitempty"`
+	LastOutput         string          `json:"last_output,omitempty"`
+	Order              int             `json:"-"` // Internal order tracking, not exposed to JSON
+	CreatedAt          time.Time       `json:"created_at"`
+	CreatedBy          string          `json:"created_by"`
+	UpdatedAt          time.Time       `json:"updated_at"`
+	UpdatedBy          string          `json:"updated_by"`
+	ArchivedAt         *time.Time      `json:"archived_at,omitempty"` // Set while the task is in the archive
+	Source             string          `json:"source,omitempty"`      // Included file the task is stored in, empty for the board's own file
+	Commits            []TaskCommit    `json:"commits,omitempty"`     // Git commits mentioning the task, newest first (derived)
+	Branches           []string        `json:"branches,omitempty"`    // Local git branches named after the task (derived)
+	ExtraLines         []string        `json:"-"`                     // Unrecognised metadata and notes attached to the task, written back verbatim
+	MetadataKeys       []string        `json:"-"`                     // Metadata keys in the order they were read, so unknown ones are written back in place
+}
+
+// TaskCommit is a git commit whose message mentions a task
+type TaskCommit struct {
+	Hash         string    `json:"hash"`
+	Author       string    `json:"author"`
+	Time         time.Time `json:"time"`
+	Subject      string    `json:"subject"`
+	Closes       bool      `json:"closes"`         // The message closes the task, e.g. "Closes task-AB12CD34"
+	OnMainBranch bool      `json:"on_main_branch"` // The commit has landed on the main branch
 }
 
 // CreateTaskRequest is the request body for creating a task
 type CreateTaskRequest struct {
-	Title              string   `json:"title"`
-	AcceptanceCriteria string   `json:"acceptance_criteria"`
-	Priority           Priority `json:"priority"`
-	Tags               []string `json:"tags,omitempty"`          // Optional: array of tags for categorization
-	RequiresTest       *bool    `json:"requires_test,omitempty"` // Optional: whether task requires a passing test (default: false)
-	Author             string   `json:"author,omitempty"`        // Optional: who is creating this task
+	Title              string          `json:"title"`
+	AcceptanceCriteria string          `json:"acceptance_criteria"`
+	Description        string          `json:"description,omitempty"` // Optional: free-form description
+	Priority           Priority        `json:"priority"`
+	Tags               []string        `json:"tags,omitempty"`          // Optional: array of tags for categorization
+	RequiresTest       *bool           `json:"requires_test,omitempty"` // Optional: whether task requires a passing test (default: false)
+	Checklist          []ChecklistItem `json:"checklist,omitempty"`     // Optional: acceptance-criteria checklist items
+	BlockedBy          []string        `json:"blocked_by,omitempty"`    // Optional: IDs of tasks that block this one
+	Parent             string          `json:"parent,omitempty"`        // Optional: ID of the parent task, making this a subtask
+	Author             string          `json:"author,omitempty"`        // Optional: who is creating this task
 }
 
 // UpdateTaskRequest is the request body for updating a task
 type UpdateTaskRequest struct {
-	Title              *string    `json:"title,omitempty"`
-	AcceptanceCriteria *string    `json:"acceptance_criteria,omitempty"`
-	Priority           *Priority  `json:"priority,omitempty"`
-	Column             *Column    `json:"column,omitempty"`
-	Tags               []string   `json:"tags,omitempty"`         // Optional: array of tags for categorization
-	RequiresTest       *bool      `json:"requires_test,omitempty"` // Optional: whether task requires a passing test
-	Tests              []TestSpec `json:"tests,omitempty"`         // Optional: array of test specifications
-	Author             string     `json:"author,omitempty"`        // Optional: who is updating this task
+	Title              *string         `json:"title,omitempty"`
+	AcceptanceCriteria *string         `json:"acceptance_criteria,omitempty"`
+	Description        *string         `json:"description,omitempty"`
+	Priority           *Priority       `json:"priority,omitempty"`
+	Column             *Column         `json:"column,omitempty"`
+	Tags               []string        `json:"tags,omitempty"`              // Optional: array of tags for categorization
+	RequiresTest       *bool           `json:"requires_test,omitempty"`     // Optional: whether task requires a passing test
+	Tests              []TestSpec      `json:"tests,omitempty"`             // Optional: array of test specifications
+	Checklist          []ChecklistItem `json:"checklist,omitempty"`         // Optional: replaces the acceptance-criteria checklist
+	BlockedBy          []string        `json:"blocked_by,omitempty"`        // Optional: replaces the IDs of tasks that block this one (empty clears)
+	Parent             *string         `json:"parent,omitempty"`            // Optional: new parent task ID (empty makes it a top-level task)
+	Author             string          `json:"author,omitempty"`            // Optional: who is updating this task
+	ExpectedRevision   string          `json:"expected_revision,omitempty"` // Optional: reject the update if the task's revision differs
+}
+
+// ChecklistItem is a single acceptance criterion with its own done state
+type ChecklistItem struct {
+	Text string `json:"text"`
+	Done bool   `json:"done"`
+}
+
+// UpdateChecklistItemRequest is the request body for changing a checklist item
+type UpdateChecklistItemRequest struct {
+	Text   *string `json:"text,omitempty"`
+	Done   *bool   `json:"done,omitempty"`
+	Author string  `json:"author,omitempty"` // Optional: who is updating this item
 }
 
 // TestSpec represents a single test file and function pair
 type TestSpec struct {
-	File string `json:"file"` // Path to test file relative to working dir (e.g., "internal/auth/auth_test.go")
-	Func string `json:"func"` // Test function name (e.g., "TestLogin")
+	File   string `json:"file"`             // Path to test file relative to working dir (e.g., "internal/auth/auth_test.go")
+	Func   string `json:"func"`             // Test function name (e.g., "TestLogin")
+	Runner string `json:"runner,omitempty"` // Test runner profile, empty to choose one by file
+}
+
+// String returns the spec as written in a tasks file, e.g. "auth_test.go:TestLogin",
+// prefixed with "@profile " if it names a runner profile
+func (t TestSpec) String() string {
+	if t.Runner != "" {
+		return "@" + t.Runner + " " + t.File + ":" + t.Func
+	}
+	return t.File + ":" + t.Func
 }
 
 // TestResult represents the result of running a test
 type TestResult struct {
-	Passed  bool   `json:"passed"`
-	Output  string `json:"output"`
-	Error   string `json:"error,omitempty"`
-	RunTime int64  `json:"run_time_ms"`
+	Passed  bool             `json:"passed"`
+	Output  string           `json:"output"`
+	Error   string           `json:"error,omitempty"`
+	RunTime int64            `json:"run_time_ms"`
+	Cases   []TestCaseResult `json:"cases,omitempty"` // Per-test results, when the runner's result format is structured
+}
+
+// Test case statuses reported by structured test result formats
+const (
+	TestCasePassed  = "pass"
+	TestCaseFailed  = "fail"
+	TestCaseSkipped = "skip"
+)
+
+// TestCaseResult is the result of one test (or subtest) within a test run
+type TestCaseResult struct {
+	Name     string `json:"name"`
+	Suite    string `json:"suite,omitempty"` // Package, class or suite the test belongs to
+	Status   string `json:"status"`          // pass, fail or skip
+	Duration int64  `json:"duration_ms"`
+	Message  string `json:"message,omitempty"` // Failure message or skip reason
 }
 
 // TestResults represents the aggregated result of running multiple tests
@@ -127,6 +214,8 @@ type TestResults struct {
 	AllPassed bool         `json:"all_passed"`
 	Results   []TestResult `json:"results"`
 	TotalTime int64        `json:"total_time_ms"`
+	Commit    string       `json:"commit,omitempty"`  // HEAD when the tests ran
+	Changes   string       `json:"changes,omitempty"` // Hash of uncommitted changes the tests ran with, empty if none
 }
 
 // HasTest returns true if the task has at least one test associated with it
@@ -134,6 +223,63 @@ func (t *Task) HasTest() bool {
 	return len(t.Tests) > 0
 }
 
+// Revision returns a short content hash of the task's persisted fields.
+// It changes whenever the task is modified, whether through the API or by
+// editing TASKS.md directly, and is used for optimistic concurrency (ETags).
+func (t *Task) Revision() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "title=%s\n", t.Title)
+	fmt.Fprintf(&sb, "criteria=%q\n", t.AcceptanceCriteria)
+	fmt.Fprintf(&sb, "description=%q\n", t.Description)
+	for _, item := range t.Checklist {
+		fmt.Fprintf(&sb, "checklist=%t:%s\n", item.Done, item.Text)
+	}
+	fmt.Fprintf(&sb, "blocked_by=%s\n", strings.Join(t.BlockedBy, ","))
+	fmt.Fprintf(&sb, "parent=%s\n", t.Parent)
+	fmt.Fprintf(&sb, "priority=%s\n", t.Priority)
+	fmt.Fprintf(&sb, "column=%s\n", t.Column)
+	fmt.Fprintf(&sb, "tags=%s\n", strings.Join(t.Tags, ","))
+	fmt.Fprintf(&sb, "requires_test=%t\n", t.RequiresTest)
+	for _, test := range t.Tests {
+		fmt.Fprintf(&sb, "test=%s\n", test)
+	}
+	fmt.Fprintf(&sb, "status=%s\n", t.CheckboxChar())
+	fmt.Fprintf(&sb, "tests=%d/%d\n", t.TestsPassed, t.TestsTotal)
+	for _, line := range t.ExtraLines {
+		fmt.Fprintf(&sb, "extra=%s\n", line)
+	}
+	// Timestamps use the same second precision as TASKS.md so the revision
+	// is stable across a save and reload. Authors are left out because they
+	// are refreshed from git history without the task itself changing.
+	fmt.Fprintf(&sb, "created=%s\n", t.CreatedAt.UTC().Format("2006-01-02T15:04:05Z"))
+	fmt.Fprintf(&sb, "updated=%s\n", t.UpdatedAt.UTC().Format("2006-01-02T15:04:05Z"))
+
+	sum := sha256.Sum256([]byte(sb.String()))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ChecklistProgress returns the number of completed checklist items and the total
+func (t *Task) ChecklistProgress() (done, total int) {
+	for _, item := range t.Checklist {
+		if item.Done {
+			done++
+		}
+	}
+	return done, len(t.Checklist)
+}
+
+// MarshalJSON includes the computed revision alongside the task fields
+func (t Task) MarshalJSON() ([]byte, error) {
+	type taskFields Task
+	return json.Marshal(struct {
+		taskFields
+		Revision string `json:"revision"`
+	}{
+		taskFields: taskFields(t),
+		Revision:   t.Revision(),
+	})
+}
+
 // CheckboxChar returns the markdown checkbox character for this task's test status.
 func (t *Task) CheckboxChar() string {
 	switch t.TestStatus {
@@ -168,7 +314,7 @@ type AIQueueState struct {
 
 // ChatMessage represents a message in the LLM conversation
 type ChatMessage struct {
-	Role      string    `json:"role"`      // "user", "assistant", "system"
+	Role      string    `json:"role"` // "user", "assistant", "system"
 	Content   string    `json:"content"`
 	Timestamp time.Time `json:"timestamp"`
 }
@@ -180,6 +326,165 @@ type AISession struct {
 	Started  time.Time     `json:"started"`
 }
 
+// MergeConflict describes a change to TASKS.md on disk that could not be
+// merged automatically with unsaved in-memory changes
+type MergeConflict struct {
+	TaskID     string `json:"task_id,omitempty"` // Empty for board-level conflicts (columns, settings)
+	Title      string `json:"title,omitempty"`
+	Field      string `json:"field"`      // Conflicting field, or "task" when one side deleted the task
+	Base       string `json:"base"`       // Value at the last load/save
+	Ours       string `json:"ours"`       // In-memory value
+	Theirs     string `json:"theirs"`     // Value on disk
+	Resolution string `json:"resolution"` // "kept_ours", "kept_theirs", "merged", or "unresolved" in a merge preview
+}
+
+// HistoryEventType identifies the kind of change recorded in the history log
+type HistoryEventType string
+
+const (
+	HistoryCreated   HistoryEventType = "created"
+	HistoryUpdated   HistoryEventType = "updated" // A single field changed
+	HistoryMoved     HistoryEventType = "moved"   // The task changed column
+	HistoryDeleted   HistoryEventType = "deleted" // Permanently removed from the archive
+	HistoryArchived  HistoryEventType = "archived"
+	HistoryRestored  HistoryEventType = "restored" // Brought back from the archive
+	HistoryTestRun   HistoryEventType = "test_run"
+	HistoryAIStarted HistoryEventType = "ai_started"
+	HistoryAIStopped HistoryEventType = "ai_stopped"
+)
+
+// HistoryEvent is one entry in the append-only task history log
+type HistoryEvent struct {
+	Time    time.Time        `json:"time"`
+	TaskID  string           `json:"task_id"`
+	Type    HistoryEventType `json:"type"`
+	Author  string           `json:"author,omitempty"`
+	Field   string           `json:"field,omitempty"` // Changed field for "updated" events
+	From    string           `json:"from,omitempty"`  // Previous value (column for "moved" events)
+	To      string           `json:"to,omitempty"`    // New value (column for "moved", status for "test_run")
+	Summary string           `json:"summary,omitempty"`
+}
+
+// TestRunRecord is one test's outcome in one run, kept in the test history log
+type TestRunRecord struct {
+	Time     time.Time `json:"time"`
+	TaskID   string    `json:"task_id"`
+	Test     string    `json:"test"` // The test spec, e.g. "internal/auth/auth_test.go:TestLogin"
+	Passed   bool      `json:"passed"`
+	Duration int64     `json:"duration_ms"`
+	Commit   string    `json:"commit,omitempty"`
+	Changes  string    `json:"changes,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// TestStats summarises the recent runs of one test. A test that passed and
+// failed on the same code (same commit and uncommitted changes) is flaky.
+type TestStats struct {
+	Test        string  `json:"test"`
+	Runs        int     `json:"runs"`
+	Passed      int     `json:"passed"`
+	Flips       int     `json:"flips"`     // Outcome changes between consecutive runs on the same code
+	Flakiness   float64 `json:"flakiness"` // Flips per pair of consecutive runs on the same code, from 0 to 1
+	Flaky       bool    `json:"flaky"`
+	AvgDuration int64   `json:"avg_duration_ms"`
+	LastPassed  bool    `json:"last_passed"`
+}
+
+// TestHistory is the recorded test runs of a task with per-test statistics
+type TestHistory struct {
+	TaskID string          `json:"task_id"`
+	Tests  []TestStats     `json:"tests"`
+	Runs   []TestRunRecord `json:"runs"` // Oldest first
+}
+
+// TestJobStatus is the state of a background test run
+type TestJobStatus string
+
+const (
+	TestJobRunning  TestJobStatus = "running"
+	TestJobPassed   TestJobStatus = "passed"
+	TestJobFailed   TestJobStatus = "failed"
+	TestJobCanceled TestJobStatus = "canceled"
+)
+
+// TestJob is a run of a task's tests in the background
+type TestJob struct {
+	ID         string        `json:"id"`
+	TaskID     string        `json:"task_id"`
+	Status     TestJobStatus `json:"status"`
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt *time.Time    `json:"finished_at,omitempty"`
+	Output     string        `json:"output"`            // Output so far, in the order lines arrived
+	Lines      int           `json:"lines"`             // Number of output lines so far
+	Results    *TestResults  `json:"results,omitempty"` // Set once the tests have finished
+}
+
+// TestOutputLine is one line of a test job's output, streamed as it is written
+type TestOutputLine struct {
+	JobID  string `json:"job_id"`
+	TaskID string `json:"task_id"`
+	Seq    int    `json:"seq"`    // Position in the job's output, from 1
+	Test   string `json:"test"`   // What the command runs: a test, or a package and pattern for a batch
+	Stream string `json:"stream"` // "stdout" or "stderr"
+	Line   string `json:"line"`
+}
+
+// UndoResult describes a board change that was undone or redone
+type UndoResult struct {
+	Action      string   `json:"action"`      // "undo" or "redo"
+	Description string   `json:"description"` // What the original change did
+	TaskIDs     []string `json:"task_ids"`    // Tasks that were restored
+	CanUndo     bool     `json:"can_undo"`
+	CanRedo     bool     `json:"can_redo"`
+}
+
+// BranchInfo describes a local git branch and the worktree it is checked out in
+type BranchInfo struct {
+	Name     string `json:"name"`
+	Commit   string `json:"commit"`
+	Worktree string `json:"worktree,omitempty"` // Path of the worktree with the branch checked out
+	Current  bool   `json:"current"`            // Checked out in the working directory Kantext serves
+}
+
+// BranchBoard is the board as it is on a branch or in a worktree
+type BranchBoard struct {
+	Ref     string             `json:"ref"`
+	Commit  string             `json:"commit"`
+	Source  string             `json:"source"` // "commit" for the committed file, "worktree" for the file on disk
+	Columns []ColumnDefinition `json:"columns"`
+	Tasks   []*Task            `json:"tasks"`
+}
+
+// FieldChange is one field that differs between two versions of a task
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// TaskDiff describes how a task differs between two boards
+type TaskDiff struct {
+	TaskID string        `json:"task_id"`
+	Title  string        `json:"title"`
+	Change string        `json:"change"`           // "added", "removed" or "changed"
+	Fields []FieldChange `json:"fields,omitempty"` // Changed fields for "changed" tasks
+}
+
+// BoardComparison lists the task differences between two boards
+type BoardComparison struct {
+	Base  string     `json:"base"`
+	Head  string     `json:"head"`
+	Tasks []TaskDiff `json:"tasks"`
+}
+
+// MergePreview describes what merging a branch would do to the current board
+type MergePreview struct {
+	Ref       string          `json:"ref"`        // Branch being merged
+	MergeBase string          `json:"merge_base"` // Common ancestor commit
+	Changes   []TaskDiff      `json:"changes"`    // Changes to the current board
+	Conflicts []MergeConflict `json:"conflicts"`  // Unresolved fields changed on both sides; Theirs is the merged branch
+}
+
 // AddToQueueRequest is the request body for adding a task to the AI queue
 type AddToQueueRequest struct {
 	TaskID   string `json:"task_id"`
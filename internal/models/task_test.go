@@ -1,7 +1,9 @@
 package models
 
 import (
+	"encoding/json"
 	"testing"
+	"time"
 )
 
 // TestNameToSlug tests the NameToSlug function
@@ -180,6 +182,63 @@ func TestTaskCheckboxChar(t *testing.T) {
 	}
 }
 
+// TestTaskRevision tests that Task.Revision tracks content changes
+func TestTaskRevision(t *testing.T) {
+	updated := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	task := Task{ID: "task-rev1", Title: "Original", Priority: PriorityMedium, UpdatedAt: updated}
+	original := task.Revision()
+
+	if original == "" {
+		t.Fatal("Revision() returned empty string")
+	}
+
+	// Sub-second precision is not persisted, so it must not change the revision
+	task.UpdatedAt = updated.Add(500 * time.Millisecond)
+	if got := task.Revision(); got != original {
+		t.Errorf("Revision() changed on sub-second timestamp difference: %q != %q", got, original)
+	}
+
+	// Authors are refreshed from git blame and must not change the revision
+	task.UpdatedBy = "someone"
+	if got := task.Revision(); got != original {
+		t.Errorf("Revision() changed when only the author changed: %q != %q", got, original)
+	}
+
+	task.Title = "Changed"
+	if got := task.Revision(); got == original {
+		t.Error("Revision() did not change when the title changed")
+	}
+
+	// A test's runner is part of its spec
+	task.Tests = []TestSpec{{File: "app_test.go", Func: "TestApp"}}
+	withTest := task.Revision()
+	task.Tests[0].Runner = "python"
+	if got := task.Revision(); got == withTest {
+		t.Error("Revision() did not change when only the test runner changed")
+	}
+}
+
+// TestTaskMarshalJSONIncludesRevision verifies the revision is exposed in JSON
+func TestTaskMarshalJSONIncludesRevision(t *testing.T) {
+	task := Task{ID: "task-rev2", Title: "JSON"}
+
+	data, err := json.Marshal(&task)
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal failed: %v", err)
+	}
+	if decoded["revision"] != task.Revision() {
+		t.Errorf("revision = %v, want %q", decoded["revision"], task.Revision())
+	}
+	if decoded["id"] != "task-rev2" {
+		t.Errorf("id = %v, want %q", decoded["id"], "task-rev2")
+	}
+}
+
 // TestColumnConstants verifies column constant values
 func TestColumnConstants(t *testing.T) {
 	if ColumnInbox != "inbox" {
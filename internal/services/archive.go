@@ -0,0 +1,396 @@
+package services
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"kantext/internal/models"
+)
+
+// ErrNotArchived is returned when a task is not in the archive
+var ErrNotArchived = errors.New("task is not in the archive")
+
+// archiveHeader and archiveSection lay out the archive file like a board
+// with a single column, so archived tasks stay readable and diffable
+const (
+	archiveHeader  = "# Kantext Archive"
+	archiveSection = "Archive"
+)
+
+// archiveTimeFormat matches the timestamps written for task metadata
+const archiveTimeFormat = "2006-01-02T15:04:05Z"
+
+// parseArchive parses the archive file. Each task carries archived_at and
+// archived_from metadata, which are taken out of its extra lines so only
+// genuinely unknown content is written back.
+func parseArchive(lines []string) map[string]*models.Task {
+	tasks := parseBoard(lines).tasks
+	for _, task := range tasks {
+		kept := task.ExtraLines[:0]
+		for _, line := range task.ExtraLines {
+			matches := metadataRegex.FindStringSubmatch(line)
+			if matches == nil {
+				kept = append(kept, line)
+				continue
+			}
+			value := strings.TrimSpace(matches[2])
+			switch strings.TrimSpace(matches[1]) {
+			case "archived_at":
+				if t, err := time.Parse(time.RFC3339, value); err == nil {
+					task.ArchivedAt = &t
+				}
+			case "archived_from":
+				task.Column = models.Column(value)
+			default:
+				kept = append(kept, line)
+			}
+		}
+		task.ExtraLines = kept
+		if len(kept) == 0 {
+			task.ExtraLines = nil
+		}
+	}
+	return tasks
+}
+
+// loadArchiveLocked reads the archive file, dropping copies of tasks that
+// are back on the board (e.g. restored by hand). The file is re-read before
+// every archive operation because other processes sharing the board, such as
+// the MCP server, may have changed it. Caller must hold the write lock.
+func (s *TaskStore) loadArchiveLocked() error {
+	data, err := os.ReadFile(s.archivePath)
+	if errors.Is(err, os.ErrNotExist) {
+		s.archive = make(map[string]*models.Task)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	lines, err := splitLines(data)
+	if err != nil {
+		return err
+	}
+
+	s.archive = parseArchive(lines)
+	for id := range s.archive {
+		if _, ok := s.tasks[id]; ok {
+			delete(s.archive, id)
+		}
+	}
+	return nil
+}
+
+// purgeArchiveOnStartup loads the archive and purges tasks that expired while
+// the server was not running. Failures are logged: the board works without it.
+func (s *TaskStore) purgeArchiveOnStartup() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.loadArchiveLocked(); err != nil {
+		log.Printf("Error reading task archive: %v", err)
+		return
+	}
+	purged, err := s.purgeExpiredLocked()
+	if err != nil {
+		log.Printf("Error purging task archive: %v", err)
+		return
+	}
+	if len(purged) > 0 {
+		log.Printf("Purged %d task(s) archived more than %d days ago", len(purged), s.settings.GetArchiveRetentionDays())
+	}
+}
+
+// archivedTasksLocked returns the archived tasks, most recently archived first.
+// Caller must hold at least a read lock.
+func (s *TaskStore) archivedTasksLocked() []*models.Task {
+	tasks := make([]*models.Task, 0, len(s.archive))
+	for _, task := range s.archive {
+		tasks = append(tasks, task)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		ti, tj := archivedAt(tasks[i]), archivedAt(tasks[j])
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return tasks[i].ID < tasks[j].ID
+	})
+	return tasks
+}
+
+// archivedAt returns when a task was archived, or the zero time if unknown
+func archivedAt(task *models.Task) time.Time {
+	if task.ArchivedAt == nil {
+		return time.Time{}
+	}
+	return *task.ArchivedAt
+}
+
+// saveArchiveLocked writes the archive file synchronously. Like TASKS.md it
+// is written to a temporary file and renamed into place.
+// Caller must hold at least a read lock.
+func (s *TaskStore) saveArchiveLocked() error {
+	dir := filepath.Dir(s.archivePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(s.archivePath)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	renamed := false
+	defer func() {
+		if !renamed {
+			tmp.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+	if err := tmp.Chmod(0644); err != nil {
+		return fmt.Errorf("failed to set temp file permissions: %w", err)
+	}
+
+	buf := bufio.NewWriter(tmp)
+	ew := &errWriter{w: buf}
+	fmt.Fprintln(ew, archiveHeader)
+	fmt.Fprintln(ew, "")
+	fmt.Fprintf(ew, "## %s\n", archiveSection)
+	for _, task := range s.archivedTasksLocked() {
+		// Where and when the task was archived travel as extra metadata
+		out := copyTask(task)
+		var meta []string
+		if task.ArchivedAt != nil {
+			meta = append(meta, "  - archived_at: "+task.ArchivedAt.UTC().Format(archiveTimeFormat))
+		}
+		meta = append(meta, "  - archived_from: "+string(task.Column))
+		out.ExtraLines = append(meta, task.ExtraLines...)
+		if err := writeTask(ew, out); err != nil {
+			return fmt.Errorf("failed to write archive: %w", err)
+		}
+	}
+	if ew.err != nil {
+		return fmt.Errorf("failed to write archive: %w", ew.err)
+	}
+	if err := buf.Flush(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to sync archive: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.archivePath); err != nil {
+		return fmt.Errorf("failed to replace archive: %w", err)
+	}
+	renamed = true
+	return nil
+}
+
+// archivedCopy returns a copy of a board task as it is kept in the archive.
+// Fields derived from other tasks on the board are cleared.
+func archivedCopy(task *models.Task, at time.Time) *models.Task {
+	archived := copyTask(task)
+	archived.ArchivedAt = &at
+	archived.Blocks = nil
+	archived.Blocked = false
+	archived.Children = nil
+	archived.SubtasksDone = 0
+	return archived
+}
+
+// GetArchive returns the archived tasks, most recently archived first
+func (s *TaskStore) GetArchive() ([]*models.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.loadArchiveLocked(); err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	return s.archivedTasksLocked(), nil
+}
+
+// RestoreTask moves an archived task back onto the board, at the end of the
+// column it was archived from (or the first column if that no longer exists).
+// References to tasks that have since gone are dropped.
+func (s *TaskStore) RestoreTask(id, author string) (*models.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.loadArchiveLocked(); err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	archived, ok := s.archive[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotArchived, id)
+	}
+	if _, exists := s.tasks[id]; exists {
+		return nil, fmt.Errorf("task %s is already on the board", id)
+	}
+
+	task := copyTask(archived)
+	task.ArchivedAt = nil
+	if !s.columnExistsLocked(string(task.Column)) {
+		if first := s.getFirstColumn(); first != nil {
+			task.Column = models.Column(first.Slug)
+		}
+	}
+	task.Order = s.endOfColumnOrderLocked(task.Column)
+	if _, ok := s.tasks[task.Parent]; !ok {
+		task.Parent = ""
+	}
+	blockedBy := task.BlockedBy[:0]
+	for _, blockerID := range task.BlockedBy {
+		if _, ok := s.tasks[blockerID]; ok {
+			blockedBy = append(blockedBy, blockerID)
+		}
+	}
+	task.BlockedBy = blockedBy
+	task.UpdatedAt = time.Now().UTC()
+	if author != "" {
+		task.UpdatedBy = author
+	}
+
+	delete(s.archive, id)
+	if err := s.saveArchiveLocked(); err != nil {
+		s.archive[id] = archived
+		return nil, fmt.Errorf("%w: %v", ErrSaveFailed, err)
+	}
+
+	snapshot := s.snapshotTasksLocked()
+	s.tasks[id] = task
+	undo := s.undoEntryLocked(fmt.Sprintf("Restore %q", task.Title), snapshot)
+	rollback := s.rollbackLocked(snapshot)
+	if err := s.saveLocked(); err != nil {
+		// Put the task back in the archive, so it is still there to restore
+		rollback()
+		s.archive[id] = archived
+		if archiveErr := s.saveArchiveLocked(); archiveErr != nil {
+			log.Printf("Error saving task archive: %v", archiveErr)
+		}
+		return nil, err
+	}
+	if undo != nil {
+		undo.archive = true
+	}
+	s.pushUndoLocked(undo)
+
+	s.recordHistory(models.HistoryEvent{
+		TaskID:  id,
+		Type:    models.HistoryRestored,
+		Author:  author,
+		To:      string(task.Column),
+		Summary: task.Title,
+	})
+	return task, nil
+}
+
+// endOfColumnOrderLocked returns an order that places a task after every
+// task in the column. Caller must hold at least a read lock.
+func (s *TaskStore) endOfColumnOrderLocked(column models.Column) int {
+	order := 0
+	for _, task := range s.getTasksByColumn(column) {
+		if task.Order >= order {
+			order = task.Order + 1
+		}
+	}
+	return order
+}
+
+// columnExistsLocked reports whether a column with the given slug exists.
+// Caller must hold at least a read lock.
+func (s *TaskStore) columnExistsLocked(slug string) bool {
+	for _, col := range s.columns {
+		if col.Slug == slug {
+			return true
+		}
+	}
+	return false
+}
+
+// PurgeArchive permanently removes archived tasks older than the
+// archive_retention_days setting. Returns the IDs of the purged tasks.
+func (s *TaskStore) PurgeArchive() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.loadArchiveLocked(); err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	return s.purgeExpiredLocked()
+}
+
+// purgeExpiredLocked removes archived tasks past the retention period and
+// saves the archive. Caller must hold the write lock.
+func (s *TaskStore) purgeExpiredLocked() ([]string, error) {
+	expired := s.expiredArchiveLocked()
+	ids := make([]string, len(expired))
+	for i, task := range expired {
+		ids[i] = task.ID
+		delete(s.archive, task.ID)
+	}
+	if len(expired) == 0 {
+		return ids, nil
+	}
+	if err := s.saveArchiveLocked(); err != nil {
+		for _, task := range expired {
+			s.archive[task.ID] = task
+		}
+		return nil, fmt.Errorf("%w: %v", ErrSaveFailed, err)
+	}
+	s.recordHistory(purgeEvents(expired)...)
+	return ids, nil
+}
+
+// PurgeArchivedTask permanently removes a single task from the archive
+func (s *TaskStore) PurgeArchivedTask(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.loadArchiveLocked(); err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+	task, ok := s.archive[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotArchived, id)
+	}
+	delete(s.archive, id)
+	if err := s.saveArchiveLocked(); err != nil {
+		s.archive[id] = task
+		return fmt.Errorf("%w: %v", ErrSaveFailed, err)
+	}
+	s.recordHistory(purgeEvents([]*models.Task{task})...)
+	return nil
+}
+
+// expiredArchiveLocked returns the archived tasks past the retention period.
+// Caller must hold at least a read lock.
+func (s *TaskStore) expiredArchiveLocked() []*models.Task {
+	cutoff := time.Now().UTC().AddDate(0, 0, -s.settings.GetArchiveRetentionDays())
+	var expired []*models.Task
+	for _, task := range s.archivedTasksLocked() {
+		if task.ArchivedAt != nil && task.ArchivedAt.Before(cutoff) {
+			expired = append(expired, task)
+		}
+	}
+	return expired
+}
+
+// purgeEvents records the permanent deletion of archived tasks
+func purgeEvents(tasks []*models.Task) []models.HistoryEvent {
+	events := make([]models.HistoryEvent, len(tasks))
+	for i, task := range tasks {
+		events[i] = models.HistoryEvent{
+			TaskID:  task.ID,
+			Type:    models.HistoryDeleted,
+			Summary: "Purged from archive: " + task.Title,
+		}
+	}
+	return events
+}
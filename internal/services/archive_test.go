@@ -0,0 +1,233 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"kantext/internal/models"
+)
+
+const archiveTestContent = `---
+stale_threshold_days: 7
+archive_retention_days: 10
+---
+# Kantext Tasks
+
+## Inbox
+
+- [ ] Write docs
+  - id: task-docs
+  - priority: low
+  - blocked_by: task-api
+  - requires_test: false
+
+## In Progress
+
+- [ ] Build API
+  - id: task-api
+  - priority: high
+  - tags: backend
+  - requires_test: false
+  - estimate: 3
+  Keep the handlers thin.
+
+## Review
+
+- [ ] Check error codes
+  - id: task-review
+  - priority: medium
+  - requires_test: false
+
+## Done
+`
+
+func TestTaskStore_Delete_Archives(t *testing.T) {
+	store, cleanup := setupTaskStoreEnv(t, archiveTestContent)
+	defer cleanup()
+
+	if err := store.Delete("task-api"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := store.Get("task-api"); err == nil {
+		t.Error("Expected task to be removed from the board")
+	}
+	docs, _ := store.Get("task-docs")
+	if len(docs.BlockedBy) != 0 {
+		t.Errorf("Expected dependency on the deleted task to be removed, got %v", docs.BlockedBy)
+	}
+
+	data, err := os.ReadFile(filepath.Join(store.GetWorkingDir(), ".kantext", "archive.md"))
+	if err != nil {
+		t.Fatalf("Expected archive file: %v", err)
+	}
+	for _, want := range []string{"## Archive", "- [ ] Build API", "  - archived_from: in_progress", "  - estimate: 3", "  Keep the handlers thin."} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Archive file missing %q:\n%s", want, data)
+		}
+	}
+
+	// The archive is read back from disk, keeping unknown lines
+	archived, err := store.GetArchive()
+	if err != nil {
+		t.Fatalf("GetArchive failed: %v", err)
+	}
+	if len(archived) != 1 {
+		t.Fatalf("Expected 1 archived task, got %d", len(archived))
+	}
+	task := archived[0]
+	if task.ID != "task-api" || task.Column != models.ColumnInProgress || task.ArchivedAt == nil {
+		t.Errorf("Unexpected archived task: %+v", task)
+	}
+	if strings.Join(task.ExtraLines, "\n") != "  - estimate: 3\n  Keep the handlers thin." {
+		t.Errorf("Expected archive metadata to be kept out of extra lines, got %q", task.ExtraLines)
+	}
+}
+
+func TestTaskStore_RestoreTask(t *testing.T) {
+	store, cleanup := setupTaskStoreEnv(t, archiveTestContent)
+	defer cleanup()
+
+	if err := store.Delete("task-docs"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := store.Delete("task-api"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	// task-docs was blocked by task-api, which is still archived
+	task, err := store.RestoreTask("task-docs", "Alice")
+	if err != nil {
+		t.Fatalf("RestoreTask failed: %v", err)
+	}
+	if task.Column != models.ColumnInbox || task.ArchivedAt != nil || task.UpdatedBy != "Alice" {
+		t.Errorf("Unexpected restored task: %+v", task)
+	}
+	if len(task.BlockedBy) != 0 {
+		t.Errorf("Expected missing blocker to be dropped, got %v", task.BlockedBy)
+	}
+
+	archived := mustGetArchive(t, store)
+	if len(archived) != 1 || archived[0].ID != "task-api" {
+		t.Errorf("Expected only task-api to remain archived, got %d tasks", len(archived))
+	}
+	if _, err := store.RestoreTask("task-docs", ""); !errors.Is(err, ErrNotArchived) {
+		t.Errorf("Expected ErrNotArchived, got %v", err)
+	}
+
+	// A task whose column has gone is restored to the first column
+	if err := store.Delete("task-review"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := store.DeleteColumn("review"); err != nil {
+		t.Fatalf("DeleteColumn failed: %v", err)
+	}
+	task, err = store.RestoreTask("task-review", "")
+	if err != nil {
+		t.Fatalf("RestoreTask failed: %v", err)
+	}
+	if task.Column != models.ColumnInbox {
+		t.Errorf("Expected task restored to inbox, got %s", task.Column)
+	}
+}
+
+func TestTaskStore_Undo_DeleteArchive(t *testing.T) {
+	store, cleanup := setupTaskStoreEnv(t, archiveTestContent)
+	defer cleanup()
+
+	if err := store.Delete("task-api"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := store.Undo(); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if _, err := store.Get("task-api"); err != nil {
+		t.Errorf("Expected undo to put the task back: %v", err)
+	}
+	if archived := mustGetArchive(t, store); len(archived) != 0 {
+		t.Errorf("Expected undo to take the task out of the archive, got %d tasks", len(archived))
+	}
+
+	if _, err := store.Redo(); err != nil {
+		t.Fatalf("Redo failed: %v", err)
+	}
+	if archived := mustGetArchive(t, store); len(archived) != 1 {
+		t.Errorf("Expected redo to archive the task again, got %d tasks", len(archived))
+	}
+
+	// The history shows the task going in and out of the archive
+	events, _ := store.GetHistory("task-api")
+	var types []string
+	for _, event := range events {
+		types = append(types, string(event.Type))
+	}
+	if got := strings.Join(types, ","); got != "archived,restored,archived" {
+		t.Errorf("Expected archived,restored,archived in the history, got %s", got)
+	}
+
+	// Undoing a create removes the task without archiving it
+	created, err := store.Create(models.CreateTaskRequest{Title: "Scratch"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := store.Undo(); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	for _, task := range mustGetArchive(t, store) {
+		if task.ID == created.ID {
+			t.Error("Expected an undone create not to be archived")
+		}
+	}
+}
+
+func TestTaskStore_PurgeArchive(t *testing.T) {
+	store, cleanup := setupTaskStoreEnv(t, archiveTestContent)
+	defer cleanup()
+
+	for _, id := range []string{"task-docs", "task-api"} {
+		if err := store.Delete(id); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+	}
+
+	// Age one task past the 10 day retention period
+	store.mu.Lock()
+	old := time.Now().UTC().AddDate(0, 0, -11)
+	store.archive["task-docs"].ArchivedAt = &old
+	store.saveArchiveLocked()
+	store.mu.Unlock()
+
+	purged, err := store.PurgeArchive()
+	if err != nil {
+		t.Fatalf("PurgeArchive failed: %v", err)
+	}
+	if strings.Join(purged, ",") != "task-docs" {
+		t.Errorf("Expected task-docs to be purged, got %v", purged)
+	}
+	archived := mustGetArchive(t, store)
+	if len(archived) != 1 || archived[0].ID != "task-api" {
+		t.Errorf("Expected task-api to be kept, got %d tasks", len(archived))
+	}
+
+	if err := store.PurgeArchivedTask("task-api"); err != nil {
+		t.Fatalf("PurgeArchivedTask failed: %v", err)
+	}
+	if len(mustGetArchive(t, store)) != 0 {
+		t.Error("Expected the archive to be empty")
+	}
+	if _, err := store.RestoreTask("task-api", ""); !errors.Is(err, ErrNotArchived) {
+		t.Errorf("Expected purged task to be gone for good, got %v", err)
+	}
+}
+
+func mustGetArchive(t *testing.T, store *TaskStore) []*models.Task {
+	t.Helper()
+	archived, err := store.GetArchive()
+	if err != nil {
+		t.Fatalf("GetArchive failed: %v", err)
+	}
+	return archived
+}
@@ -0,0 +1,270 @@
+package services
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"kantext/internal/models"
+)
+
+// autoCommitDelay is how long the board must be quiet after a save before
+// the changes are committed, so a burst of edits becomes a single commit
+const autoCommitDelay = 2 * time.Second
+
+// autoCommitter batches saves of TASKS.md into git commits
+type autoCommitter struct {
+	mu      sync.Mutex // Protects timer and pending
+	timer   *time.Timer
+	pending bool       // A save has happened since the last commit attempt
+	gitMu   sync.Mutex // Serialises git commands
+}
+
+// gitBusyMarkers are files in the git directory that exist while a merge,
+// rebase, cherry-pick or revert is in progress
+var gitBusyMarkers = []string{"MERGE_HEAD", "rebase-merge", "rebase-apply", "CHERRY_PICK_HEAD", "REVERT_HEAD"}
+
+// scheduleAutoCommit is called after every successful save. It (re)starts the
+// batch timer; whether to commit at all is decided when the timer fires.
+func (s *TaskStore) scheduleAutoCommit() {
+	c := &s.autoCommit
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending = true
+	if c.timer != nil {
+		c.timer.Reset(autoCommitDelay)
+		return
+	}
+	c.timer = time.AfterFunc(autoCommitDelay, func() {
+		if err := s.commitPending(); err != nil {
+			log.Printf("Error auto-committing tasks: %v", err)
+		}
+	})
+}
+
+// flushAutoCommit commits any saves still waiting for the batch timer
+func (s *TaskStore) flushAutoCommit() error {
+	c := &s.autoCommit
+	c.mu.Lock()
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	c.mu.Unlock()
+	return s.commitPending()
+}
+
+// commitPending commits TASKS.md if a save happened since the last attempt
+func (s *TaskStore) commitPending() error {
+	c := &s.autoCommit
+	c.mu.Lock()
+	pending := c.pending
+	c.pending = false
+	c.mu.Unlock()
+	if !pending {
+		return nil
+	}
+	return s.commitBoard()
+}
+
+// commitBoard commits TASKS.md to git with a message describing what changed
+// since HEAD, if git.auto_commit is enabled. Only TASKS.md and its included
+// files are committed: anything else the user has staged is left alone.
+// Nothing is committed while a merge or rebase is in progress, or if the
+// board's files have not changed.
+func (s *TaskStore) commitBoard() error {
+	s.mu.RLock()
+	git := s.settings.Git
+	s.mu.RUnlock()
+	if !git.AutoCommit {
+		return nil
+	}
+
+	c := &s.autoCommit
+	c.gitMu.Lock()
+	defer c.gitMu.Unlock()
+
+	dir, name := filepath.Dir(s.filePath), filepath.Base(s.filePath)
+	gitDir, err := gitOutput(dir, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return nil // Not a git repository
+	}
+	for _, marker := range gitBusyMarkers {
+		if _, err := os.Stat(filepath.Join(strings.TrimSpace(string(gitDir)), marker)); err == nil {
+			log.Printf("Skipping auto-commit of %s: %s in progress", name, strings.ToLower(strings.TrimSuffix(marker, "_HEAD")))
+			return nil
+		}
+	}
+
+	// The board's files that exist, relative to the tasks file
+	var names []string
+	for _, file := range s.storage.Files() {
+		if _, err := os.Stat(file); err != nil {
+			continue
+		}
+		if rel, err := filepath.Rel(dir, file); err == nil {
+			names = append(names, filepath.ToSlash(rel))
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	status, err := gitOutput(dir, append([]string{"status", "--porcelain", "--"}, names...)...)
+	if err != nil {
+		return fmt.Errorf("git status failed: %w", err)
+	}
+	if len(strings.TrimSpace(string(status))) == 0 {
+		return nil // Nothing to commit
+	}
+
+	current, _, err := s.storage.Load()
+	if err != nil {
+		return err
+	}
+	var previous *boardSnapshot
+	if storage, ok := s.storage.(fileStorage); ok {
+		previous, err = storage.readFrom(func(name string) ([]byte, error) {
+			committed, err := gitOutput(dir, "show", "HEAD:./"+name)
+			if err != nil {
+				return nil, fs.ErrNotExist
+			}
+			return committed, nil
+		})
+		if err != nil {
+			previous = nil // Not committed yet
+		}
+	}
+	message := commitMessage(describeBoardChanges(previous, current))
+
+	// New files must be added before they can be committed on their own
+	if _, err := gitOutput(dir, append([]string{"add", "--"}, names...)...); err != nil {
+		return fmt.Errorf("git add failed: %w", err)
+	}
+	var args []string
+	if git.AuthorName != "" {
+		args = append(args, "-c", "user.name="+git.AuthorName)
+	}
+	if git.AuthorEmail != "" {
+		args = append(args, "-c", "user.email="+git.AuthorEmail)
+	}
+	args = append(args, "commit", "--quiet", "--only", "-m", message, "--")
+	args = append(args, names...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git commit failed: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// commitMessage formats change descriptions as a commit message. A single
+// change makes up the subject; several are listed in the body.
+func commitMessage(changes []string) string {
+	switch len(changes) {
+	case 0:
+		return "kantext: update board"
+	case 1:
+		return "kantext: " + changes[0]
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "kantext: %d board changes\n\n", len(changes))
+	for _, change := range changes {
+		fmt.Fprintf(&sb, "- %s\n", change)
+	}
+	return sb.String()
+}
+
+// describeBoardChanges lists what changed between two versions of the board,
+// e.g. "move task-AB12 to done (tests 3/3 passed)". previous is nil when the
+// file is new.
+func describeBoardChanges(previous, current *boardSnapshot) []string {
+	if previous == nil {
+		return []string{fmt.Sprintf("add board with %d tasks", len(current.tasks))}
+	}
+
+	var changes []string
+	if !columnsEqual(previous.columns, current.columns) {
+		changes = append(changes, "update columns")
+	}
+	if settingsString(previous.settings) != settingsString(current.settings) {
+		changes = append(changes, "update settings")
+	}
+
+	ids := make([]string, 0, len(current.tasks))
+	for id := range current.tasks {
+		ids = append(ids, id)
+	}
+	for id := range previous.tasks {
+		if _, ok := current.tasks[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		before, after := previous.tasks[id], current.tasks[id]
+		switch {
+		case before == nil:
+			changes = append(changes, fmt.Sprintf("add %s %q", id, after.Title))
+		case after == nil:
+			changes = append(changes, fmt.Sprintf("remove %s %q", id, before.Title))
+		default:
+			if change := describeTaskChange(before, after); change != "" {
+				changes = append(changes, change)
+			}
+		}
+	}
+	return changes
+}
+
+// describeTaskChange summarises how a task changed, or returns "" if it did not
+func describeTaskChange(before, after *models.Task) string {
+	tests := ""
+	if before.TestStatus != after.TestStatus || before.TestsPassed != after.TestsPassed || before.TestsTotal != after.TestsTotal {
+		tests = testSummary(after)
+	}
+
+	var change string
+	switch {
+	case before.Column != after.Column:
+		change = fmt.Sprintf("move %s to %s", after.ID, after.Column)
+	case undoSignature(before) != undoSignature(after):
+		change = fmt.Sprintf("edit %s", after.ID)
+	case tests != "":
+		change = fmt.Sprintf("test %s", after.ID)
+	default:
+		return "" // Only timestamps or authors changed
+	}
+	if tests != "" {
+		change += fmt.Sprintf(" (%s)", tests)
+	}
+	return change
+}
+
+// testSummary describes a task's latest test results, e.g. "tests 3/3 passed"
+func testSummary(task *models.Task) string {
+	if task.TestsTotal > 0 {
+		return fmt.Sprintf("tests %d/%d passed", task.TestsPassed, task.TestsTotal)
+	}
+	return "tests " + string(task.TestStatus)
+}
+
+// columnsEqual reports whether two column lists have the same names in the same order
+func columnsEqual(a, b []models.ColumnDefinition) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Slug != b[i].Slug || a[i].Name != b[i].Name {
+			return false
+		}
+	}
+	return true
+}
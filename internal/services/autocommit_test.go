@@ -0,0 +1,153 @@
+package services
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"kantext/internal/models"
+)
+
+const autoCommitTestContent = `---
+stale_threshold_days: 7
+test_runner:
+    command: go test -json -count=1 -run ^{testFunc}$ {testPath}
+    pass_string: PASS
+    fail_string: FAIL
+    no_tests_string: no tests to run
+    format: go-json
+    batch: true
+git:
+  auto_commit: true
+  author_name: Kantext Bot
+  author_email: bot@example.com
+---
+# Kantext Tasks
+
+## Inbox
+
+## In Progress
+
+- [ ] Build API
+  - id: task-api
+  - priority: high
+  - requires_test: true
+  - test: api_test.go:TestAPI
+
+## Done
+`
+
+func gitLog(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"log"}, args...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git log failed: %v\n%s", err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestTaskStore_AutoCommit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v\n%s", err, out)
+	}
+	gitCommitAs(t, dir, autoCommitTestContent, "Alice", "2024-01-01T00:00:00Z")
+
+	// Something else the user has staged must not be committed
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("wip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("git", "-C", dir, "add", "notes.txt").CombinedOutput(); err != nil {
+		t.Fatalf("git add failed: %v\n%s", err, out)
+	}
+
+	store := NewTaskStore(dir)
+	defer store.Close()
+	store.SetDurableWrites(true)
+
+	if _, err := store.UpdateTestResults("task-api", models.TestResults{
+		AllPassed: true,
+		Results:   []models.TestResult{{Passed: true}, {Passed: true}, {Passed: true}},
+	}); err != nil {
+		t.Fatalf("UpdateTestResults failed: %v", err)
+	}
+	if err := store.flushAutoCommit(); err != nil {
+		t.Fatalf("Auto-commit failed: %v", err)
+	}
+
+	if got := gitLog(t, dir, "-1", "--format=%an <%ae>%n%s"); got != "Kantext Bot <bot@example.com>\nkantext: move task-api to done (tests 3/3 passed)" {
+		t.Errorf("Unexpected commit:\n%s", got)
+	}
+	if files := gitLog(t, dir, "-1", "--name-only", "--format="); files != "TASKS.md" {
+		t.Errorf("Expected only TASKS.md to be committed, got %q", files)
+	}
+
+	// Several changes are batched into one commit
+	for _, title := range []string{"Write docs", "Deploy"} {
+		if _, err := store.Create(models.CreateTaskRequest{Title: title}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+	if err := store.flushAutoCommit(); err != nil {
+		t.Fatalf("Auto-commit failed: %v", err)
+	}
+	body := gitLog(t, dir, "-1", "--format=%B")
+	if !strings.HasPrefix(body, "kantext: 2 board changes") || !strings.Contains(body, `"Write docs"`) || !strings.Contains(body, `"Deploy"`) {
+		t.Errorf("Expected both creates in one commit, got:\n%s", body)
+	}
+
+	// Nothing is committed while a merge is in progress
+	gitDir := filepath.Join(dir, ".git")
+	if err := os.WriteFile(filepath.Join(gitDir, "MERGE_HEAD"), []byte("0000000000000000000000000000000000000000\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Reorder("task-api", models.ColumnInbox, 0); err != nil {
+		t.Fatalf("Reorder failed: %v", err)
+	}
+	if err := store.flushAutoCommit(); err != nil {
+		t.Fatalf("Auto-commit failed: %v", err)
+	}
+	if count := gitLog(t, dir, "--oneline"); strings.Count(count, "\n")+1 != 3 {
+		t.Errorf("Expected no commit during a merge, got:\n%s", count)
+	}
+}
+
+func TestTaskStore_AutoCommit_Close(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v\n%s", err, out)
+	}
+	gitCommitAs(t, dir, autoCommitTestContent, "Alice", "2024-01-01T00:00:00Z")
+
+	// A change still waiting for the saver and the batch delay is committed on close
+	store := NewTaskStore(dir)
+	docs, err := store.Create(models.CreateTaskRequest{Title: "Write docs"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if body := gitLog(t, dir, "-1", "--format=%B"); !strings.Contains(body, `add `+docs.ID+` "Write docs"`) {
+		t.Errorf("Expected the pending change to be committed, got:\n%s", body)
+	}
+
+	// Changes made after closing, e.g. by a test job finishing, are saved right away
+	deploy, err := store.Create(models.CreateTaskRequest{Title: "Deploy"})
+	if err != nil {
+		t.Fatalf("Create after Close failed: %v", err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(dir, "TASKS.md")); !strings.Contains(string(content), deploy.ID) {
+		t.Errorf("Expected the change made after closing to be saved, got:\n%s", content)
+	}
+}
@@ -0,0 +1,294 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"kantext/internal/models"
+)
+
+// Buckets of a bolt tasks file
+var (
+	boltMetaBucket  = []byte("meta")  // Settings, columns and the version of the last save
+	boltTasksBucket = []byte("tasks") // Task ID -> task as JSON
+	boltOrderBucket = []byte("order") // One bucket per column: position -> task ID
+)
+
+// Keys of the meta bucket
+var (
+	boltSettingsKey = []byte("settings")
+	boltColumnsKey  = []byte("columns")
+	boltVersionKey  = []byte("version")
+)
+
+// boltOpenTimeout is how long to wait for another process, such as the MCP
+// server, to finish with the database
+const boltOpenTimeout = 5 * time.Second
+
+// boltStorage stores a board in an embedded bbolt database. Tasks are kept
+// in a B+tree keyed by ID, with an index of each column's tasks in board
+// order, so a save only rewrites the tasks and columns that changed. It
+// suits boards too large to rewrite as a whole on every change. Like a JSON
+// board, it has no included files, header or free-form notes.
+//
+// The database is opened for each operation, so the web and MCP servers can
+// share it.
+type boltStorage struct {
+	path string
+}
+
+func (b *boltStorage) Load() (*boardSnapshot, [sha256.Size]byte, error) {
+	var hash [sha256.Size]byte
+	// Opening the database would create it
+	if _, err := os.Stat(b.path); err != nil {
+		return nil, hash, err
+	}
+	return readBoltBoard(b.path)
+}
+
+func (b *boltStorage) Save(board *boardSnapshot) ([sha256.Size]byte, error) {
+	var hash [sha256.Size]byte
+	db, err := bolt.Open(b.path, 0644, &bolt.Options{Timeout: boltOpenTimeout})
+	if err != nil {
+		return hash, fmt.Errorf("failed to open tasks database: %w", err)
+	}
+	defer db.Close()
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		var err error
+		hash, err = writeBoltBoard(tx, board)
+		return err
+	})
+	if err != nil {
+		return hash, fmt.Errorf("failed to write tasks database: %w", err)
+	}
+	return hash, nil
+}
+
+func (b *boltStorage) Files() []string {
+	return []string{b.path}
+}
+
+// readFrom reads a copy of the database, such as one committed to git, from
+// a temporary file, since bbolt can only open files
+func (b *boltStorage) readFrom(read boardReader) (*boardSnapshot, error) {
+	data, err := read(filepath.Base(b.path))
+	if err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp("", "kantext-*.db")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, err
+	}
+	board, _, err := readBoltBoard(tmp.Name())
+	return board, err
+}
+
+// readBoltBoard reads the board in a bolt database. A database without a
+// board is an empty board.
+func readBoltBoard(path string) (*boardSnapshot, [sha256.Size]byte, error) {
+	var hash [sha256.Size]byte
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: boltOpenTimeout, ReadOnly: true})
+	if err != nil {
+		return nil, hash, fmt.Errorf("failed to open tasks database: %w", err)
+	}
+	defer db.Close()
+
+	board := &boardSnapshot{
+		tasks:       make(map[string]*models.Task),
+		columns:     []models.ColumnDefinition{},
+		lineNumbers: make(map[string]int),
+	}
+	err = db.View(func(tx *bolt.Tx) error {
+		meta := tx.Bucket(boltMetaBucket)
+		if meta == nil {
+			return nil
+		}
+		hash = sha256.Sum256(meta.Get(boltVersionKey))
+		if err := json.Unmarshal(meta.Get(boltSettingsKey), &board.settings); err != nil {
+			return fmt.Errorf("invalid settings: %w", err)
+		}
+		if err := json.Unmarshal(meta.Get(boltColumnsKey), &board.columns); err != nil {
+			return fmt.Errorf("invalid columns: %w", err)
+		}
+
+		// Walk each column's index in board order
+		tasks, order := tx.Bucket(boltTasksBucket), tx.Bucket(boltOrderBucket)
+		for _, col := range board.columns {
+			index := order.Bucket([]byte(col.Slug))
+			if index == nil {
+				continue
+			}
+			err := index.ForEach(func(_, id []byte) error {
+				var task models.Task
+				if err := json.Unmarshal(tasks.Get(id), &task); err != nil {
+					return fmt.Errorf("invalid task %s: %w", id, err)
+				}
+				task.Order = len(board.tasks)
+				board.tasks[task.ID] = &task
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, hash, fmt.Errorf("failed to read tasks database: %w", err)
+	}
+	return board, hash, nil
+}
+
+// writeBoltBoard stores a board, writing only the tasks, column indexes and
+// settings that differ from what is stored. Every save gets a new random
+// version, whose hash identifies the stored board.
+func writeBoltBoard(tx *bolt.Tx, board *boardSnapshot) ([sha256.Size]byte, error) {
+	var hash [sha256.Size]byte
+	meta, err := tx.CreateBucketIfNotExists(boltMetaBucket)
+	if err != nil {
+		return hash, err
+	}
+	tasks, err := tx.CreateBucketIfNotExists(boltTasksBucket)
+	if err != nil {
+		return hash, err
+	}
+	order, err := tx.CreateBucketIfNotExists(boltOrderBucket)
+	if err != nil {
+		return hash, err
+	}
+
+	columns := sortColumns(board.columns)
+	if err := putIfChanged(meta, boltSettingsKey, settingsWithDefaults(board.settings)); err != nil {
+		return hash, err
+	}
+	if err := putIfChanged(meta, boltColumnsKey, columns); err != nil {
+		return hash, err
+	}
+
+	// Tasks in a column that does not exist are not stored, as in a JSON board
+	stored := make(map[string]bool, len(board.tasks))
+	indexed := make(map[string]bool, len(columns))
+	for _, col := range columns {
+		var ids [][]byte
+		for _, task := range columnTasks(board.tasks, models.Column(col.Slug)) {
+			if err := putIfChanged(tasks, []byte(task.ID), storedTask(task)); err != nil {
+				return hash, err
+			}
+			stored[task.ID] = true
+			ids = append(ids, []byte(task.ID))
+		}
+		if err := writeColumnIndex(order, col.Slug, ids); err != nil {
+			return hash, err
+		}
+		indexed[col.Slug] = true
+	}
+
+	// Drop what was removed from the board. Keys are copied first, since a
+	// bucket must not be changed while it is iterated.
+	var removed [][]byte
+	tasks.ForEach(func(id, _ []byte) error {
+		if !stored[string(id)] {
+			removed = append(removed, bytes.Clone(id))
+		}
+		return nil
+	})
+	for _, id := range removed {
+		if err := tasks.Delete(id); err != nil {
+			return hash, err
+		}
+	}
+	removed = nil
+	order.ForEach(func(slug, _ []byte) error {
+		if !indexed[string(slug)] {
+			removed = append(removed, bytes.Clone(slug))
+		}
+		return nil
+	})
+	for _, slug := range removed {
+		if err := order.DeleteBucket(slug); err != nil {
+			return hash, err
+		}
+	}
+
+	version := make([]byte, 16)
+	if _, err := rand.Read(version); err != nil {
+		return hash, err
+	}
+	if err := meta.Put(boltVersionKey, version); err != nil {
+		return hash, err
+	}
+	return sha256.Sum256(version), nil
+}
+
+// writeColumnIndex stores the IDs of a column's tasks in board order,
+// leaving the index alone if the order has not changed
+func writeColumnIndex(order *bolt.Bucket, slug string, ids [][]byte) error {
+	if index := order.Bucket([]byte(slug)); index != nil {
+		var current [][]byte
+		index.ForEach(func(_, id []byte) error {
+			current = append(current, id)
+			return nil
+		})
+		if sameIDs(current, ids) {
+			return nil
+		}
+		if err := order.DeleteBucket([]byte(slug)); err != nil {
+			return err
+		}
+	}
+	index, err := order.CreateBucket([]byte(slug))
+	if err != nil {
+		return err
+	}
+	for i, id := range ids {
+		position := make([]byte, 8)
+		binary.BigEndian.PutUint64(position, uint64(i))
+		if err := index.Put(position, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// putIfChanged stores value as JSON under key, unless it is already stored
+func putIfChanged(bucket *bolt.Bucket, key []byte, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(bucket.Get(key), data) {
+		return nil
+	}
+	return bucket.Put(key, data)
+}
+
+// sameIDs reports whether two lists hold the same IDs in the same order
+func sameIDs(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
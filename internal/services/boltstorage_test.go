@@ -0,0 +1,97 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"kantext/internal/models"
+)
+
+func TestTaskStore_BoltStorage(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tasks.db")
+
+	// A new database board is created with the default columns and settings
+	store := NewTaskStoreWithFile(dir, "tasks.db")
+	store.SetDurableWrites(true)
+	parent, err := store.Create(models.CreateTaskRequest{Title: "Ship v2", AcceptanceCriteria: "Released"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	child, err := store.Create(models.CreateTaskRequest{Title: "Write changelog", AcceptanceCriteria: "Merged", Parent: parent.ID})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := store.Reorder(child.ID, models.ColumnInProgress, 0); err != nil {
+		t.Fatalf("Reorder failed: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("Expected a database file: %v", err)
+	}
+
+	storage := &boltStorage{path: path}
+	stored, firstHash, err := storage.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(stored.columns) != len(models.DefaultColumns) || stored.settings.TestRunner.Command != DefaultTestCommand || len(stored.tasks) != 2 {
+		t.Errorf("Unexpected stored board: %+v", stored)
+	}
+	if task := stored.tasks[parent.ID]; task == nil || task.Children != nil {
+		t.Errorf("Derived fields should not be stored, got %+v", task)
+	}
+
+	// Reopening restores the tasks in board order, with derived fields filled in
+	store = NewTaskStoreWithFile(dir, path)
+	store.SetDurableWrites(true)
+	defer store.Close()
+	tasks := store.GetAll()
+	if len(tasks) != 2 || tasks[0].ID != parent.ID || tasks[1].ID != child.ID || tasks[1].Column != models.ColumnInProgress {
+		t.Fatalf("Expected tasks in board order, got %+v", tasks)
+	}
+	if reloaded, _ := store.Get(parent.ID); len(reloaded.Children) != 1 || reloaded.Children[0] != child.ID {
+		t.Errorf("Expected children to be derived on load, got %v", reloaded.Children)
+	}
+	if files := store.Files(); len(files) != 1 || files[0] != path {
+		t.Errorf("Expected board files [%s], got %v", path, files)
+	}
+
+	// Changes made by another process sharing the database are merged
+	other := &boltStorage{path: path}
+	board, _, err := other.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	board.tasks[child.ID].Title = "Write the changelog"
+	delete(board.tasks, parent.ID)
+	board.tasks[child.ID].Parent = ""
+	secondHash, err := other.Save(board)
+	if err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if secondHash == firstHash {
+		t.Error("Expected each save to change the hash")
+	}
+	if _, err := store.ReloadFromDisk(); err != nil {
+		t.Fatalf("ReloadFromDisk failed: %v", err)
+	}
+	if reloaded, _ := store.Get(child.ID); reloaded.Title != "Write the changelog" {
+		t.Errorf("Expected the external edit to be merged, got %q", reloaded.Title)
+	}
+	if _, err := store.Get(parent.ID); err == nil {
+		t.Error("Expected the task deleted by the other process to be gone")
+	}
+
+	// Removed tasks leave nothing behind in the database
+	board, _, err = other.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(board.tasks) != 1 || board.tasks[child.ID] == nil {
+		t.Errorf("Expected only %s to be stored, got %+v", child.ID, board.tasks)
+	}
+}
@@ -0,0 +1,309 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"kantext/internal/models"
+)
+
+// worktreeRefPrefix marks a board reference as the TASKS.md on disk in the
+// worktree that has a branch checked out, e.g. "worktree:feature/login",
+// rather than the version committed on that branch
+const worktreeRefPrefix = "worktree:"
+
+// Board sources reported in models.BranchBoard
+const (
+	boardSourceCommit   = "commit"
+	boardSourceWorktree = "worktree"
+)
+
+// ErrUnknownRef is returned when a branch, commit or worktree does not exist
+var ErrUnknownRef = errors.New("unknown branch or worktree")
+
+// ListBranches returns the local branches and the worktrees they are checked
+// out in. Returns an empty list outside a git repository.
+func (s *TaskStore) ListBranches() ([]models.BranchInfo, error) {
+	dir := filepath.Dir(s.filePath)
+	if _, err := gitOutput(dir, "rev-parse", "--git-dir"); err != nil {
+		return []models.BranchInfo{}, nil
+	}
+
+	out, err := gitOutput(dir, "for-each-ref", "--format=%(refname:short)%1f%(objectname)%1f%(worktreepath)", "refs/heads")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+	toplevel, _ := gitOutput(dir, "rev-parse", "--show-toplevel")
+	current := strings.TrimSpace(string(toplevel))
+
+	branches := []models.BranchInfo{}
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Split(line, "\x1f")
+		if len(fields) != 3 {
+			continue
+		}
+		branches = append(branches, models.BranchInfo{
+			Name:     fields[0],
+			Commit:   fields[1],
+			Worktree: fields[2],
+			Current:  fields[2] != "" && fields[2] == current,
+		})
+	}
+	return branches, nil
+}
+
+// GetBranchBoard returns the board as committed on a branch (or any commit),
+// or as it is on disk in a worktree when ref has the "worktree:" prefix
+func (s *TaskStore) GetBranchBoard(ref string) (*models.BranchBoard, error) {
+	board, commit, source, err := s.readBranchBoard(ref)
+	if err != nil {
+		return nil, err
+	}
+	return &models.BranchBoard{
+		Ref:     ref,
+		Commit:  commit,
+		Source:  source,
+		Columns: board.columns,
+		Tasks:   boardTasks(board),
+	}, nil
+}
+
+// CompareBranches lists how the tasks on head differ from those on base.
+// Either side may be a branch, a commit or a "worktree:" reference.
+func (s *TaskStore) CompareBranches(base, head string) (*models.BoardComparison, error) {
+	before, _, _, err := s.readBranchBoard(base)
+	if err != nil {
+		return nil, err
+	}
+	after, _, _, err := s.readBranchBoard(head)
+	if err != nil {
+		return nil, err
+	}
+	return &models.BoardComparison{
+		Base:  base,
+		Head:  head,
+		Tasks: diffTaskMaps(before.tasks, after.tasks),
+	}, nil
+}
+
+// PreviewMerge reports what merging ref into the current branch would do to
+// the board, without changing anything. The board is merged task by task
+// against the merge base the same way external edits are merged, except that
+// nothing changed on both sides is resolved: such a field (or a task changed
+// on one side and deleted on the other) keeps its current value and is listed
+// as an unresolved conflict with the base value and both sides' values.
+// The current board includes edits that have not been committed yet.
+func (s *TaskStore) PreviewMerge(ref string) (*models.MergePreview, error) {
+	theirs, theirsCommit, _, err := s.readBranchBoard(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(s.filePath)
+	base := parseBoard(nil)
+	mergeBase := ""
+	if out, err := gitOutput(dir, "merge-base", "HEAD", theirsCommit); err == nil {
+		mergeBase = strings.TrimSpace(string(out))
+		if base, err = s.readCommittedBoard(mergeBase); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.RLock()
+	ours := s.snapshotLocked().tasks
+	s.mu.RUnlock()
+
+	merged := make(map[string]*models.Task, len(ours))
+	for id, task := range ours {
+		merged[id] = copyTask(task)
+	}
+	conflicts, _ := mergeTaskMaps(base.tasks, merged, theirs.tasks)
+	if conflicts == nil {
+		conflicts = []models.MergeConflict{}
+	}
+	for i := range conflicts {
+		unresolveConflict(&conflicts[i], ours, merged)
+	}
+
+	return &models.MergePreview{
+		Ref:       ref,
+		MergeBase: mergeBase,
+		Changes:   diffTaskMaps(ours, merged),
+		Conflicts: conflicts,
+	}, nil
+}
+
+// unresolveConflict undoes the resolution mergeTaskMaps chose for a conflict,
+// putting back the current board's side in merged, and marks it unresolved
+func unresolveConflict(conflict *models.MergeConflict, ours, merged map[string]*models.Task) {
+	conflict.Resolution = resolutionUnresolved
+	current, inOurs := ours[conflict.TaskID]
+	if conflict.Field == "task" {
+		// Changed on one side and deleted on the other
+		if inOurs {
+			merged[conflict.TaskID] = copyTask(current)
+		} else {
+			delete(merged, conflict.TaskID)
+		}
+		return
+	}
+	for _, field := range taskMergeFields {
+		if field.name == conflict.Field && inOurs {
+			field.take(merged[conflict.TaskID], current)
+		}
+	}
+}
+
+// readBranchBoard reads the board for a branch, commit or "worktree:"
+// reference, along with the commit it was read from and its source.
+// A branch without a tasks file has an empty board.
+func (s *TaskStore) readBranchBoard(ref string) (*boardSnapshot, string, string, error) {
+	if branch, ok := strings.CutPrefix(ref, worktreeRefPrefix); ok {
+		branches, err := s.ListBranches()
+		if err != nil {
+			return nil, "", "", err
+		}
+		for _, info := range branches {
+			if info.Name != branch || info.Worktree == "" {
+				continue
+			}
+			board, err := s.readWorktreeBoard(info.Worktree)
+			return board, info.Commit, boardSourceWorktree, err
+		}
+		return nil, "", "", fmt.Errorf("%w: no worktree has %s checked out", ErrUnknownRef, branch)
+	}
+
+	commit, err := s.resolveCommit(ref)
+	if err != nil {
+		return nil, "", "", err
+	}
+	board, err := s.readCommittedBoard(commit)
+	return board, commit, boardSourceCommit, err
+}
+
+// resolveCommit returns the commit hash a branch name or revision points to
+func (s *TaskStore) resolveCommit(ref string) (string, error) {
+	// Refuse anything git could take for an option
+	if ref == "" || strings.HasPrefix(ref, "-") {
+		return "", fmt.Errorf("%w: %q", ErrUnknownRef, ref)
+	}
+	out, err := gitOutput(filepath.Dir(s.filePath), "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrUnknownRef, ref)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// readCommittedBoard reads the board's files as of a commit
+func (s *TaskStore) readCommittedBoard(commit string) (*boardSnapshot, error) {
+	dir := filepath.Dir(s.filePath)
+	prefix, err := s.repoRelativeDir()
+	if err != nil {
+		return nil, err
+	}
+	return s.readBranchFiles(func(name string) ([]byte, error) {
+		data, err := gitOutput(dir, "show", commit+":"+path.Clean(prefix+name))
+		if err != nil {
+			return nil, fs.ErrNotExist
+		}
+		return data, nil
+	})
+}
+
+// readWorktreeBoard reads the board's files on disk in another worktree,
+// including changes that have not been committed
+func (s *TaskStore) readWorktreeBoard(worktree string) (*boardSnapshot, error) {
+	prefix, err := s.repoRelativeDir()
+	if err != nil {
+		return nil, err
+	}
+	return s.readBranchFiles(osBoardReader(filepath.Join(worktree, filepath.FromSlash(prefix))))
+}
+
+// readBranchFiles reads a board from another branch or worktree, in the
+// format of this board's storage. A branch without a tasks file has an empty
+// board.
+func (s *TaskStore) readBranchFiles(read boardReader) (*boardSnapshot, error) {
+	storage, ok := s.storage.(fileStorage)
+	if !ok {
+		return nil, fmt.Errorf("%w: the board is not stored in files", ErrUnknownRef)
+	}
+	board, err := storage.readFrom(read)
+	if errors.Is(err, fs.ErrNotExist) {
+		return parseBoard(nil), nil
+	}
+	return board, err
+}
+
+// repoRelativeDir returns the directory of the tasks file relative to the
+// root of the repository, which is the same in every worktree. It is empty
+// at the root, and ends in a slash otherwise.
+func (s *TaskStore) repoRelativeDir() (string, error) {
+	out, err := gitOutput(filepath.Dir(s.filePath), "rev-parse", "--show-prefix")
+	if err != nil {
+		return "", fmt.Errorf("%w: not a git repository", ErrUnknownRef)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// boardTasks returns a board's tasks in column order, then file order
+func boardTasks(board *boardSnapshot) []*models.Task {
+	position := make(map[models.Column]int, len(board.columns))
+	for i, col := range board.columns {
+		position[models.Column(col.Slug)] = i
+	}
+
+	tasks := make([]*models.Task, 0, len(board.tasks))
+	for _, task := range board.tasks {
+		tasks = append(tasks, task)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		if pi, pj := position[tasks[i].Column], position[tasks[j].Column]; pi != pj {
+			return pi < pj
+		}
+		return tasks[i].Order < tasks[j].Order
+	})
+	return tasks
+}
+
+// diffTaskMaps lists the tasks added, removed or changed between two boards,
+// comparing the same fields that are merged independently
+func diffTaskMaps(before, after map[string]*models.Task) []models.TaskDiff {
+	ids := make([]string, 0, len(after))
+	for id := range after {
+		ids = append(ids, id)
+	}
+	for id := range before {
+		if _, ok := after[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	diffs := []models.TaskDiff{}
+	for _, id := range ids {
+		b, a := before[id], after[id]
+		switch {
+		case b == nil:
+			diffs = append(diffs, models.TaskDiff{TaskID: id, Title: a.Title, Change: "added"})
+		case a == nil:
+			diffs = append(diffs, models.TaskDiff{TaskID: id, Title: b.Title, Change: "removed"})
+		default:
+			var fields []models.FieldChange
+			for _, field := range taskMergeFields {
+				if from, to := field.value(b), field.value(a); from != to {
+					fields = append(fields, models.FieldChange{Field: field.name, From: from, To: to})
+				}
+			}
+			if len(fields) > 0 {
+				diffs = append(diffs, models.TaskDiff{TaskID: id, Title: a.Title, Change: "changed", Fields: fields})
+			}
+		}
+	}
+	return diffs
+}
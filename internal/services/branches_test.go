@@ -0,0 +1,122 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"kantext/internal/models"
+)
+
+// branchBoard renders a board with task-a in column aColumn titled aTitle,
+// task-b with bPriority, task-c titled cTitle and, if withD, task-d
+func branchBoard(aColumn, aTitle, bPriority, cTitle string, withD bool) string {
+	task := func(title, id, priority string) string {
+		return fmt.Sprintf("- [ ] %s\n  - id: %s\n  - priority: %s\n  - requires_test: false\n\n", title, id, priority)
+	}
+	columns := map[string]string{"inbox": "", "done": ""}
+	columns[aColumn] += task(aTitle, "task-a", "low")
+	columns["inbox"] += task("Deploy", "task-b", bPriority) + task(cTitle, "task-c", "low")
+	if withD {
+		columns["done"] += task("Add login", "task-d", "medium")
+	}
+	return "# Kantext Tasks\n\n## Inbox\n\n" + columns["inbox"] + "## In Progress\n\n## Done\n\n" + columns["done"]
+}
+
+func TestTaskStore_Branches(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", "-b", "main", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v\n%s", err, out)
+	}
+	gitCommitAs(t, dir, branchBoard("inbox", "Build API", "medium", "Write docs", false), "Alice", "2024-01-01T00:00:00Z")
+
+	// On a feature branch in its own worktree, task-a is done, task-b lowered
+	// and task-d added; task-c is renamed but not committed
+	worktree := filepath.Join(t.TempDir(), "feature")
+	gitRun(t, dir, "Alice", "2024-01-01T00:00:00Z", "worktree", "add", "-q", "-b", "feature", worktree)
+	gitCommitAs(t, worktree, branchBoard("done", "Build API", "low", "Write docs", true), "Bob", "2024-01-02T00:00:00Z")
+	edited := branchBoard("done", "Build API", "low", "Write the docs", true)
+	if err := os.WriteFile(filepath.Join(worktree, "TASKS.md"), []byte(edited), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Meanwhile on main, task-a is renamed and task-b raised
+	gitCommitAs(t, dir, branchBoard("inbox", "Build the API", "high", "Write docs", false), "Carol", "2024-01-03T00:00:00Z")
+
+	store := NewTaskStore(dir)
+	defer store.Close()
+
+	branches, err := store.ListBranches()
+	if err != nil {
+		t.Fatalf("ListBranches failed: %v", err)
+	}
+	realWorktree, _ := filepath.EvalSymlinks(worktree)
+	if len(branches) != 2 || branches[0].Name != "feature" || branches[0].Worktree != realWorktree || branches[0].Current || !branches[1].Current {
+		t.Errorf("Unexpected branches: %+v", branches)
+	}
+
+	board, err := store.GetBranchBoard("feature")
+	if err != nil {
+		t.Fatalf("GetBranchBoard failed: %v", err)
+	}
+	if board.Source != "commit" || len(board.Tasks) != 4 || board.Tasks[3].ID != "task-d" {
+		t.Errorf("Unexpected feature board: %+v", board)
+	}
+
+	// Only the uncommitted rename differs between the branch and its worktree
+	comparison, err := store.CompareBranches("feature", "worktree:feature")
+	if err != nil {
+		t.Fatalf("CompareBranches failed: %v", err)
+	}
+	if len(comparison.Tasks) != 1 || comparison.Tasks[0].TaskID != "task-c" || comparison.Tasks[0].Fields[0].To != "Write the docs" {
+		t.Errorf("Unexpected comparison: %+v", comparison.Tasks)
+	}
+
+	// Merging feature moves task-a without undoing its rename and adds task-d.
+	// The priority both sides changed is left alone and reported unresolved.
+	preview, err := store.PreviewMerge("feature")
+	if err != nil {
+		t.Fatalf("PreviewMerge failed: %v", err)
+	}
+	changes := make(map[string]string)
+	for _, change := range preview.Changes {
+		for _, field := range change.Fields {
+			changes[change.TaskID+" "+field.Field] = field.From + " -> " + field.To
+		}
+		if change.Change == "added" {
+			changes[change.TaskID] = "added"
+		}
+	}
+	expected := map[string]string{
+		"task-a column": "inbox -> done",
+		"task-d":        "added",
+	}
+	if fmt.Sprint(changes) != fmt.Sprint(expected) {
+		t.Errorf("Expected changes %v, got %v", expected, changes)
+	}
+	expectedConflict := models.MergeConflict{
+		TaskID:     "task-b",
+		Title:      "Deploy",
+		Field:      "priority",
+		Base:       "medium",
+		Ours:       "high",
+		Theirs:     "low",
+		Resolution: "unresolved",
+	}
+	if len(preview.Conflicts) != 1 || preview.Conflicts[0] != expectedConflict {
+		t.Errorf("Expected an unresolved priority conflict on task-b, got %+v", preview.Conflicts)
+	}
+
+	if _, err := store.GetBranchBoard("no-such-branch"); !errors.Is(err, ErrUnknownRef) {
+		t.Errorf("Expected ErrUnknownRef, got %v", err)
+	}
+	if _, err := store.GetBranchBoard("--output=x"); !errors.Is(err, ErrUnknownRef) {
+		t.Errorf("Expected ErrUnknownRef for an option-like ref, got %v", err)
+	}
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"kantext/internal/models"
+)
+
+// normalizeChecklistText collapses whitespace so an item fits on its
+// "  - [ ] text" line in TASKS.md
+func normalizeChecklistText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// normalizeChecklist cleans up item text and drops empty items
+func normalizeChecklist(items []models.ChecklistItem) []models.ChecklistItem {
+	var result []models.ChecklistItem
+	for _, item := range items {
+		item.Text = normalizeChecklistText(item.Text)
+		if item.Text != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// GetChecklist returns a copy of a task's acceptance-criteria checklist
+func (s *TaskStore) GetChecklist(id string) ([]models.ChecklistItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	task, ok := s.tasks[id]
+	if !ok {
+		return nil, fmt.Errorf("task not found: %s", id)
+	}
+
+	items := make([]models.ChecklistItem, len(task.Checklist))
+	copy(items, task.Checklist)
+	return items, nil
+}
+
+// AddChecklistItem appends an unchecked item to a task's checklist
+func (s *TaskStore) AddChecklistItem(id, text, author string) (*models.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.tasks[id]
+	if !ok {
+		return nil, fmt.Errorf("task not found: %s", id)
+	}
+
+	text = normalizeChecklistText(text)
+	if text == "" {
+		return nil, fmt.Errorf("checklist item text is required")
+	}
+
+	snapshot := s.snapshotTasksLocked()
+	task.Checklist = append(task.Checklist, models.ChecklistItem{Text: text})
+	s.touchLocked(task, author)
+	undo := s.undoEntryLocked(fmt.Sprintf("Add checklist item to %q", task.Title), snapshot)
+
+	rollback := s.rollbackLocked(snapshot)
+	if err := s.saveLocked(); err != nil {
+		rollback()
+		return nil, err
+	}
+	s.pushUndoLocked(undo)
+
+	s.recordHistory(models.HistoryEvent{
+		TaskID:  id,
+		Type:    models.HistoryUpdated,
+		Author:  author,
+		Field:   "checklist",
+		Summary: fmt.Sprintf("Added item %d: %s", len(task.Checklist), text),
+	})
+
+	return task, nil
+}
+
+// UpdateChecklistItem changes the text or done state of a checklist item.
+// Items are numbered from 1 in the order they appear under the task.
+func (s *TaskStore) UpdateChecklistItem(id string, item int, req models.UpdateChecklistItemRequest) (*models.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.tasks[id]
+	if !ok {
+		return nil, fmt.Errorf("task not found: %s", id)
+	}
+	if item < 1 || item > len(task.Checklist) {
+		return nil, fmt.Errorf("checklist item %d not found on task %s", item, id)
+	}
+
+	snapshot := s.snapshotTasksLocked()
+	entry := &task.Checklist[item-1]
+	previous := *entry
+	if req.Text != nil {
+		text := normalizeChecklistText(*req.Text)
+		if text == "" {
+			return nil, fmt.Errorf("checklist item text is required")
+		}
+		entry.Text = text
+	}
+	if req.Done != nil {
+		entry.Done = *req.Done
+	}
+	s.touchLocked(task, req.Author)
+	undo := s.undoEntryLocked(fmt.Sprintf("Edit checklist item %d of %q", item, task.Title), snapshot)
+
+	rollback := s.rollbackLocked(snapshot)
+	if err := s.saveLocked(); err != nil {
+		rollback()
+		return nil, err
+	}
+	s.pushUndoLocked(undo)
+
+	if *entry != previous {
+		summary := fmt.Sprintf("Edited item %d: %s", item, entry.Text)
+		if entry.Done != previous.Done {
+			state := "Unchecked"
+			if entry.Done {
+				state = "Checked"
+			}
+			summary = fmt.Sprintf("%s item %d: %s", state, item, entry.Text)
+		}
+		s.recordHistory(models.HistoryEvent{
+			TaskID:  id,
+			Type:    models.HistoryUpdated,
+			Author:  req.Author,
+			Field:   "checklist",
+			Summary: summary,
+		})
+	}
+
+	return task, nil
+}
+
+// RemoveChecklistItem deletes a checklist item. Items are numbered from 1.
+func (s *TaskStore) RemoveChecklistItem(id string, item int, author string) (*models.Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.tasks[id]
+	if !ok {
+		return nil, fmt.Errorf("task not found: %s", id)
+	}
+	if item < 1 || item > len(task.Checklist) {
+		return nil, fmt.Errorf("checklist item %d not found on task %s", item, id)
+	}
+
+	snapshot := s.snapshotTasksLocked()
+	removed := task.Checklist[item-1]
+	task.Checklist = append(task.Checklist[:item-1:item-1], task.Checklist[item:]...)
+	s.touchLocked(task, author)
+	undo := s.undoEntryLocked(fmt.Sprintf("Remove checklist item %d from %q", item, task.Title), snapshot)
+
+	rollback := s.rollbackLocked(snapshot)
+	if err := s.saveLocked(); err != nil {
+		rollback()
+		return nil, err
+	}
+	s.pushUndoLocked(undo)
+
+	s.recordHistory(models.HistoryEvent{
+		TaskID:  id,
+		Type:    models.HistoryUpdated,
+		Author:  author,
+		Field:   "checklist",
+		Summary: fmt.Sprintf("Removed item %d: %s", item, removed.Text),
+	})
+
+	return task, nil
+}
+
+// touchLocked records that a task was modified. Caller must hold the write lock.
+func (s *TaskStore) touchLocked(task *models.Task, author string) {
+	task.UpdatedAt = time.Now().UTC()
+	if author != "" {
+		task.UpdatedBy = author
+	}
+}
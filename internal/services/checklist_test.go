@@ -0,0 +1,126 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"kantext/internal/models"
+)
+
+const checklistTestContent = `---
+stale_threshold_days: 7
+---
+# Kantext Tasks
+
+## Inbox
+
+- [ ] Login page
+  - id: task-check01
+  - priority: high
+  - requires_test: false
+  - criteria: Users can sign in
+  - [x] Form renders
+  - [ ] Invalid password shows an error
+  - [X] Remember me works
+
+## In Progress
+
+## Done
+`
+
+func TestTaskStore_Checklist_Parsing(t *testing.T) {
+	store, cleanup := setupTaskStoreEnv(t, checklistTestContent)
+	defer cleanup()
+
+	if count := len(store.GetAll()); count != 1 {
+		t.Fatalf("Expected 1 task (checklist items must not become tasks), got %d", count)
+	}
+
+	task, err := store.Get("task-check01")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+
+	expected := []models.ChecklistItem{
+		{Text: "Form renders", Done: true},
+		{Text: "Invalid password shows an error", Done: false},
+		{Text: "Remember me works", Done: true},
+	}
+	if len(task.Checklist) != len(expected) {
+		t.Fatalf("Expected %d checklist items, got %d: %+v", len(expected), len(task.Checklist), task.Checklist)
+	}
+	for i, want := range expected {
+		if task.Checklist[i] != want {
+			t.Errorf("Item %d: expected %+v, got %+v", i+1, want, task.Checklist[i])
+		}
+	}
+	if task.AcceptanceCriteria != "Users can sign in" {
+		t.Errorf("Expected criteria to be kept alongside the checklist, got %q", task.AcceptanceCriteria)
+	}
+
+	done, total := task.ChecklistProgress()
+	if done != 2 || total != 3 {
+		t.Errorf("Expected progress 2/3, got %d/%d", done, total)
+	}
+}
+
+func TestTaskStore_Checklist_UpdateAndRemove(t *testing.T) {
+	store, cleanup := setupTaskStoreEnv(t, checklistTestContent)
+	defer cleanup()
+
+	done := true
+	task, err := store.UpdateChecklistItem("task-check01", 2, models.UpdateChecklistItemRequest{Done: &done, Author: "Reviewer"})
+	if err != nil {
+		t.Fatalf("UpdateChecklistItem failed: %v", err)
+	}
+	if !task.Checklist[1].Done {
+		t.Error("Expected item 2 to be done")
+	}
+	if task.UpdatedBy != "Reviewer" {
+		t.Errorf("Expected updated_by 'Reviewer', got %q", task.UpdatedBy)
+	}
+
+	if _, err := store.AddChecklistItem("task-check01", "  Logout\n clears the session ", ""); err != nil {
+		t.Fatalf("AddChecklistItem failed: %v", err)
+	}
+	if _, err := store.RemoveChecklistItem("task-check01", 1, ""); err != nil {
+		t.Fatalf("RemoveChecklistItem failed: %v", err)
+	}
+
+	for _, item := range []int{0, 4} {
+		if _, err := store.UpdateChecklistItem("task-check01", item, models.UpdateChecklistItemRequest{Done: &done}); err == nil {
+			t.Errorf("Expected error for item %d", item)
+		}
+	}
+	if _, err := store.AddChecklistItem("task-check01", "   ", ""); err == nil {
+		t.Error("Expected error when adding an empty item")
+	}
+
+	store.mu.Lock()
+	err = store.saveToFile()
+	store.mu.Unlock()
+	if err != nil {
+		t.Fatalf("saveToFile failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(store.GetWorkingDir(), "TASKS.md"))
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	want := "  - [x] Invalid password shows an error\n  - [x] Remember me works\n  - [ ] Logout clears the session\n"
+	if !strings.Contains(string(data), want) {
+		t.Errorf("Expected saved file to contain %q, got:\n%s", want, data)
+	}
+
+	store2 := NewTaskStore(store.GetWorkingDir())
+	defer store2.Close()
+	reloaded, err := store2.Get("task-check01")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if len(reloaded.Checklist) != 3 || reloaded.Checklist[2].Text != "Logout clears the session" {
+		t.Errorf("Checklist did not round-trip: %+v", reloaded.Checklist)
+	}
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"kantext/internal/models"
+)
+
+// ErrInvalidDependency is returned when blocked_by references a missing task,
+// the task itself, or would create a dependency cycle.
+var ErrInvalidDependency = errors.New("invalid dependency")
+
+// ErrTaskBlocked is returned when a task with unfinished blockers is moved
+// into the in-progress column.
+var ErrTaskBlocked = errors.New("task is blocked")
+
+// parseIDList splits a comma-separated list of task IDs, dropping empty entries
+func parseIDList(value string) []string {
+	var ids []string
+	for _, id := range strings.Split(value, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// foldBlocksMetadata turns "blocks" declarations written in TASKS.md into
+// blocked_by entries on the tasks they point at, so blocked_by is the single
+// source of truth. References to unknown tasks are dropped.
+func foldBlocksMetadata(tasks map[string]*models.Task) {
+	for _, task := range tasks {
+		for _, id := range task.Blocks {
+			blocked, ok := tasks[id]
+			if !ok || id == task.ID || containsString(blocked.BlockedBy, task.ID) {
+				continue
+			}
+			blocked.BlockedBy = append(blocked.BlockedBy, task.ID)
+		}
+		task.Blocks = nil
+	}
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// isDoneColumnLocked returns true if tasks in the column count as finished:
+// the done column, or the last column (where passing tasks are moved).
+// Must be called with at least a read lock held.
+func (s *TaskStore) isDoneColumnLocked(column models.Column) bool {
+	if column == models.ColumnDone {
+		return true
+	}
+	lastCol := s.getLastColumn()
+	return lastCol != nil && column == models.Column(lastCol.Slug)
+}
+
+// linkDependenciesLocked recomputes the derived Blocks and Blocked fields
+// from every task's blocked_by list. Blockers that no longer exist are ignored.
+// Caller must hold the write lock.
+func (s *TaskStore) linkDependenciesLocked() {
+	for _, task := range s.tasks {
+		task.Blocks = nil
+		task.Blocked = false
+	}
+	for _, task := range s.tasks {
+		for _, id := range task.BlockedBy {
+			blocker, ok := s.tasks[id]
+			if !ok {
+				continue
+			}
+			blocker.Blocks = append(blocker.Blocks, task.ID)
+			if !s.isDoneColumnLocked(blocker.Column) {
+				task.Blocked = true
+			}
+		}
+	}
+	for _, task := range s.tasks {
+		sort.Strings(task.Blocks)
+	}
+}
+
+// unfinishedBlockersLocked returns the tasks in blockedBy that are not done.
+// Must be called with at least a read lock held.
+func (s *TaskStore) unfinishedBlockersLocked(blockedBy []string) []*models.Task {
+	var blockers []*models.Task
+	for _, id := range blockedBy {
+		if blocker, ok := s.tasks[id]; ok && !s.isDoneColumnLocked(blocker.Column) {
+			blockers = append(blockers, blocker)
+		}
+	}
+	return blockers
+}
+
+// isInProgressColumnLocked returns true if tasks in the column are being
+// worked on: the in-progress column, or a column after it that does not count
+// as finished (such as a Review column).
+// Must be called with at least a read lock held.
+func (s *TaskStore) isInProgressColumnLocked(column models.Column) bool {
+	if column == models.ColumnInProgress {
+		return true
+	}
+	if s.isDoneColumnLocked(column) {
+		return false
+	}
+	inProgress, order := -1, -1
+	for _, col := range s.columns {
+		switch models.Column(col.Slug) {
+		case models.ColumnInProgress:
+			inProgress = col.Order
+		case column:
+			order = col.Order
+		}
+	}
+	return inProgress >= 0 && order > inProgress
+}
+
+// checkCanMoveLocked returns ErrTaskBlocked if a task with the given blockers
+// may not be moved into column, because it would start the task.
+// Must be called with at least a read lock held.
+func (s *TaskStore) checkCanMoveLocked(task *models.Task, column models.Column, blockedBy []string) error {
+	if task.Column == column || !s.isInProgressColumnLocked(column) {
+		return nil
+	}
+	return s.checkCanStartLocked(task.ID, blockedBy)
+}
+
+// checkCanStartLocked returns ErrTaskBlocked if a task with the given
+// blockers may not be moved into an in-progress column.
+// Must be called with at least a read lock held.
+func (s *TaskStore) checkCanStartLocked(id string, blockedBy []string) error {
+	blockers := s.unfinishedBlockersLocked(blockedBy)
+	if len(blockers) == 0 {
+		return nil
+	}
+	names := make([]string, len(blockers))
+	for i, blocker := range blockers {
+		names[i] = fmt.Sprintf("%s (%s)", blocker.ID, blocker.Title)
+	}
+	return fmt.Errorf("%w: %s is waiting on %s", ErrTaskBlocked, id, strings.Join(names, ", "))
+}
+
+// validateBlockedByLocked cleans up a blocked_by list for task id and checks
+// that every blocker exists and that no cycle would be created. id may be
+// empty for a task that does not exist yet.
+// Must be called with at least a read lock held.
+func (s *TaskStore) validateBlockedByLocked(id string, blockedBy []string) ([]string, error) {
+	var cleaned []string
+	for _, blockerID := range blockedBy {
+		blockerID = strings.TrimSpace(blockerID)
+		if blockerID == "" || containsString(cleaned, blockerID) {
+			continue
+		}
+		if blockerID == id {
+			return nil, fmt.Errorf("%w: task %s cannot block itself", ErrInvalidDependency, id)
+		}
+		if _, ok := s.tasks[blockerID]; !ok {
+			return nil, fmt.Errorf("%w: blocking task not found: %s", ErrInvalidDependency, blockerID)
+		}
+		if id != "" && s.dependsOnLocked(blockerID, id) {
+			return nil, fmt.Errorf("%w: %s is already blocked by %s, which would create a cycle", ErrInvalidDependency, blockerID, id)
+		}
+		cleaned = append(cleaned, blockerID)
+	}
+	return cleaned, nil
+}
+
+// dependsOnLocked reports whether task from is (transitively) blocked by task target.
+// Must be called with at least a read lock held.
+func (s *TaskStore) dependsOnLocked(from, target string) bool {
+	visited := make(map[string]bool)
+	stack := []string{from}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if id == target {
+			return true
+		}
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+		if task, ok := s.tasks[id]; ok {
+			stack = append(stack, task.BlockedBy...)
+		}
+	}
+	return false
+}
+
+// removeDependencyLocked drops id from every task's blocked_by list.
+// Caller must hold the write lock.
+func (s *TaskStore) removeDependencyLocked(id string) {
+	for _, task := range s.tasks {
+		for i, blockerID := range task.BlockedBy {
+			if blockerID == id {
+				task.BlockedBy = append(task.BlockedBy[:i:i], task.BlockedBy[i+1:]...)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"kantext/internal/models"
+)
+
+const dependencyTestContent = `---
+stale_threshold_days: 7
+---
+# Kantext Tasks
+
+## Inbox
+
+- [ ] Design API
+  - id: task-design
+  - priority: high
+  - requires_test: false
+  - blocks: task-build
+
+- [ ] Build API
+  - id: task-build
+  - priority: medium
+  - requires_test: false
+
+- [ ] Ship API
+  - id: task-ship
+  - priority: low
+  - requires_test: false
+  - blocked_by: task-build, task-missing
+
+## In Progress
+
+## Done
+`
+
+func TestTaskStore_Dependencies_Parsing(t *testing.T) {
+	store, cleanup := setupTaskStoreEnv(t, dependencyTestContent)
+	defer cleanup()
+
+	build, _ := store.Get("task-build")
+	if strings.Join(build.BlockedBy, ",") != "task-design" {
+		t.Errorf("Expected 'blocks' on task-design to become blocked_by on task-build, got %v", build.BlockedBy)
+	}
+	if !build.Blocked {
+		t.Error("Expected task-build to be blocked")
+	}
+	if strings.Join(build.Blocks, ",") != "task-ship" {
+		t.Errorf("Expected task-build to block task-ship, got %v", build.Blocks)
+	}
+
+	design, _ := store.Get("task-design")
+	if design.Blocked || strings.Join(design.Blocks, ",") != "task-build" {
+		t.Errorf("Expected task-design unblocked and blocking task-build, got blocked=%t blocks=%v", design.Blocked, design.Blocks)
+	}
+
+	// Saving rewrites "blocks" as blocked_by on the blocked task
+	store.mu.Lock()
+	err := store.saveToFile()
+	store.mu.Unlock()
+	if err != nil {
+		t.Fatalf("saveToFile failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(store.GetWorkingDir(), "TASKS.md"))
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if strings.Contains(string(data), "- blocks:") {
+		t.Errorf("Expected no 'blocks' lines after save, got:\n%s", data)
+	}
+	if !strings.Contains(string(data), "  - blocked_by: task-design\n") {
+		t.Errorf("Expected blocked_by line for task-build, got:\n%s", data)
+	}
+}
+
+func TestTaskStore_Dependencies_Validation(t *testing.T) {
+	store, cleanup := setupTaskStoreEnv(t, dependencyTestContent)
+	defer cleanup()
+
+	tests := []struct {
+		name      string
+		id        string
+		blockedBy []string
+	}{
+		{"missing task", "task-design", []string{"task-nope"}},
+		{"self", "task-design", []string{"task-design"}},
+		{"direct cycle", "task-design", []string{"task-build"}},
+		{"transitive cycle", "task-design", []string{"task-ship"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := store.Update(tt.id, models.UpdateTaskRequest{BlockedBy: tt.blockedBy})
+			if !errors.Is(err, ErrInvalidDependency) {
+				t.Errorf("Expected ErrInvalidDependency, got %v", err)
+			}
+		})
+	}
+
+	if _, err := store.Create(models.CreateTaskRequest{Title: "Bad", BlockedBy: []string{"task-nope"}}); !errors.Is(err, ErrInvalidDependency) {
+		t.Errorf("Expected ErrInvalidDependency on create, got %v", err)
+	}
+
+	// Clearing dependencies is always allowed
+	task, err := store.Update("task-build", models.UpdateTaskRequest{BlockedBy: []string{}})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if len(task.BlockedBy) != 0 || task.Blocked {
+		t.Errorf("Expected no blockers, got %v (blocked=%t)", task.BlockedBy, task.Blocked)
+	}
+}
+
+func TestTaskStore_Dependencies_BlockedTaskCannotStart(t *testing.T) {
+	store, cleanup := setupTaskStoreEnv(t, dependencyTestContent)
+	defer cleanup()
+
+	inProgress := models.ColumnInProgress
+	if _, err := store.Update("task-build", models.UpdateTaskRequest{Column: &inProgress}); !errors.Is(err, ErrTaskBlocked) {
+		t.Errorf("Expected ErrTaskBlocked from Update, got %v", err)
+	}
+	if _, err := store.Reorder("task-build", inProgress, 0); !errors.Is(err, ErrTaskBlocked) {
+		t.Errorf("Expected ErrTaskBlocked from Reorder, got %v", err)
+	}
+
+	// Finishing the blocker unblocks the task
+	done := models.ColumnDone
+	if _, err := store.Update("task-design", models.UpdateTaskRequest{Column: &done}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	task, err := store.Update("task-build", models.UpdateTaskRequest{Column: &inProgress})
+	if err != nil {
+		t.Fatalf("Expected unblocked task to start, got %v", err)
+	}
+	if task.Blocked {
+		t.Error("Expected task-build to no longer be blocked")
+	}
+}
+
+func TestTaskStore_Dependencies_BlockedTaskCannotStartInCustomColumn(t *testing.T) {
+	store, cleanup := setupTaskStoreEnv(t, dependencyTestContent)
+	defer cleanup()
+
+	// Review sits between In Progress and Done, so work in it has started;
+	// Backlog comes before In Progress
+	for _, name := range []string{"Review", "Backlog"} {
+		if _, err := store.CreateColumn(name); err != nil {
+			t.Fatalf("CreateColumn failed: %v", err)
+		}
+	}
+	if err := store.ReorderColumns([]string{"backlog", "inbox", "in_progress", "review", "done"}); err != nil {
+		t.Fatalf("ReorderColumns failed: %v", err)
+	}
+
+	review := models.Column("review")
+	if _, err := store.Update("task-build", models.UpdateTaskRequest{Column: &review}); !errors.Is(err, ErrTaskBlocked) {
+		t.Errorf("Expected ErrTaskBlocked from Update, got %v", err)
+	}
+	if _, err := store.Reorder("task-build", review, 0); !errors.Is(err, ErrTaskBlocked) {
+		t.Errorf("Expected ErrTaskBlocked from Reorder, got %v", err)
+	}
+	if _, err := store.Reorder("task-build", "backlog", 0); err != nil {
+		t.Errorf("Expected a blocked task to move to the backlog, got %v", err)
+	}
+}
+
+func TestTaskStore_Dependencies_DeleteRemovesReferences(t *testing.T) {
+	store, cleanup := setupTaskStoreEnv(t, dependencyTestContent)
+	defer cleanup()
+
+	if err := store.Delete("task-design"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	build, _ := store.Get("task-build")
+	if len(build.BlockedBy) != 0 || build.Blocked {
+		t.Errorf("Expected deleted blocker to be removed, got %v (blocked=%t)", build.BlockedBy, build.Blocked)
+	}
+}
+
+func TestTaskStore_StartNextTask_SkipsBlockedTasks(t *testing.T) {
+	store, cleanup := setupTaskStoreEnv(t, dependencyTestContent)
+	defer cleanup()
+
+	for _, id := range []string{"task-ship", "task-build", "task-design"} {
+		if err := store.AddToQueue(id, -1); err != nil {
+			t.Fatalf("AddToQueue(%s) failed: %v", id, err)
+		}
+	}
+
+	taskID, err := store.StartNextTask()
+	if err != nil {
+		t.Fatalf("StartNextTask failed: %v", err)
+	}
+	if taskID != "task-design" {
+		t.Errorf("Expected the only unblocked task 'task-design', got %q", taskID)
+	}
+
+	queue := store.GetAIQueue()
+	if queue.TaskIDs[0] != "task-design" || queue.ActiveTaskID != "task-design" {
+		t.Errorf("Expected active task at the front of the queue, got %+v", queue)
+	}
+	if strings.Join(queue.TaskIDs[1:], ",") != "task-ship,task-build" {
+		t.Errorf("Expected remaining queue order to be kept, got %v", queue.TaskIDs)
+	}
+
+	task, _ := store.Get("task-design")
+	if task.Column != models.ColumnInProgress {
+		t.Errorf("Expected started task in progress, got %s", task.Column)
+	}
+}
+
+func TestTaskStore_StartNextTask_AllBlocked(t *testing.T) {
+	store, cleanup := setupTaskStoreEnv(t, dependencyTestContent)
+	defer cleanup()
+
+	if err := store.AddToQueue("task-build", -1); err != nil {
+		t.Fatalf("AddToQueue failed: %v", err)
+	}
+	if _, err := store.StartNextTask(); !errors.Is(err, ErrTaskBlocked) {
+		t.Errorf("Expected ErrTaskBlocked, got %v", err)
+	}
+	if store.GetAIQueue().ActiveTaskID != "" {
+		t.Error("Expected no active task")
+	}
+}
@@ -3,14 +3,18 @@ package services
 import (
 	"log"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
 )
 
-// FileWatcher monitors a file for changes and notifies the hub
+// FileWatcher monitors a file, and any other files added with WatchFile,
+// for changes and notifies the hub
 type FileWatcher struct {
 	filePath     string
+	files        map[string]bool // Cleaned paths of the watched files
+	filesMu      sync.Mutex      // Protects files
 	hub          *WSHub
 	watcher      *fsnotify.Watcher
 	debounce     time.Duration
@@ -26,6 +30,7 @@ func NewFileWatcher(filePath string, hub *WSHub) (*FileWatcher, error) {
 
 	return &FileWatcher{
 		filePath: filePath,
+		files:    map[string]bool{filepath.Clean(filePath): true},
 		hub:      hub,
 		watcher:  watcher,
 		debounce: 1 * time.Second, // Wait before processing to handle git operations that briefly remove files
@@ -39,29 +44,49 @@ func (fw *FileWatcher) SetOnFileChange(callback func()) {
 
 // Start begins watching the file for changes
 func (fw *FileWatcher) Start() error {
-	// Get the directory containing the file (to handle file replacements)
-	dir := filepath.Dir(fw.filePath)
-	filename := filepath.Base(fw.filePath)
-
 	// Watch the directory, not the file directly
 	// This handles cases where the file is replaced (atomic writes)
-	err := fw.watcher.Add(dir)
+	err := fw.watcher.Add(filepath.Dir(filepath.Clean(fw.filePath)))
 	if err != nil {
 		return err
 	}
 
 	log.Printf("File watcher started for: %s", fw.filePath)
 
-	go fw.watch(filename)
+	go fw.watch()
 	return nil
 }
 
+// WatchFile adds another file to watch, such as a file included in the
+// board. Watching a file twice has no effect.
+func (fw *FileWatcher) WatchFile(path string) error {
+	path = filepath.Clean(path)
+	fw.filesMu.Lock()
+	defer fw.filesMu.Unlock()
+	if fw.files[path] {
+		return nil
+	}
+	if err := fw.watcher.Add(filepath.Dir(path)); err != nil {
+		return err
+	}
+	fw.files[path] = true
+	log.Printf("File watcher started for: %s", path)
+	return nil
+}
+
+// isWatched reports whether a path is one of the watched files
+func (fw *FileWatcher) isWatched(path string) bool {
+	fw.filesMu.Lock()
+	defer fw.filesMu.Unlock()
+	return fw.files[filepath.Clean(path)]
+}
+
 // Stop stops the file watcher
 func (fw *FileWatcher) Stop() error {
 	return fw.watcher.Close()
 }
 
-func (fw *FileWatcher) watch(targetFilename string) {
+func (fw *FileWatcher) watch() {
 	var debounceTimer *time.Timer
 
 	for {
@@ -71,9 +96,8 @@ func (fw *FileWatcher) watch(targetFilename string) {
 				return
 			}
 
-			// Only care about our target file
-			eventFilename := filepath.Base(event.Name)
-			if eventFilename != targetFilename {
+			// Only care about our target files
+			if !fw.isWatched(event.Name) {
 				continue
 			}
 
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"kantext/internal/models"
+)
+
+// gitAuthorship is what the git history of TASKS.md says about who wrote
+// each task, as of the HEAD commit
+type gitAuthorship struct {
+	createdBy  map[string]string          // Task ID -> author of the commit that introduced the task
+	modifiedBy map[string]gitModification // Task ID -> newest commit touching the task's content
+}
+
+// gitModification describes the newest commit that changed a task's block
+type gitModification struct {
+	author string
+	time   time.Time
+	// The commit also changed the task's updated_at line, meaning the edit
+	// was made through Kantext, which recorded its own author for it
+	touchedUpdatedAt bool
+}
+
+// gitAuthorCache holds the authorship for one HEAD commit. Walking the
+// history is far slower than reading the board, so it is only redone when
+// HEAD moves.
+type gitAuthorCache struct {
+	mu         sync.Mutex
+	head       string
+	authorship *gitAuthorship
+}
+
+// refreshGitAuthors fills in task authors from git history. Authors already
+// recorded in TASKS.md (or passed to the API) take precedence: git only
+// supplies a missing creator, and replaces the last editor when a newer
+// commit changed the task outside Kantext. Runs when the board is loaded and
+// whenever git is checked again (see SyncCommits); readers only see the
+// authors filled in here. Caller must hold the write lock.
+func (s *TaskStore) refreshGitAuthors() {
+	authorship := s.gitAuthors.get(s.filePath)
+	if authorship == nil {
+		return // Not a git repository, or TASKS.md is not committed
+	}
+	for id, task := range s.tasks {
+		applyGitAuthorship(task, authorship.createdBy[id], authorship.modifiedBy[id])
+	}
+}
+
+// applyGitAuthorship merges the authors found in git into a task
+func applyGitAuthorship(task *models.Task, creator string, modified gitModification) {
+	if task.CreatedBy == "" {
+		task.CreatedBy = creator
+	}
+	if modified.author == "" {
+		return
+	}
+	// An edit made through Kantext and then committed by someone else is
+	// still attributed to whoever made it
+	if task.UpdatedBy == "" || (!modified.touchedUpdatedAt && modified.time.After(task.UpdatedAt)) {
+		task.UpdatedBy = modified.author
+	}
+}
+
+// get returns the authorship of the file at HEAD, walking the history again
+// only if HEAD has moved. Returns nil if git history is not available.
+func (c *gitAuthorCache) get(filePath string) *gitAuthorship {
+	dir := filepath.Dir(filePath)
+	out, err := gitOutput(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return nil
+	}
+	head := strings.TrimSpace(string(out))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.head == head {
+		return c.authorship
+	}
+
+	name := filepath.Base(filePath)
+	lines, err := gitBlame(dir, name)
+	if err != nil {
+		return nil
+	}
+	creators, err := gitTaskCreators(dir, name)
+	if err != nil {
+		return nil
+	}
+	c.head = head
+	c.authorship = &gitAuthorship{
+		createdBy:  creators,
+		modifiedBy: gitTaskModifications(lines),
+	}
+	return c.authorship
+}
+
+// gitOutput runs a git command in dir and returns its standard output
+func gitOutput(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	return cmd.Output()
+}
+
+// blameLine is one line of TASKS.md at HEAD with the commit that last changed it
+type blameLine struct {
+	commit  string
+	author  string
+	time    time.Time
+	content string
+}
+
+// gitBlame blames the committed version of a file, ignoring uncommitted changes
+func gitBlame(dir, name string) ([]blameLine, error) {
+	out, err := gitOutput(dir, "blame", "--line-porcelain", "HEAD", "--", name)
+	if err != nil {
+		return nil, err
+	}
+
+	// --line-porcelain repeats the commit details for every line, so each
+	// line can be read on its own
+	var lines []blameLine
+	var current blameLine
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "\t"):
+			current.content = strings.TrimPrefix(line, "\t")
+			lines = append(lines, current)
+			current = blameLine{}
+		case current.commit == "":
+			current.commit, _, _ = strings.Cut(line, " ")
+		case strings.HasPrefix(line, "author "):
+			current.author = strings.TrimPrefix(line, "author ")
+		case strings.HasPrefix(line, "author-time "):
+			if secs, err := strconv.ParseInt(strings.TrimPrefix(line, "author-time "), 10, 64); err == nil {
+				current.time = time.Unix(secs, 0).UTC()
+			}
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// gitTaskModifications finds the newest commit to change each task's block:
+// its title line and the indented lines below it. Author metadata lines are
+// skipped, since writing back an author found in git is not an edit.
+func gitTaskModifications(lines []blameLine) map[string]gitModification {
+	modified := make(map[string]gitModification)
+
+	var id string
+	var block []blameLine
+	var updatedAtCommit string
+	finish := func() {
+		if id != "" {
+			var newest blameLine
+			for _, line := range block {
+				if line.time.After(newest.time) || newest.commit == "" {
+					newest = line
+				}
+			}
+			modified[id] = gitModification{
+				author:           newest.author,
+				time:             newest.time,
+				touchedUpdatedAt: updatedAtCommit == newest.commit,
+			}
+		}
+		id, block, updatedAtCommit = "", nil, ""
+	}
+
+	inTask := false
+	for _, line := range lines {
+		switch {
+		case taskTitleRegex.MatchString(line.content):
+			finish()
+			inTask = true
+			block = append(block, line)
+		case !inTask:
+		case strings.HasPrefix(line.content, "  "):
+			key, value, _ := strings.Cut(strings.TrimPrefix(line.content, "  - "), ":")
+			switch key {
+			case "created_by", "updated_by":
+				continue
+			case "id":
+				id = strings.TrimSpace(value)
+			case "updated_at":
+				updatedAtCommit = line.commit
+			}
+			block = append(block, line)
+		case strings.TrimSpace(line.content) == "":
+			// Blank lines may separate a task's notes; they are not content
+		default:
+			finish()
+			inTask = false
+		}
+	}
+	finish()
+	return modified
+}
+
+// gitTaskCreators finds, for every task ID that ever appeared in the file,
+// the author of the commit that added its id line. Renames of the file are
+// followed.
+func gitTaskCreators(dir, name string) (map[string]string, error) {
+	out, err := gitOutput(dir, "log", "--follow", "--no-color", "--no-ext-diff",
+		"-p", "--unified=0", "--format=%x00%an", "--", name)
+	if err != nil {
+		return nil, err
+	}
+
+	// The log runs newest first, so the last commit seen adding an ID is the
+	// one that introduced it
+	creators := make(map[string]string)
+	var author string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "\x00") {
+			author = strings.TrimPrefix(line, "\x00")
+			continue
+		}
+		if id, ok := strings.CutPrefix(line, "+  - id: "); ok {
+			creators[strings.TrimSpace(id)] = author
+		}
+	}
+	return creators, scanner.Err()
+}
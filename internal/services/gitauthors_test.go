@@ -0,0 +1,145 @@
+package services
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"kantext/internal/models"
+)
+
+// gitCommitAs writes TASKS.md and commits it with the given author and date
+func gitCommitAs(t *testing.T, dir, content, author, date string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, "TASKS.md"), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write TASKS.md: %v", err)
+	}
+	gitCommitFile(t, dir, author, date)
+}
+
+// gitCommitFile commits TASKS.md as it is on disk
+func gitCommitFile(t *testing.T, dir, author, date string) {
+	t.Helper()
+	gitRun(t, dir, author, date, "add", "TASKS.md")
+	gitRun(t, dir, author, date, "commit", "-q", "-m", "Update tasks")
+}
+
+// gitRun runs a git command in dir as the given author and date
+func gitRun(t *testing.T, dir, author, date string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "commit.gpgsign=false"}, args...)...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME="+author, "GIT_AUTHOR_EMAIL=dev@example.com", "GIT_AUTHOR_DATE="+date,
+		"GIT_COMMITTER_NAME="+author, "GIT_COMMITTER_EMAIL=dev@example.com", "GIT_COMMITTER_DATE="+date)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+}
+
+func TestTaskStore_GitAuthors(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v\n%s", err, out)
+	}
+
+	// Alice adds both tasks; task-b records its own author
+	gitCommitAs(t, dir, `# Kantext Tasks
+
+## Inbox
+
+- [ ] Write docs
+  - id: task-a
+  - priority: low
+  - requires_test: false
+
+- [ ] Build API
+  - id: task-b
+  - priority: high
+  - requires_test: false
+  - created_by: Carol
+  - updated_at: 2023-12-31T00:00:00Z
+  - updated_by: Carol
+`, "Alice", "2024-01-01T00:00:00Z")
+
+	// Bob renames task-a by hand and commits an edit Dana made to task-b
+	gitCommitAs(t, dir, `# Kantext Tasks
+
+## Inbox
+
+- [ ] Write the docs
+  - id: task-a
+  - priority: low
+  - requires_test: false
+
+- [ ] Build the API
+  - id: task-b
+  - priority: high
+  - requires_test: false
+  - created_by: Carol
+  - updated_at: 2024-01-02T00:00:00Z
+  - updated_by: Dana
+`, "Bob", "2024-01-02T00:00:00Z")
+
+	checkAuthors := func() {
+		t.Helper()
+		store := NewTaskStore(dir)
+		defer store.Close()
+		for _, tt := range []struct{ id, createdBy, updatedBy string }{
+			{"task-a", "Alice", "Bob"},
+			{"task-b", "Carol", "Dana"},
+		} {
+			task, err := store.Get(tt.id)
+			if err != nil {
+				t.Fatalf("Get(%s) failed: %v", tt.id, err)
+			}
+			if task.CreatedBy != tt.createdBy || task.UpdatedBy != tt.updatedBy {
+				t.Errorf("%s: expected created by %s and updated by %s, got %s and %s",
+					tt.id, tt.createdBy, tt.updatedBy, task.CreatedBy, task.UpdatedBy)
+			}
+		}
+	}
+	checkAuthors()
+
+	// Erin commits a creator written back by Kantext, which is not an edit
+	gitCommitAs(t, dir, `# Kantext Tasks
+
+## Inbox
+
+- [ ] Write the docs
+  - id: task-a
+  - priority: low
+  - requires_test: false
+  - created_by: Alice
+
+- [ ] Build the API
+  - id: task-b
+  - priority: high
+  - requires_test: false
+  - created_by: Carol
+  - updated_at: 2024-01-02T00:00:00Z
+  - updated_by: Dana
+`, "Erin", "2024-01-03T00:00:00Z")
+
+	checkAuthors()
+
+	// Authors passed to the API are kept once someone else commits the task
+	store := NewTaskStore(dir)
+	defer store.Close()
+	store.SetDurableWrites(true)
+
+	created, err := store.Create(models.CreateTaskRequest{Title: "New task", Author: "Frank"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	gitCommitFile(t, dir, "Erin", "2030-01-01T00:00:00Z")
+	store.SyncCommits()
+	task, _ := store.Get(created.ID)
+	if task.CreatedBy != "Frank" || task.UpdatedBy != "Frank" {
+		t.Errorf("Expected API author to be kept, got %s and %s", task.CreatedBy, task.UpdatedBy)
+	}
+}
@@ -0,0 +1,276 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"kantext/internal/models"
+)
+
+var (
+	// taskMentionRegex finds task IDs in commit messages and branch names,
+	// including trailers such as "Kantext-Task: task-AB12CD34"
+	taskMentionRegex = regexp.MustCompile(`\btask-[0-9A-Za-z]+\b`)
+	// taskClosingRegex finds task IDs a commit says it closes, e.g. "Closes task-AB12CD34"
+	taskClosingRegex = regexp.MustCompile(`(?i)\b(?:close[sd]?|fix(?:e[sd])?|resolve[sd]?):?\s+(task-[0-9A-Za-z]+)\b`)
+)
+
+// gitLinks are the commits and branches that mention each task
+type gitLinks struct {
+	commits  map[string][]models.TaskCommit // Task ID -> commits, newest first
+	branches map[string][]string            // Task ID -> local branches named after it
+}
+
+// gitLinkCache holds the links for one state of the local branches.
+// Scanning the log is slow, so it is only redone when a branch moves.
+type gitLinkCache struct {
+	mu    sync.Mutex
+	key   string
+	links *gitLinks
+}
+
+// get returns the links for the repository containing dir, rescanning only
+// if a branch has moved. Returns nil if git is not available.
+func (c *gitLinkCache) get(dir, mainBranch string) *gitLinks {
+	refs, err := gitOutput(dir, "for-each-ref", "--format=%(objectname) %(refname:short)", "refs/heads")
+	if err != nil {
+		return nil
+	}
+	key := mainBranch + "\n" + string(refs)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.key == key && c.links != nil {
+		return c.links
+	}
+
+	links, err := scanGitLinks(dir, string(refs), mainBranch)
+	if err != nil {
+		return nil
+	}
+	c.key, c.links = key, links
+	return links
+}
+
+// scanGitLinks reads the log of every local branch for commits mentioning
+// tasks, and marks those that have landed on the main branch
+func scanGitLinks(dir, refs, mainBranch string) (*gitLinks, error) {
+	links := &gitLinks{
+		commits:  make(map[string][]models.TaskCommit),
+		branches: make(map[string][]string),
+	}
+
+	var branchNames []string
+	for _, line := range strings.Split(strings.TrimSpace(refs), "\n") {
+		if _, name, ok := strings.Cut(line, " "); ok {
+			branchNames = append(branchNames, name)
+		}
+	}
+	for _, name := range branchNames {
+		for _, id := range uniqueMatches(taskMentionRegex, name, 0) {
+			links.branches[id] = append(links.branches[id], name)
+		}
+	}
+	if len(branchNames) == 0 {
+		return links, nil // No commits yet
+	}
+
+	onMain := make(map[string]bool)
+	if mainBranch = resolveMainBranch(mainBranch, branchNames); mainBranch != "" {
+		out, err := gitOutput(dir, "rev-list", "refs/heads/"+mainBranch)
+		if err != nil {
+			return nil, err
+		}
+		for _, hash := range strings.Fields(string(out)) {
+			onMain[hash] = true
+		}
+	}
+
+	out, err := gitOutput(dir, "log", "--branches", "--format=%x1e%H%x1f%an%x1f%ct%x1f%B")
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range bytes.Split(out, []byte{0x1e}) {
+		fields := strings.SplitN(string(record), "\x1f", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		mentioned := uniqueMatches(taskMentionRegex, fields[3], 0)
+		if len(mentioned) == 0 {
+			continue
+		}
+		closes := make(map[string]bool)
+		for _, id := range uniqueMatches(taskClosingRegex, fields[3], 1) {
+			closes[id] = true
+		}
+		secs, _ := strconv.ParseInt(fields[2], 10, 64)
+		subject, _, _ := strings.Cut(strings.TrimSpace(fields[3]), "\n")
+		for _, id := range mentioned {
+			links.commits[id] = append(links.commits[id], models.TaskCommit{
+				Hash:         fields[0],
+				Author:       fields[1],
+				Time:         time.Unix(secs, 0).UTC(),
+				Subject:      subject,
+				Closes:       closes[id],
+				OnMainBranch: onMain[fields[0]],
+			})
+		}
+	}
+	return links, nil
+}
+
+// resolveMainBranch returns the configured main branch if it exists, or
+// "main" or "master" when none is configured. Returns "" if there is none.
+func resolveMainBranch(configured string, branches []string) string {
+	candidates := []string{configured}
+	if configured == "" {
+		candidates = []string{"main", "master"}
+	}
+	for _, candidate := range candidates {
+		for _, name := range branches {
+			if name == candidate {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// uniqueMatches returns the distinct values of a regexp group in s, in order
+func uniqueMatches(re *regexp.Regexp, s string, group int) []string {
+	var values []string
+	seen := make(map[string]bool)
+	for _, match := range re.FindAllStringSubmatch(s, -1) {
+		if value := match[group]; !seen[value] {
+			seen[value] = true
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// refreshGitLinks attaches the commits and branches that mention each task.
+// Readers only see the links attached here, and never run git themselves.
+// Caller must hold the write lock.
+func (s *TaskStore) refreshGitLinks() {
+	links := s.gitLinks.get(filepath.Dir(s.filePath), s.settings.Git.MainBranch)
+	for id, task := range s.tasks {
+		task.Commits, task.Branches = nil, nil
+		if links != nil {
+			task.Commits, task.Branches = links.commits[id], links.branches[id]
+		}
+	}
+}
+
+// refreshFromGit fills in everything the board learns from git: authors,
+// and the commits and branches that mention each task.
+// Caller must hold the write lock.
+func (s *TaskStore) refreshFromGit() {
+	s.refreshGitAuthors()
+	s.refreshGitLinks()
+}
+
+// GetCommits returns the commits that mention a task, newest first, as of
+// the last time git was checked (see SyncCommits)
+func (s *TaskStore) GetCommits(id string) ([]models.TaskCommit, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	task, ok := s.tasks[id]
+	if !ok {
+		return nil, fmt.Errorf("task not found: %s", id)
+	}
+	if task.Commits == nil {
+		return []models.TaskCommit{}, nil
+	}
+	return task.Commits, nil
+}
+
+// SyncCommits reads authors (when HEAD has moved) and the commits and
+// branches that mention each task again, and applies the git.close_column
+// rule: a task mentioned as "Closes task-…" by a commit on the main branch is
+// moved to that column, unless the task has been changed since the commit
+// (e.g. moved back by hand). Returns the IDs of the tasks moved. Servers call
+// it periodically, since commits land in git without touching TASKS.md.
+func (s *TaskStore) SyncCommits() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.refreshFromGit()
+	snapshot := s.snapshotTasksLocked()
+	events := s.closeTasksLocked()
+	if len(events) == 0 {
+		return nil, nil
+	}
+	rollback := s.rollbackLocked(snapshot)
+	if err := s.saveLocked(); err != nil {
+		// The tasks are closed again on the next check
+		rollback()
+		return nil, err
+	}
+	s.recordHistory(events...)
+
+	moved := make([]string, len(events))
+	for i, event := range events {
+		moved[i] = event.TaskID
+	}
+	return moved, nil
+}
+
+// closeTasksLocked moves tasks closed by commits on the main branch to the
+// configured column and returns the history events for the moves.
+// Commit links must be current. Caller must hold the write lock.
+func (s *TaskStore) closeTasksLocked() []models.HistoryEvent {
+	column := models.Column(s.settings.Git.CloseColumn)
+	if column == "" || !s.columnExistsLocked(string(column)) {
+		return nil
+	}
+
+	ids := make([]string, 0, len(s.tasks))
+	for id := range s.tasks {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var events []models.HistoryEvent
+	for _, id := range ids {
+		task := s.tasks[id]
+		if task.Column == column {
+			continue
+		}
+		for _, commit := range task.Commits {
+			if !commit.Closes || !commit.OnMainBranch || !commit.Time.After(task.UpdatedAt) {
+				continue
+			}
+			events = append(events, models.HistoryEvent{
+				TaskID:  id,
+				Type:    models.HistoryMoved,
+				Author:  commit.Author,
+				From:    string(task.Column),
+				To:      string(column),
+				Summary: fmt.Sprintf("Closed by commit %s", shortHash(commit.Hash)),
+			})
+			task.Column = column
+			task.Order = s.endOfColumnOrderLocked(column)
+			task.UpdatedAt = time.Now().UTC()
+			task.UpdatedBy = commit.Author
+			break
+		}
+	}
+	return events
+}
+
+// shortHash abbreviates a commit hash for display
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"os/exec"
+	"sync"
+	"testing"
+
+	"kantext/internal/models"
+)
+
+const gitCommitsTestContent = `---
+git:
+  close_column: done
+---
+# Kantext Tasks
+
+## Inbox
+
+- [ ] Build API
+  - id: task-a
+  - priority: high
+  - requires_test: false
+  - created_at: 2024-01-01T00:00:00Z
+  - updated_at: 2024-01-01T00:00:00Z
+
+- [ ] Write docs
+  - id: task-b
+  - priority: low
+  - requires_test: false
+  - created_at: 2024-01-01T00:00:00Z
+  - updated_at: 2024-01-01T00:00:00Z
+
+## In Progress
+
+## Done
+`
+
+func TestTaskStore_GitCommits(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", "-b", "main", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v\n%s", err, out)
+	}
+	gitCommitAs(t, dir, gitCommitsTestContent, "Alice", "2024-01-01T00:00:00Z")
+
+	gitRun(t, dir, "Bob", "2024-02-01T00:00:00Z", "commit", "-q", "--allow-empty", "-m", "Add API handlers\n\nKantext-Task: task-a")
+	gitRun(t, dir, "Bob", "2024-02-01T00:00:00Z", "branch", "feature/task-b")
+	gitRun(t, dir, "Carol", "2024-03-01T00:00:00Z", "commit", "-q", "--allow-empty", "-m", "Document the API\n\nCloses task-b")
+
+	// A closing commit that is not on the main branch does not move the task
+	gitRun(t, dir, "Dana", "2024-04-01T00:00:00Z", "checkout", "-q", "-b", "experiment")
+	gitRun(t, dir, "Dana", "2024-04-01T00:00:00Z", "commit", "-q", "--allow-empty", "-m", "Fixes task-a")
+	gitRun(t, dir, "Dana", "2024-04-01T00:00:00Z", "checkout", "-q", "main")
+
+	store := NewTaskStore(dir)
+	defer store.Close()
+	store.SetDurableWrites(true)
+
+	commits, err := store.GetCommits("task-a")
+	if err != nil {
+		t.Fatalf("GetCommits failed: %v", err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("Expected 2 commits for task-a, got %+v", commits)
+	}
+	if c := commits[0]; c.Subject != "Fixes task-a" || !c.Closes || c.OnMainBranch || c.Author != "Dana" {
+		t.Errorf("Unexpected newest commit: %+v", c)
+	}
+	if c := commits[1]; c.Subject != "Add API handlers" || c.Closes || !c.OnMainBranch || c.Author != "Bob" {
+		t.Errorf("Unexpected trailer commit: %+v", c)
+	}
+
+	// task-b was closed by Carol's commit on main when the board loaded
+	task, _ := store.Get("task-b")
+	if task.Column != models.ColumnDone || task.UpdatedBy != "Carol" {
+		t.Errorf("Expected task-b moved to done by Carol, got %s by %s", task.Column, task.UpdatedBy)
+	}
+	if len(task.Branches) != 1 || task.Branches[0] != "feature/task-b" {
+		t.Errorf("Expected branch feature/task-b, got %v", task.Branches)
+	}
+	if task, _ := store.Get("task-a"); task.Column != models.ColumnInbox {
+		t.Errorf("Expected task-a to stay in inbox, got %s", task.Column)
+	}
+	events, _ := store.GetHistory("task-b")
+	if last := events[len(events)-1]; last.Type != models.HistoryMoved || last.To != "done" {
+		t.Errorf("Expected a move to done in history, got %+v", last)
+	}
+
+	// A task moved back by hand stays put
+	if _, err := store.Reorder("task-b", models.ColumnInbox, 0); err != nil {
+		t.Fatalf("Reorder failed: %v", err)
+	}
+	moved, err := store.SyncCommits()
+	if err != nil {
+		t.Fatalf("SyncCommits failed: %v", err)
+	}
+	if len(moved) != 0 {
+		t.Errorf("Expected no tasks moved, got %v", moved)
+	}
+
+	// Once the experiment is merged, its closing commit moves task-a
+	gitRun(t, dir, "Dana", "2030-01-01T00:00:00Z", "merge", "-q", "--ff-only", "experiment")
+	moved, err = store.SyncCommits()
+	if err != nil {
+		t.Fatalf("SyncCommits failed: %v", err)
+	}
+	if len(moved) != 1 || moved[0] != "task-a" {
+		t.Errorf("Expected task-a to be moved, got %v", moved)
+	}
+
+	// Reads never run git: new commits show up once git is checked again,
+	// which may happen while the board is being read
+	gitRun(t, dir, "Erin", "2030-02-01T00:00:00Z", "commit", "-q", "--allow-empty", "-m", "Review the docs for task-b")
+	if commits, _ := store.GetCommits("task-b"); len(commits) != 1 {
+		t.Errorf("Expected the links from the last check, got %+v", commits)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				for _, task := range store.GetAll() {
+					_ = len(task.Commits) + len(task.Branches)
+				}
+				store.GetCommits("task-b")
+			}
+		}()
+	}
+	if _, err := store.SyncCommits(); err != nil {
+		t.Fatalf("SyncCommits failed: %v", err)
+	}
+	wg.Wait()
+	if commits, _ := store.GetCommits("task-b"); len(commits) != 2 || commits[0].Author != "Erin" {
+		t.Errorf("Expected Erin's commit after checking git, got %+v", commits)
+	}
+}
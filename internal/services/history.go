@@ -0,0 +1,187 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"kantext/internal/models"
+)
+
+// HistoryLog is an append-only JSON Lines log of task events, kept in a
+// sidecar file next to TASKS.md so the markdown stays readable
+type HistoryLog struct {
+	path string
+	mu   sync.Mutex // Serialises appends
+}
+
+// NewHistoryLog creates a history log stored at path. The file and its
+// directory are created on the first append.
+func NewHistoryLog(path string) *HistoryLog {
+	return &HistoryLog{path: path}
+}
+
+// Append writes events to the end of the log, one JSON object per line
+func (h *HistoryLog) Append(events ...models.HistoryEvent) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return appendJSONLines(h.path, events)
+}
+
+// ForTask returns the events recorded for a task, oldest first.
+// Lines that cannot be parsed (e.g. a partial write) are skipped.
+func (h *HistoryLog) ForTask(id string) ([]models.HistoryEvent, error) {
+	return readJSONLines(h.path, func(event models.HistoryEvent) bool {
+		return event.TaskID == id
+	})
+}
+
+// appendJSONLines writes items to the end of a JSON Lines file, creating it
+// and its directory if needed
+func appendJSONLines[T any](path string, items []T) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	// Encode everything first so a batch is written with a single call
+	var buf []byte
+	for _, item := range items {
+		line, err := json.Marshal(item)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		buf = append(append(buf, line...), '\n')
+	}
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// readJSONLines returns the items in a JSON Lines file that keep accepts, in
+// file order. A missing file has no items, and lines that cannot be parsed
+// are skipped.
+func readJSONLines[T any](path string, keep func(T) bool) ([]T, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return []T{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	items := []T{}
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			var item T
+			if json.Unmarshal(line, &item) == nil && keep(item) {
+				items = append(items, item)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+// recordHistory appends events to the history log, stamping any without a
+// time. History is best effort: a failed write is logged, not returned, so
+// it never fails a change that has already been applied to the board.
+func (s *TaskStore) recordHistory(events ...models.HistoryEvent) {
+	now := time.Now().UTC()
+	for i := range events {
+		if events[i].Time.IsZero() {
+			events[i].Time = now
+		}
+	}
+	if err := s.history.Append(events...); err != nil {
+		log.Printf("Error writing task history: %v", err)
+	}
+}
+
+// taskChangeEvents describes how a task changed between two copies, with one
+// event per changed field. A column change is reported as a move.
+func taskChangeEvents(before, after *models.Task, author string) []models.HistoryEvent {
+	var events []models.HistoryEvent
+	for _, field := range taskMergeFields {
+		from, to := field.value(before), field.value(after)
+		if from == to {
+			continue
+		}
+		event := models.HistoryEvent{
+			TaskID: after.ID,
+			Type:   models.HistoryUpdated,
+			Author: author,
+			Field:  field.name,
+			From:   from,
+			To:     to,
+		}
+		if field.name == "column" {
+			event.Type = models.HistoryMoved
+			event.Field = ""
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+// testRunEvents records the outcome of a test run, and the move to the last
+// column if passing tests moved the task
+func testRunEvents(task *models.Task, previousColumn models.Column, summary string) []models.HistoryEvent {
+	events := []models.HistoryEvent{{
+		TaskID:  task.ID,
+		Type:    models.HistoryTestRun,
+		To:      string(task.TestStatus),
+		Summary: summary,
+	}}
+	if task.Column != previousColumn {
+		events = append(events, models.HistoryEvent{
+			TaskID:  task.ID,
+			Type:    models.HistoryMoved,
+			From:    string(previousColumn),
+			To:      string(task.Column),
+			Summary: "Moved after tests passed",
+		})
+	}
+	return events
+}
+
+// GetHistory returns the recorded history of a task, oldest first. History
+// is kept for deleted tasks, so it is only an error if nothing was recorded
+// and the task does not exist.
+func (s *TaskStore) GetHistory(id string) ([]models.HistoryEvent, error) {
+	events, err := s.history.ForTask(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read task history: %w", err)
+	}
+	if len(events) == 0 {
+		if _, err := s.Get(id); err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
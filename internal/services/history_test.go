@@ -0,0 +1,100 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"kantext/internal/models"
+)
+
+func TestTaskStore_History(t *testing.T) {
+	store, cleanup := setupTaskStoreEnv(t, checklistTestContent)
+	defer cleanup()
+
+	task, err := store.Create(models.CreateTaskRequest{Title: "Audit me", Author: "Alice"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	title := "Audit me please"
+	high := models.PriorityHigh
+	if _, err := store.Update(task.ID, models.UpdateTaskRequest{Title: &title, Priority: &high, Author: "Bob"}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if _, err := store.Reorder(task.ID, models.ColumnInProgress, 0); err != nil {
+		t.Fatalf("Reorder failed: %v", err)
+	}
+	if _, err := store.UpdateTestResults(task.ID, models.TestResults{AllPassed: true, Results: []models.TestResult{{Passed: true}}}); err != nil {
+		t.Fatalf("UpdateTestResults failed: %v", err)
+	}
+	if err := store.Delete(task.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	events, err := store.GetHistory(task.ID)
+	if err != nil {
+		t.Fatalf("GetHistory failed: %v", err)
+	}
+
+	expected := []models.HistoryEvent{
+		{Type: models.HistoryCreated, Author: "Alice", Summary: "Audit me"},
+		{Type: models.HistoryUpdated, Author: "Bob", Field: "title", From: "Audit me", To: "Audit me please"},
+		{Type: models.HistoryUpdated, Author: "Bob", Field: "priority", From: "medium", To: "high"},
+		{Type: models.HistoryMoved, From: "inbox", To: "in_progress"},
+		{Type: models.HistoryTestRun, To: "passed", Summary: "1/1 tests passed"},
+		{Type: models.HistoryMoved, From: "in_progress", To: "done", Summary: "Moved after tests passed"},
+		{Type: models.HistoryArchived, From: "done", Summary: "Audit me please"},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %d: %+v", len(expected), len(events), events)
+	}
+	for i, want := range expected {
+		got := events[i]
+		if got.TaskID != task.ID || got.Time.IsZero() {
+			t.Errorf("Event %d: expected task ID and time to be set, got %+v", i, got)
+		}
+		got.TaskID, got.Time = "", want.Time
+		if got != want {
+			t.Errorf("Event %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+
+	// Events for other tasks are kept separate
+	other, err := store.GetHistory("task-check01")
+	if err != nil {
+		t.Fatalf("GetHistory failed: %v", err)
+	}
+	if len(other) != 0 {
+		t.Errorf("Expected no history for task-check01, got %+v", other)
+	}
+	if _, err := store.GetHistory("task-nope"); err == nil {
+		t.Error("Expected error for a task with no history that does not exist")
+	}
+
+	if _, err := os.Stat(filepath.Join(store.GetWorkingDir(), ".kantext", "history.jsonl")); err != nil {
+		t.Errorf("Expected history file in .kantext: %v", err)
+	}
+}
+
+func TestTaskStore_History_Checklist(t *testing.T) {
+	store, cleanup := setupTaskStoreEnv(t, checklistTestContent)
+	defer cleanup()
+
+	done := true
+	if _, err := store.UpdateChecklistItem("task-check01", 2, models.UpdateChecklistItemRequest{Done: &done, Author: "Reviewer"}); err != nil {
+		t.Fatalf("UpdateChecklistItem failed: %v", err)
+	}
+
+	events, err := store.GetHistory("task-check01")
+	if err != nil {
+		t.Fatalf("GetHistory failed: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %+v", events)
+	}
+	want := "Checked item 2: Invalid password shows an error"
+	if events[0].Field != "checklist" || events[0].Summary != want || events[0].Author != "Reviewer" {
+		t.Errorf("Expected checklist event %q by Reviewer, got %+v", want, events[0])
+	}
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+
+	"kantext/internal/models"
+)
+
+// boardReader returns the content of a board file, given its path relative
+// to the directory of the main tasks file. A missing file is reported with an
+// error matching fs.ErrNotExist.
+type boardReader func(name string) ([]byte, error)
+
+// includedFile is the content of an included file kept when its tasks are
+// written back: the title line, anything before the first column, the file's
+// own columns with the notes under them, and tasks left off the board
+// because their ID is already taken
+type includedFile struct {
+	header     string
+	preamble   []string
+	columns    []models.ColumnDefinition
+	duplicates []*models.Task
+}
+
+// osBoardReader reads board files from disk, relative to dir
+func osBoardReader(dir string) boardReader {
+	return func(name string) ([]byte, error) {
+		return os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	}
+}
+
+// readBoardFiles reads a board's main tasks file and the files listed in its
+// include setting, and combines them into one board. Included tasks remember
+// the file they came from, and follow the tasks already read in each column.
+// A missing included file is treated as empty. Returns the board and a hash
+// of the content of every file; an error reading the main file is returned
+// as is, so callers can check for fs.ErrNotExist.
+func readBoardFiles(mainName string, read boardReader) (*boardSnapshot, [sha256.Size]byte, error) {
+	data, err := read(mainName)
+	if err != nil {
+		return nil, [sha256.Size]byte{}, err
+	}
+	lines, err := splitLines(data)
+	if err != nil {
+		return nil, [sha256.Size]byte{}, err
+	}
+	board := parseBoard(lines)
+	hashes := [][sha256.Size]byte{sha256.Sum256(data)}
+
+	for _, include := range board.settings.Include {
+		data, err := read(include)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, [sha256.Size]byte{}, fmt.Errorf("failed to read included file %s: %w", include, err)
+		}
+		hashes = append(hashes, sha256.Sum256(data))
+		lines, err := splitLines(data)
+		if err != nil {
+			return nil, [sha256.Size]byte{}, err
+		}
+		addIncludedBoard(board, include, parseBoard(lines))
+	}
+	return board, combineHashes(hashes), nil
+}
+
+// addIncludedBoard adds the tasks of an included file to a board. Columns
+// the board does not have yet are shown after its own, but are not written
+// to the main file. A task whose ID is already on the board is left off it,
+// and only written back to its own file.
+func addIncludedBoard(board *boardSnapshot, source string, included *boardSnapshot) {
+	if board.includes == nil {
+		board.includes = make(map[string]includedFile)
+	}
+	file := includedFile{header: included.header, preamble: included.preamble, columns: sortColumns(included.columns)}
+
+	offset, columnOrder := 0, 0
+	for _, task := range board.tasks {
+		if task.Order >= offset {
+			offset = task.Order + 1
+		}
+	}
+	known := make(map[string]bool)
+	for _, col := range board.columns {
+		known[col.Slug] = true
+		if col.Order >= columnOrder {
+			columnOrder = col.Order + 1
+		}
+	}
+
+	for _, col := range file.columns {
+		if !known[col.Slug] {
+			known[col.Slug] = true
+			board.columns = append(board.columns, models.ColumnDefinition{Slug: col.Slug, Name: col.Name, Order: columnOrder, Source: source})
+			columnOrder++
+		}
+	}
+	for _, task := range included.tasks {
+		task.Source = source
+		if _, exists := board.tasks[task.ID]; exists {
+			log.Printf("Warning: task %s in %s duplicates a task on the board, skipped", task.ID, source)
+			file.duplicates = append(file.duplicates, task)
+			continue
+		}
+		task.Order += offset
+		board.tasks[task.ID] = task
+	}
+	board.includes[source] = file
+}
+
+// combineHashes combines the hashes of a board's files into one. A board
+// without includes has the hash of its main file.
+func combineHashes(hashes [][sha256.Size]byte) [sha256.Size]byte {
+	if len(hashes) == 1 {
+		return hashes[0]
+	}
+	h := sha256.New()
+	for _, hash := range hashes {
+		h.Write(hash[:])
+	}
+	var combined [sha256.Size]byte
+	copy(combined[:], h.Sum(nil))
+	return combined
+}
+
+// saveIncludedFiles writes the tasks from each file included in a board back
+// to it, relative to dir. An included file that does not exist is only
+// created once it has tasks. Returns the hash of each file's content, in
+// include order.
+func saveIncludedFiles(dir string, board *boardSnapshot) ([][sha256.Size]byte, error) {
+	hashes := make([][sha256.Size]byte, 0, len(board.settings.Include))
+	for _, include := range board.settings.Include {
+		path := filepath.Join(dir, filepath.FromSlash(include))
+		if !hasTasksFrom(board, include) {
+			if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+				hashes = append(hashes, sha256.Sum256(nil))
+				continue
+			}
+		}
+		hash, err := writeFileAtomic(path, func(w io.Writer) error {
+			return writeIncludedFile(w, board, include)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to save included file %s: %w", include, err)
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
+
+// hasTasksFrom reports whether any task on a board is stored in the given
+// included file
+func hasTasksFrom(board *boardSnapshot, source string) bool {
+	for _, task := range board.tasks {
+		if task.Source == source {
+			return true
+		}
+	}
+	return false
+}
+
+// writeIncludedFile writes an included file: its header and preamble, its
+// own columns with their notes, and any other columns that now hold tasks
+// from the file. Included files have no front matter, as settings belong to
+// the board's main file.
+func writeIncludedFile(w io.Writer, board *boardSnapshot, source string) error {
+	ew := &errWriter{w: w}
+	file := board.includes[source]
+	if file.header == "" {
+		file.header = defaultBoardHeader
+	}
+	fmt.Fprintln(ew, file.header)
+	fmt.Fprintln(ew, "")
+	if len(file.preamble) > 0 {
+		for _, line := range file.preamble {
+			fmt.Fprintln(ew, line)
+		}
+		fmt.Fprintln(ew, "")
+	}
+
+	// tasksIn returns the tasks from this file in a column, duplicates last
+	tasksIn := func(slug string) []*models.Task {
+		var tasks []*models.Task
+		for _, task := range columnTasks(board.tasks, models.Column(slug)) {
+			if task.Source == source {
+				tasks = append(tasks, task)
+			}
+		}
+		for _, task := range file.duplicates {
+			if string(task.Column) == slug {
+				tasks = append(tasks, task)
+			}
+		}
+		return tasks
+	}
+
+	written := make(map[string]bool)
+	for _, col := range file.columns {
+		written[col.Slug] = true
+		if err := writeColumn(ew, col, tasksIn(col.Slug)); err != nil {
+			return err
+		}
+	}
+	for _, col := range sortColumns(board.columns) {
+		if written[col.Slug] {
+			continue
+		}
+		if tasks := tasksIn(col.Slug); len(tasks) > 0 {
+			col.ExtraLines, col.Notes = nil, nil // Notes of the main file's column stay there
+			if err := writeColumn(ew, col, tasks); err != nil {
+				return err
+			}
+		}
+	}
+
+	if ew.err != nil {
+		return fmt.Errorf("failed to write tasks file: %w", ew.err)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"kantext/internal/models"
+)
+
+const includeMainContent = `---
+include:
+  - ../api/TASKS.md
+---
+# Kantext Tasks
+
+## Inbox
+- [ ] Write docs
+  - id: main-1
+  - priority: low
+  - requires_test: false
+
+## In Progress
+
+## Done
+
+`
+
+const includeAPIContent = `# API Tasks
+
+## Inbox
+
+Column note in include
+
+- [ ] Add endpoint
+  - id: api-1
+  - priority: medium
+  - requires_test: false
+- [ ] Copied task
+  - id: main-1
+  - priority: low
+  - requires_test: false
+
+## Backlog
+
+Nothing here yet
+
+## Review
+- [ ] Check auth
+  - id: api-2
+  - priority: high
+  - requires_test: false
+`
+
+func TestTaskStore_IncludedFiles(t *testing.T) {
+	dir := t.TempDir()
+	mainPath := filepath.Join(dir, "docs", "BOARD.md")
+	apiPath := filepath.Join(dir, "api", "TASKS.md")
+	for path, content := range map[string]string{mainPath: includeMainContent, apiPath: includeAPIContent} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store := NewTaskStoreWithFile(dir, "docs/BOARD.md")
+	store.SetDurableWrites(true)
+	defer store.Close()
+
+	if store.GetTasksFile() != mainPath {
+		t.Errorf("Expected tasks file %s, got %s", mainPath, store.GetTasksFile())
+	}
+	if files := store.Files(); len(files) != 2 || files[0] != mainPath || files[1] != apiPath {
+		t.Errorf("Expected board files [%s %s], got %v", mainPath, apiPath, files)
+	}
+
+	// Included tasks keep their column and remember their file; the Review
+	// column only exists in the included file
+	task, err := store.Get("api-2")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if task.Source != "../api/TASKS.md" || task.Column != "review" {
+		t.Errorf("Unexpected included task: source %q, column %q", task.Source, task.Column)
+	}
+	if all := store.GetAll(); len(all) != 3 {
+		t.Errorf("Expected 3 tasks on the board, got %d", len(all))
+	}
+
+	// A task whose ID is already on the board is left off it
+	if task, _ := store.Get("main-1"); task.Title != "Write docs" || task.Source != "" {
+		t.Errorf("Expected main-1 from the main file, got %q from %q", task.Title, task.Source)
+	}
+
+	// Edits go back to the task's own file, new tasks to the main file
+	title := "Add users endpoint"
+	if _, err := store.Update("api-1", models.UpdateTaskRequest{Title: &title}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	created, err := store.Create(models.CreateTaskRequest{Title: "Release notes", AcceptanceCriteria: "Published"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	mainData, _ := os.ReadFile(mainPath)
+	apiData, _ := os.ReadFile(apiPath)
+	if !strings.Contains(string(apiData), "- [ ] Add users endpoint") || !strings.HasPrefix(string(apiData), "# API Tasks\n") || strings.Contains(string(mainData), "Add users endpoint") {
+		t.Errorf("Expected the edit in the included file only:\nmain:\n%s\napi:\n%s", mainData, apiData)
+	}
+	if !strings.Contains(string(mainData), "id: "+created.ID) || strings.Contains(string(apiData), created.ID) {
+		t.Errorf("Expected the new task in the main file only:\nmain:\n%s\napi:\n%s", mainData, apiData)
+	}
+	if strings.Contains(string(apiData), "include:") || strings.Contains(string(apiData), "Write docs") {
+		t.Errorf("Included file should only hold its own tasks:\n%s", apiData)
+	}
+
+	// The included file keeps its notes, columns without tasks and the
+	// duplicate task; the main file does not get its columns
+	for _, want := range []string{"## Inbox\n\nColumn note in include\n\n- [ ] Add users endpoint", "## Backlog\n\nNothing here yet\n", "- [ ] Copied task\n  - id: main-1", "## Review\n- [ ] Check auth"} {
+		if !strings.Contains(string(apiData), want) {
+			t.Errorf("Expected the included file to keep %q:\n%s", want, apiData)
+		}
+	}
+	if strings.Contains(string(mainData), "## Backlog") || strings.Contains(string(mainData), "## Review") || strings.Contains(string(mainData), "Column note") {
+		t.Errorf("Included columns should not be written to the main file:\n%s", mainData)
+	}
+
+	// External edits to the included file are merged
+	editOnDisk(t, apiPath, "priority: high", "priority: low")
+	if _, err := store.ReloadFromDisk(); err != nil {
+		t.Fatalf("ReloadFromDisk failed: %v", err)
+	}
+	task, _ = store.Get("api-2")
+	if task.Priority != models.PriorityLow {
+		t.Errorf("Expected the external edit to be merged, got priority %s", task.Priority)
+	}
+	if task, _ := store.Get("api-1"); task.Title != title {
+		t.Errorf("Expected title %q to survive the merge, got %q", title, task.Title)
+	}
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"kantext/internal/models"
+)
+
+// jsonBoard is the layout of a JSON tasks file. Tasks are listed column by
+// column, in board order.
+type jsonBoard struct {
+	Settings Settings                  `json:"settings"`
+	Columns  []models.ColumnDefinition `json:"columns"`
+	Tasks    []*models.Task            `json:"tasks"`
+}
+
+// jsonStorage stores a board in a single JSON file. It has no included files,
+// header or free-form notes, but is quicker to read and write than markdown
+// for large boards.
+type jsonStorage struct {
+	path string
+}
+
+func (j *jsonStorage) Load() (*boardSnapshot, [sha256.Size]byte, error) {
+	data, err := os.ReadFile(j.path)
+	if err != nil {
+		return nil, [sha256.Size]byte{}, err
+	}
+	board, err := parseJSONBoard(data)
+	return board, sha256.Sum256(data), err
+}
+
+func (j *jsonStorage) Save(board *boardSnapshot) ([sha256.Size]byte, error) {
+	return writeFileAtomic(j.path, func(w io.Writer) error {
+		return writeJSONBoard(w, board)
+	})
+}
+
+func (j *jsonStorage) Files() []string {
+	return []string{j.path}
+}
+
+func (j *jsonStorage) readFrom(read boardReader) (*boardSnapshot, error) {
+	data, err := read(filepath.Base(j.path))
+	if err != nil {
+		return nil, err
+	}
+	return parseJSONBoard(data)
+}
+
+// parseJSONBoard parses the content of a JSON tasks file. An empty file is
+// an empty board.
+func parseJSONBoard(data []byte) (*boardSnapshot, error) {
+	board := &boardSnapshot{
+		tasks:       make(map[string]*models.Task),
+		columns:     []models.ColumnDefinition{},
+		lineNumbers: make(map[string]int),
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return board, nil
+	}
+
+	var stored jsonBoard
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to parse tasks file: %w", err)
+	}
+	board.settings = stored.Settings
+	if stored.Columns != nil {
+		board.columns = stored.Columns
+	}
+	for i, task := range stored.Tasks {
+		if task == nil {
+			continue
+		}
+		if task.ID == "" {
+			task.ID = generateShortID()
+		}
+		task.Order = i
+		board.tasks[task.ID] = task
+	}
+	return board, nil
+}
+
+// writeJSONBoard writes a board as indented JSON to w
+func writeJSONBoard(w io.Writer, board *boardSnapshot) error {
+	stored := jsonBoard{
+		Settings: settingsWithDefaults(board.settings),
+		Columns:  sortColumns(board.columns),
+		Tasks:    []*models.Task{},
+	}
+	for _, col := range stored.Columns {
+		for _, task := range columnTasks(board.tasks, models.Column(col.Slug)) {
+			stored.Tasks = append(stored.Tasks, storedTask(task))
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(stored); err != nil {
+		return fmt.Errorf("failed to write tasks file: %w", err)
+	}
+	return nil
+}
+
+// storedTask returns a copy of a task without the fields derived from other
+// tasks or from git, which are filled in again when the board is loaded
+func storedTask(task *models.Task) *models.Task {
+	stored := copyTask(task)
+	stored.Blocks = nil
+	stored.Blocked = false
+	stored.Children = nil
+	stored.SubtasksDone = 0
+	stored.Commits = nil
+	stored.Branches = nil
+	return stored
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"kantext/internal/models"
+)
+
+func TestTaskStore_JSONStorage(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tasks.json")
+
+	// A new JSON board is created with the default columns and settings
+	store := NewTaskStoreWithFile(dir, "tasks.json")
+	store.SetDurableWrites(true)
+	parent, err := store.Create(models.CreateTaskRequest{Title: "Ship v2", AcceptanceCriteria: "Released"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	child, err := store.Create(models.CreateTaskRequest{Title: "Write changelog", AcceptanceCriteria: "Merged", Parent: parent.ID})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	var stored jsonBoard
+	if err := json.Unmarshal(data, &stored); err != nil {
+		t.Fatalf("Expected a JSON tasks file, got %v:\n%s", err, data)
+	}
+	if len(stored.Columns) != len(models.DefaultColumns) || stored.Settings.TestRunner.Command != DefaultTestCommand || len(stored.Tasks) != 2 {
+		t.Errorf("Unexpected stored board: %+v", stored)
+	}
+	if len(stored.Tasks) > 0 && stored.Tasks[0].Children != nil {
+		t.Errorf("Derived fields should not be stored:\n%s", data)
+	}
+
+	// Reopening restores the tasks in file order, with derived fields filled in
+	store = NewTaskStoreWithFile(dir, path)
+	store.SetDurableWrites(true)
+	defer store.Close()
+	tasks := store.GetAll()
+	if len(tasks) != 2 || tasks[0].ID != stored.Tasks[0].ID || tasks[1].ID != stored.Tasks[1].ID {
+		t.Fatalf("Expected tasks in file order, got %+v", tasks)
+	}
+	if reloaded, _ := store.Get(parent.ID); len(reloaded.Children) != 1 || reloaded.Children[0] != child.ID {
+		t.Errorf("Expected children to be derived on load, got %v", reloaded.Children)
+	}
+	if files := store.Files(); len(files) != 1 || files[0] != path {
+		t.Errorf("Expected board files [%s], got %v", path, files)
+	}
+
+	// Edits made to the file outside Kantext are merged
+	editOnDisk(t, path, `"title": "Write changelog"`, `"title": "Write the changelog"`)
+	if _, err := store.ReloadFromDisk(); err != nil {
+		t.Fatalf("ReloadFromDisk failed: %v", err)
+	}
+	if reloaded, _ := store.Get(child.ID); reloaded.Title != "Write the changelog" {
+		t.Errorf("Expected the external edit to be merged, got %q", reloaded.Title)
+	}
+}
@@ -0,0 +1,275 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"kantext/internal/models"
+)
+
+// ErrInvalidQuery is returned when a search query cannot be parsed
+var ErrInvalidQuery = errors.New("invalid query")
+
+// TaskQuery is a parsed search query. Every filter must match; within a
+// filter, any of the comma-separated values may match.
+type TaskQuery struct {
+	Text       []string // Free-text terms, each matched case-insensitively
+	Tags       []string
+	Priorities []string
+	Columns    []string
+	Statuses   []string
+	Parents    []string
+	Authors    []string
+	Stale      *bool
+	Blocked    *bool
+}
+
+// SearchResult is one page of tasks matching a query
+type SearchResult struct {
+	Tasks  []*models.Task `json:"tasks"`
+	Total  int            `json:"total"`  // Number of matching tasks across all pages
+	Offset int            `json:"offset"` // Index of the first task in Tasks
+	Limit  int            `json:"limit"`  // Page size, 0 for no limit
+}
+
+// ParseTaskQuery parses a query such as
+//
+//	tag:backend priority:high column:inbox status:failed stale:true "login"
+//
+// Terms without a key are matched against task text; double quotes group
+// words into a single term. Unknown keys are rejected so typos are not
+// silently treated as text.
+func ParseTaskQuery(query string) (TaskQuery, error) {
+	var q TaskQuery
+	for _, term := range tokenizeQuery(query) {
+		key, value, ok := strings.Cut(term, ":")
+		if !ok || strings.HasPrefix(term, `"`) {
+			if text := strings.ToLower(strings.Trim(term, `"`)); text != "" {
+				q.Text = append(q.Text, text)
+			}
+			continue
+		}
+
+		if err := q.AddFilter(key, value); err != nil {
+			return q, err
+		}
+	}
+	return q, nil
+}
+
+// AddFilter adds a key:value filter to the query, as if it were part of the
+// query string. The value is taken as is, so it needs no quoting; commas
+// still separate alternatives.
+func (q *TaskQuery) AddFilter(key, value string) error {
+	values := parseQueryValues(value)
+	if len(values) == 0 {
+		return fmt.Errorf("%w: %s has no value", ErrInvalidQuery, key)
+	}
+
+	switch strings.ToLower(key) {
+	case "tag", "tags":
+		q.Tags = append(q.Tags, values...)
+	case "priority":
+		q.Priorities = append(q.Priorities, values...)
+	case "column":
+		q.Columns = append(q.Columns, values...)
+	case "status":
+		q.Statuses = append(q.Statuses, values...)
+	case "parent":
+		q.Parents = append(q.Parents, values...)
+	case "author":
+		q.Authors = append(q.Authors, values...)
+	case "stale", "blocked":
+		b, err := strconv.ParseBool(values[0])
+		if err != nil || len(values) > 1 {
+			return fmt.Errorf("%w: %s must be true or false", ErrInvalidQuery, key)
+		}
+		if strings.ToLower(key) == "stale" {
+			q.Stale = &b
+		} else {
+			q.Blocked = &b
+		}
+	default:
+		return fmt.Errorf("%w: unknown filter %q", ErrInvalidQuery, key)
+	}
+	return nil
+}
+
+// tokenizeQuery splits a query on whitespace, keeping double-quoted
+// phrases (including key:"quoted value") together
+func tokenizeQuery(query string) []string {
+	var terms []string
+	var current strings.Builder
+	inQuotes := false
+	for _, r := range query {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			current.WriteRune(r)
+		case !inQuotes && (r == ' ' || r == '\t' || r == '\n'):
+			if current.Len() > 0 {
+				terms = append(terms, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		terms = append(terms, current.String())
+	}
+	return terms
+}
+
+// parseQueryValues splits a filter value on commas and lowercases it
+func parseQueryValues(value string) []string {
+	var values []string
+	for _, v := range strings.Split(strings.Trim(value, `"`), ",") {
+		if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// Search returns the tasks matching query in board order. offset skips that
+// many matches and limit caps the page size (0 means no limit).
+func (s *TaskStore) Search(query string, offset, limit int) (*SearchResult, error) {
+	q, err := ParseTaskQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	return s.SearchTasks(q, offset, limit)
+}
+
+// SearchTasks is Search with an already parsed query
+func (s *TaskStore) SearchTasks(q TaskQuery, offset, limit int) (*SearchResult, error) {
+	if offset < 0 || limit < 0 {
+		return nil, fmt.Errorf("%w: offset and limit must not be negative", ErrInvalidQuery)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matches []*models.Task
+	for _, task := range s.tasks {
+		if s.taskMatchesLocked(task, q) {
+			matches = append(matches, task)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Order < matches[j].Order
+	})
+
+	result := &SearchResult{Total: len(matches), Offset: offset, Limit: limit}
+	if offset > len(matches) {
+		offset = len(matches)
+	}
+	end := len(matches)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	result.Tasks = matches[offset:end]
+	if result.Tasks == nil {
+		result.Tasks = []*models.Task{}
+	}
+	return result, nil
+}
+
+// taskMatchesLocked reports whether a task satisfies every filter in q.
+// Must be called with at least a read lock held.
+func (s *TaskStore) taskMatchesLocked(task *models.Task, q TaskQuery) bool {
+	if len(q.Tags) > 0 && !matchesAny(q.Tags, task.Tags...) {
+		return false
+	}
+	if len(q.Priorities) > 0 && !matchesAny(q.Priorities, string(task.Priority)) {
+		return false
+	}
+	if len(q.Columns) > 0 && !matchesAny(q.Columns, string(task.Column), s.columnNameLocked(task.Column)) {
+		return false
+	}
+	if len(q.Statuses) > 0 && !matchesAny(q.Statuses, string(task.TestStatus)) {
+		return false
+	}
+	if len(q.Parents) > 0 && !matchesAny(q.Parents, task.Parent) {
+		return false
+	}
+	if len(q.Authors) > 0 && !authorMatches(q.Authors, task.CreatedBy, task.UpdatedBy) {
+		return false
+	}
+	if q.Stale != nil && s.isStaleLocked(task) != *q.Stale {
+		return false
+	}
+	if q.Blocked != nil && task.Blocked != *q.Blocked {
+		return false
+	}
+
+	if len(q.Text) > 0 {
+		text := strings.ToLower(searchableText(task))
+		for _, term := range q.Text {
+			if !strings.Contains(text, term) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matchesAny reports whether any of the fields equals one of the (lowercase) wanted values
+func matchesAny(wanted []string, fields ...string) bool {
+	for _, field := range fields {
+		if containsString(wanted, strings.ToLower(field)) {
+			return true
+		}
+	}
+	return false
+}
+
+// authorMatches reports whether any wanted value appears in one of the author names
+func authorMatches(wanted []string, authors ...string) bool {
+	for _, author := range authors {
+		author = strings.ToLower(author)
+		for _, w := range wanted {
+			if author != "" && strings.Contains(author, w) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// searchableText joins the task fields that free-text terms are matched against
+func searchableText(task *models.Task) string {
+	parts := []string{task.ID, task.Title, task.AcceptanceCriteria, task.Description}
+	parts = append(parts, task.Tags...)
+	for _, item := range task.Checklist {
+		parts = append(parts, item.Text)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// columnNameLocked returns the display name of a column, or "" if unknown.
+// Must be called with at least a read lock held.
+func (s *TaskStore) columnNameLocked(column models.Column) string {
+	for _, col := range s.columns {
+		if col.Slug == string(column) {
+			return col.Name
+		}
+	}
+	return ""
+}
+
+// isStaleLocked reports whether a task has not been updated within the stale
+// threshold. Finished tasks are never stale.
+// Must be called with at least a read lock held.
+func (s *TaskStore) isStaleLocked(task *models.Task) bool {
+	if task.UpdatedAt.IsZero() || s.isDoneColumnLocked(task.Column) {
+		return false
+	}
+	threshold := time.Duration(s.settings.GetStaleThresholdDays()) * 24 * time.Hour
+	return time.Since(task.UpdatedAt) > threshold
+}
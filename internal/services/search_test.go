@@ -0,0 +1,173 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const searchTestContent = `---
+stale_threshold_days: 7
+---
+# Kantext Tasks
+
+## Inbox
+
+- [-] Fix login redirect
+  - id: task-login
+  - priority: high
+  - tags: backend, auth
+  - requires_test: false
+  - updated_at: 2020-01-01T00:00:00Z
+
+- [ ] Login page styling
+  - id: task-style
+  - priority: low
+  - tags: frontend
+  - requires_test: false
+
+## In Progress
+
+- [ ] Session storage
+  - id: task-session
+  - priority: high
+  - tags: backend
+  - parent: task-login
+  - requires_test: false
+  - criteria: Sessions survive a restart
+
+## Done
+
+- [x] Old backend cleanup
+  - id: task-cleanup
+  - priority: medium
+  - tags: backend
+  - requires_test: false
+  - updated_at: 2020-01-01T00:00:00Z
+`
+
+func searchIDs(t *testing.T, store *TaskStore, query string) string {
+	t.Helper()
+	result, err := store.Search(query, 0, 0)
+	if err != nil {
+		t.Fatalf("Search(%q) failed: %v", query, err)
+	}
+	ids := make([]string, len(result.Tasks))
+	for i, task := range result.Tasks {
+		ids[i] = task.ID
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestTaskStore_Search(t *testing.T) {
+	store, cleanup := setupTaskStoreEnv(t, searchTestContent)
+	defer cleanup()
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"", "task-login,task-style,task-session,task-cleanup"},
+		{"tag:backend", "task-login,task-session,task-cleanup"},
+		{"tag:backend priority:high", "task-login,task-session"},
+		{"priority:low,medium", "task-style,task-cleanup"},
+		{"column:inbox", "task-login,task-style"},
+		{`column:"In Progress"`, "task-session"},
+		{"status:failed", "task-login"},
+		{"status:passed", "task-cleanup"},
+		{"stale:true", "task-login"},
+		{"parent:task-login", "task-session"},
+		{"login", "task-login,task-style"},
+		{`"login redirect"`, "task-login"},
+		{"LOGIN tag:frontend", "task-style"},
+		{"restart", "task-session"},
+		{"tag:backend priority:high column:inbox status:failed stale:true \"login\"", "task-login"},
+		{"tag:nothing", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := searchIDs(t, store, tt.query); got != tt.want {
+				t.Errorf("Search(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTaskStore_Search_Pagination(t *testing.T) {
+	store, cleanup := setupTaskStoreEnv(t, searchTestContent)
+	defer cleanup()
+
+	result, err := store.Search("tag:backend", 1, 1)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if result.Total != 3 || len(result.Tasks) != 1 || result.Tasks[0].ID != "task-session" {
+		t.Errorf("Expected page 2 of 3 to be task-session, got total=%d tasks=%d", result.Total, len(result.Tasks))
+	}
+
+	result, err = store.Search("tag:backend", 10, 5)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if result.Total != 3 || len(result.Tasks) != 0 {
+		t.Errorf("Expected an empty page past the end, got total=%d tasks=%d", result.Total, len(result.Tasks))
+	}
+}
+
+func TestTaskStore_Search_InvalidQuery(t *testing.T) {
+	store, cleanup := setupTaskStoreEnv(t, searchTestContent)
+	defer cleanup()
+
+	for _, query := range []string{"colour:red", "stale:maybe", "tag:", "blocked:true,false"} {
+		if _, err := store.Search(query, 0, 0); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("Search(%q): expected ErrInvalidQuery, got %v", query, err)
+		}
+	}
+	if _, err := store.Search("", -1, 0); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("Expected ErrInvalidQuery for negative offset, got %v", err)
+	}
+}
+
+func TestTaskStore_SearchTasks_Filters(t *testing.T) {
+	store, cleanup := setupTaskStoreEnv(t, `# Kantext Tasks
+
+## Inbox
+
+- [ ] Rotate keys
+  - id: task-keys
+  - priority: high
+  - tags: ops\infra
+  - requires_test: false
+  - created_by: Jane "JD" Doe
+
+- [ ] Write docs
+  - id: task-docs
+  - priority: low
+  - requires_test: false
+`)
+	defer cleanup()
+
+	// Filter values are taken as they are, quotes and backslashes included,
+	// alongside a parsed query
+	q, err := ParseTaskQuery("rotate")
+	if err != nil {
+		t.Fatalf("ParseTaskQuery failed: %v", err)
+	}
+	if err := q.AddFilter("author", `Jane "JD"`); err != nil {
+		t.Fatalf("AddFilter failed: %v", err)
+	}
+	if err := q.AddFilter("tag", `ops\infra,other`); err != nil {
+		t.Fatalf("AddFilter failed: %v", err)
+	}
+	result, err := store.SearchTasks(q, 0, 0)
+	if err != nil {
+		t.Fatalf("SearchTasks failed: %v", err)
+	}
+	if len(result.Tasks) != 1 || result.Tasks[0].ID != "task-keys" {
+		t.Errorf("Expected only task-keys to match, got %d tasks", len(result.Tasks))
+	}
+
+	if err := q.AddFilter("colour", "red"); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("Expected ErrInvalidQuery for an unknown filter, got %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"crypto/sha256"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Storage reads and writes a board. TaskStore keeps the board in memory and
+// persists it through a Storage, which decides the format on disk.
+// Implementations must be safe for concurrent use.
+type Storage interface {
+	// Load reads the stored board. Returns an error matching fs.ErrNotExist
+	// when nothing has been stored yet. The hash identifies the stored
+	// content, so edits made outside Kantext can be detected and merged.
+	Load() (*boardSnapshot, [sha256.Size]byte, error)
+
+	// Save replaces the stored board and returns the hash of the new content.
+	// The board is only read, and must not be kept after Save returns.
+	Save(board *boardSnapshot) ([sha256.Size]byte, error)
+
+	// Files returns the files holding the board. They are watched for edits
+	// made outside Kantext, and committed when git.auto_commit is on.
+	Files() []string
+}
+
+// fileStorage is a Storage whose files can also be read from somewhere else,
+// such as another worktree or a git commit
+type fileStorage interface {
+	Storage
+
+	// readFrom reads a board with read, which is given paths relative to the
+	// directory of the main file
+	readFrom(read boardReader) (*boardSnapshot, error)
+}
+
+// newStorage returns the storage for a tasks file, chosen by its extension:
+// a ".json" file holds the board as JSON, a ".db" file is a bbolt database,
+// and anything else is markdown
+func newStorage(path string) Storage {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return &jsonStorage{path: path}
+	case ".db":
+		return &boltStorage{path: path}
+	}
+	return &markdownStorage{path: path}
+}
+
+// markdownStorage stores a board in a markdown file with its settings in YAML
+// front matter. Tasks from included files are written back to those files.
+type markdownStorage struct {
+	path    string
+	mu      sync.Mutex // Protects include
+	include []string   // Included files as last loaded or saved
+}
+
+func (m *markdownStorage) Load() (*boardSnapshot, [sha256.Size]byte, error) {
+	board, hash, err := readBoardFiles(filepath.Base(m.path), osBoardReader(filepath.Dir(m.path)))
+	if err == nil {
+		m.setInclude(board.settings.Include)
+	}
+	return board, hash, err
+}
+
+func (m *markdownStorage) Save(board *boardSnapshot) ([sha256.Size]byte, error) {
+	hash, err := writeFileAtomic(m.path, func(w io.Writer) error {
+		return writeMarkdownBoard(w, board)
+	})
+	if err != nil {
+		return hash, err
+	}
+	includeHashes, err := saveIncludedFiles(filepath.Dir(m.path), board)
+	if err != nil {
+		return hash, err
+	}
+	m.setInclude(board.settings.Include)
+	return combineHashes(append([][sha256.Size]byte{hash}, includeHashes...)), nil
+}
+
+func (m *markdownStorage) Files() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	files := []string{m.path}
+	for _, include := range m.include {
+		files = append(files, filepath.Join(filepath.Dir(m.path), filepath.FromSlash(include)))
+	}
+	return files
+}
+
+func (m *markdownStorage) readFrom(read boardReader) (*boardSnapshot, error) {
+	board, _, err := readBoardFiles(filepath.Base(m.path), read)
+	return board, err
+}
+
+func (m *markdownStorage) setInclude(include []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.include = append([]string(nil), include...)
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"kantext/internal/models"
+)
+
+// ErrInvalidParent is returned when a task's parent does not exist, is the
+// task itself, or is one of the task's own subtasks.
+var ErrInvalidParent = errors.New("invalid parent")
+
+// validateParentLocked checks that parent can become the parent of task id.
+// id may be empty for a task that does not exist yet.
+// Must be called with at least a read lock held.
+func (s *TaskStore) validateParentLocked(id, parent string) error {
+	if parent == "" {
+		return nil
+	}
+	if parent == id {
+		return fmt.Errorf("%w: task %s cannot be its own parent", ErrInvalidParent, id)
+	}
+	if _, ok := s.tasks[parent]; !ok {
+		return fmt.Errorf("%w: parent task not found: %s", ErrInvalidParent, parent)
+	}
+	if id == "" {
+		return nil
+	}
+
+	// Walk up from the new parent; reaching id means it is one of id's subtasks
+	visited := make(map[string]bool)
+	for ancestor := parent; ancestor != "" && !visited[ancestor]; {
+		if ancestor == id {
+			return fmt.Errorf("%w: %s is a subtask of %s", ErrInvalidParent, parent, id)
+		}
+		visited[ancestor] = true
+		task, ok := s.tasks[ancestor]
+		if !ok {
+			break
+		}
+		ancestor = task.Parent
+	}
+	return nil
+}
+
+// linkSubtasksLocked recomputes each task's derived Children and SubtasksDone
+// fields and rolls test status up from subtasks into parents that have no
+// tests of their own. Returns true if a rolled-up status changed, since that
+// is written to TASKS.md. Caller must hold the write lock.
+func (s *TaskStore) linkSubtasksLocked() bool {
+	wasParent := make(map[string]bool)
+	for _, task := range s.tasks {
+		if len(task.Children) > 0 {
+			wasParent[task.ID] = true
+		}
+		task.Children = nil
+		task.SubtasksDone = 0
+	}
+	for _, task := range s.tasks {
+		if parent, ok := s.tasks[task.Parent]; ok && task.Parent != task.ID {
+			parent.Children = append(parent.Children, task.ID)
+			if s.isDoneColumnLocked(task.Column) {
+				parent.SubtasksDone++
+			}
+		}
+	}
+
+	changed := false
+	rolled := make(map[string]bool)
+	var rollup func(task *models.Task)
+	rollup = func(task *models.Task) {
+		if rolled[task.ID] {
+			return
+		}
+		// Mark before recursing so a parent cycle edited into the file terminates
+		rolled[task.ID] = true
+
+		sort.Slice(task.Children, func(i, j int) bool {
+			return s.tasks[task.Children[i]].Order < s.tasks[task.Children[j]].Order
+		})
+		for _, childID := range task.Children {
+			rollup(s.tasks[childID])
+		}
+		// A task that has just lost its last subtask still shows their results
+		if task.HasTest() || (len(task.Children) == 0 && !wasParent[task.ID]) {
+			return
+		}
+		if rollupTestStatus(task, s.childTasksLocked(task)) {
+			changed = true
+		}
+	}
+	for _, task := range s.tasks {
+		rollup(task)
+	}
+	return changed
+}
+
+// rollupTestStatus sets a parent's test status and counts from the subtasks
+// that have tests: running if any is running, failed if any failed, passed
+// once all have passed. Once no subtask has tests, a rolled-up status is
+// reset to pending. Returns true if anything changed.
+func rollupTestStatus(parent *models.Task, children []*models.Task) bool {
+	status := models.TestStatusPending
+	passed, total := 0, 0
+	withTests, allPassed, anyFailed, anyRunning := 0, true, false, false
+	for _, child := range children {
+		if !child.HasTest() && child.TestsTotal == 0 {
+			continue
+		}
+		withTests++
+		passed += child.TestsPassed
+		total += child.TestsTotal
+		switch child.TestStatus {
+		case models.TestStatusRunning:
+			anyRunning = true
+		case models.TestStatusFailed:
+			anyFailed = true
+		}
+		if child.TestStatus != models.TestStatusPassed {
+			allPassed = false
+		}
+	}
+	if withTests == 0 {
+		// Without tests of its own, counts or a running status came from subtasks
+		if parent.TestsTotal == 0 && parent.TestStatus != models.TestStatusRunning {
+			return false
+		}
+		parent.TestStatus = models.TestStatusPending
+		parent.TestsPassed, parent.TestsTotal = 0, 0
+		return true
+	}
+	switch {
+	case anyRunning:
+		status = models.TestStatusRunning
+	case anyFailed:
+		status = models.TestStatusFailed
+	case allPassed:
+		status = models.TestStatusPassed
+	}
+
+	if parent.TestStatus == status && parent.TestsPassed == passed && parent.TestsTotal == total {
+		return false
+	}
+	parent.TestStatus = status
+	parent.TestsPassed = passed
+	parent.TestsTotal = total
+	return true
+}
+
+// childTasksLocked returns a task's subtasks in board order.
+// Must be called with at least a read lock held, after linkSubtasksLocked.
+func (s *TaskStore) childTasksLocked(task *models.Task) []*models.Task {
+	children := make([]*models.Task, 0, len(task.Children))
+	for _, id := range task.Children {
+		if child, ok := s.tasks[id]; ok {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// reparentChildrenLocked moves the subtasks of a deleted task up to its parent.
+// Caller must hold the write lock.
+func (s *TaskStore) reparentChildrenLocked(deleted *models.Task) {
+	for _, task := range s.tasks {
+		if task.Parent == deleted.ID {
+			task.Parent = deleted.Parent
+		}
+	}
+}
+
+// refreshDerivedLocked recomputes every field derived from relationships
+// between tasks (dependencies and subtasks). Returns true if a derived value
+// that is persisted to TASKS.md changed. Caller must hold the write lock.
+func (s *TaskStore) refreshDerivedLocked() bool {
+	s.linkDependenciesLocked()
+	return s.linkSubtasksLocked()
+}
+
+// GetChildren returns the direct subtasks of a task in board order
+func (s *TaskStore) GetChildren(id string) ([]*models.Task, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	task, ok := s.tasks[id]
+	if !ok {
+		return nil, fmt.Errorf("task not found: %s", id)
+	}
+	return s.childTasksLocked(task), nil
+}
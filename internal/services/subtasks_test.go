@@ -0,0 +1,187 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"kantext/internal/models"
+)
+
+const subtaskTestContent = `---
+stale_threshold_days: 7
+---
+# Kantext Tasks
+
+## Inbox
+
+- [ ] Checkout
+  - id: task-parent
+  - priority: high
+  - requires_test: false
+
+- [x] Payment form
+  - id: task-child1
+  - priority: medium
+  - parent: task-parent
+  - requires_test: true
+  - test: payment_test.go:TestPaymentForm
+  - tests_passed: 1
+  - tests_total: 1
+
+- [ ] Card validation
+  - id: task-grandchild
+  - priority: low
+  - parent: task-child1
+  - requires_test: false
+
+## In Progress
+
+## Done
+
+- [x] Cart summary
+  - id: task-child2
+  - priority: medium
+  - parent: task-parent
+  - requires_test: true
+  - test: cart_test.go:TestCartSummary
+  - tests_passed: 2
+  - tests_total: 2
+`
+
+func TestTaskStore_Subtasks_ParsingAndRollup(t *testing.T) {
+	store, cleanup := setupTaskStoreEnv(t, subtaskTestContent)
+	defer cleanup()
+
+	parent, _ := store.Get("task-parent")
+	if strings.Join(parent.Children, ",") != "task-child1,task-child2" {
+		t.Errorf("Expected children in board order, got %v", parent.Children)
+	}
+	if parent.SubtasksDone != 1 {
+		t.Errorf("Expected 1 subtask done, got %d", parent.SubtasksDone)
+	}
+	if parent.TestStatus != models.TestStatusPassed || parent.TestsPassed != 3 || parent.TestsTotal != 3 {
+		t.Errorf("Expected rolled-up status passed 3/3, got %s %d/%d", parent.TestStatus, parent.TestsPassed, parent.TestsTotal)
+	}
+
+	// A subtask with its own tests keeps its own status
+	child, _ := store.Get("task-child1")
+	if strings.Join(child.Children, ",") != "task-grandchild" || child.TestsTotal != 1 {
+		t.Errorf("Expected task-child1 to keep its own tests, got children=%v %d/%d", child.Children, child.TestsPassed, child.TestsTotal)
+	}
+
+	children, err := store.GetChildren("task-parent")
+	if err != nil {
+		t.Fatalf("GetChildren failed: %v", err)
+	}
+	if len(children) != 2 || children[0].ID != "task-child1" {
+		t.Errorf("Expected 2 children starting with task-child1, got %d", len(children))
+	}
+	if _, err := store.GetChildren("task-nope"); err == nil {
+		t.Error("Expected error for missing task")
+	}
+
+	// A failing subtask fails the parent, and the rolled-up status is saved
+	store.mu.Lock()
+	store.tasks["task-child2"].TestStatus = models.TestStatusFailed
+	store.refreshDerivedLocked()
+	err = store.saveToFile()
+	store.mu.Unlock()
+	if err != nil {
+		t.Fatalf("saveToFile failed: %v", err)
+	}
+	parent, _ = store.Get("task-parent")
+	if parent.TestStatus != models.TestStatusFailed {
+		t.Errorf("Expected rolled-up status failed, got %s", parent.TestStatus)
+	}
+
+	data, err := os.ReadFile(filepath.Join(store.GetWorkingDir(), "TASKS.md"))
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if !strings.Contains(string(data), "  - id: task-child1\n  - priority: medium\n  - parent: task-parent\n") {
+		t.Errorf("Expected parent line after priority, got:\n%s", data)
+	}
+	if !strings.Contains(string(data), "- [-] Checkout\n  - id: task-parent\n") {
+		t.Errorf("Expected rolled-up status to be saved for task-parent, got:\n%s", data)
+	}
+
+	// Once no subtask has tests, the parent no longer shows their results
+	for _, id := range []string{"task-child1", "task-child2"} {
+		if err := store.Delete(id); err != nil {
+			t.Fatalf("Delete failed: %v", err)
+		}
+	}
+	parent, _ = store.Get("task-parent")
+	if parent.TestStatus != models.TestStatusPending || parent.TestsPassed != 0 || parent.TestsTotal != 0 {
+		t.Errorf("Expected the rolled-up status to be reset, got %s %d/%d", parent.TestStatus, parent.TestsPassed, parent.TestsTotal)
+	}
+}
+
+func TestTaskStore_Subtasks_Validation(t *testing.T) {
+	store, cleanup := setupTaskStoreEnv(t, subtaskTestContent)
+	defer cleanup()
+
+	tests := []struct {
+		name   string
+		id     string
+		parent string
+	}{
+		{"missing task", "task-parent", "task-nope"},
+		{"self", "task-parent", "task-parent"},
+		{"direct cycle", "task-parent", "task-child1"},
+		{"transitive cycle", "task-parent", "task-grandchild"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent := tt.parent
+			_, err := store.Update(tt.id, models.UpdateTaskRequest{Parent: &parent})
+			if !errors.Is(err, ErrInvalidParent) {
+				t.Errorf("Expected ErrInvalidParent, got %v", err)
+			}
+		})
+	}
+
+	if _, err := store.Create(models.CreateTaskRequest{Title: "Bad", Parent: "task-nope"}); !errors.Is(err, ErrInvalidParent) {
+		t.Errorf("Expected ErrInvalidParent on create, got %v", err)
+	}
+
+	task, err := store.Create(models.CreateTaskRequest{Title: "Shipping options", Parent: "task-parent"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	parent, _ := store.Get("task-parent")
+	if !containsString(parent.Children, task.ID) {
+		t.Errorf("Expected new subtask in children, got %v", parent.Children)
+	}
+
+	// Clearing the parent detaches the subtask
+	empty := ""
+	if _, err := store.Update(task.ID, models.UpdateTaskRequest{Parent: &empty}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	parent, _ = store.Get("task-parent")
+	if containsString(parent.Children, task.ID) {
+		t.Errorf("Expected detached subtask to be removed from children, got %v", parent.Children)
+	}
+}
+
+func TestTaskStore_Subtasks_DeleteReparentsChildren(t *testing.T) {
+	store, cleanup := setupTaskStoreEnv(t, subtaskTestContent)
+	defer cleanup()
+
+	if err := store.Delete("task-child1"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	grandchild, _ := store.Get("task-grandchild")
+	if grandchild.Parent != "task-parent" {
+		t.Errorf("Expected subtask to move up to task-parent, got %q", grandchild.Parent)
+	}
+	parent, _ := store.Get("task-parent")
+	if strings.Join(parent.Children, ",") != "task-grandchild,task-child2" {
+		t.Errorf("Expected children task-grandchild,task-child2, got %v", parent.Children)
+	}
+}
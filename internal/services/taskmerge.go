@@ -0,0 +1,573 @@
+package services
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"kantext/internal/models"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Merge resolutions reported in models.MergeConflict
+const (
+	resolutionKeptOurs   = "kept_ours"
+	resolutionKeptTheirs = "kept_theirs"
+	resolutionMerged     = "merged"
+	resolutionUnresolved = "unresolved"
+)
+
+// SetOnMergeConflicts sets a callback invoked when external edits to TASKS.md
+// conflict with in-memory edits. The callback runs in its own goroutine.
+func (s *TaskStore) SetOnMergeConflicts(callback func([]models.MergeConflict)) {
+	s.baseMu.Lock()
+	defer s.baseMu.Unlock()
+	s.onMergeConflicts = callback
+}
+
+// notifyMergeConflicts hands conflicts to the registered callback, if any
+func (s *TaskStore) notifyMergeConflicts(conflicts []models.MergeConflict) {
+	if len(conflicts) == 0 {
+		return
+	}
+	for _, c := range conflicts {
+		log.Printf("Merge conflict on %s %s: kept %s", c.TaskID, c.Field, strings.TrimPrefix(c.Resolution, "kept_"))
+	}
+
+	s.baseMu.Lock()
+	callback := s.onMergeConflicts
+	s.baseMu.Unlock()
+	if callback != nil {
+		go callback(conflicts)
+	}
+}
+
+// copyTask returns a deep copy of a task
+func copyTask(task *models.Task) *models.Task {
+	c := *task
+	if task.Tags != nil {
+		c.Tags = append([]string(nil), task.Tags...)
+	}
+	if task.Tests != nil {
+		c.Tests = append([]models.TestSpec(nil), task.Tests...)
+	}
+	if task.BlockedBy != nil {
+		c.BlockedBy = append([]string(nil), task.BlockedBy...)
+	}
+	if task.Children != nil {
+		c.Children = append([]string(nil), task.Children...)
+	}
+	if task.Blocks != nil {
+		c.Blocks = append([]string(nil), task.Blocks...)
+	}
+	if task.Checklist != nil {
+		c.Checklist = append([]models.ChecklistItem(nil), task.Checklist...)
+	}
+	if task.ExtraLines != nil {
+		c.ExtraLines = append([]string(nil), task.ExtraLines...)
+	}
+	if task.MetadataKeys != nil {
+		c.MetadataKeys = append([]string(nil), task.MetadataKeys...)
+	}
+	if task.LastRun != nil {
+		lastRun := *task.LastRun
+		c.LastRun = &lastRun
+	}
+	if task.Commits != nil {
+		c.Commits = append([]models.TaskCommit(nil), task.Commits...)
+	}
+	if task.Branches != nil {
+		c.Branches = append([]string(nil), task.Branches...)
+	}
+	if task.ArchivedAt != nil {
+		archivedAt := *task.ArchivedAt
+		c.ArchivedAt = &archivedAt
+	}
+	return &c
+}
+
+// snapshotLocked returns a deep copy of the current board state.
+// Caller must hold at least a read lock.
+func (s *TaskStore) snapshotLocked() *boardSnapshot {
+	snapshot := &boardSnapshot{
+		tasks:       make(map[string]*models.Task, len(s.tasks)),
+		columns:     append([]models.ColumnDefinition(nil), s.columns...),
+		settings:    s.settings,
+		header:      s.header,
+		preamble:    append([]string(nil), s.preamble...),
+		lineNumbers: make(map[string]int),
+	}
+	for id, task := range s.tasks {
+		snapshot.tasks[id] = copyTask(task)
+	}
+	return snapshot
+}
+
+// recordBase stores the current board as the merge base, along with the hash
+// of the file content it corresponds to. Caller must hold at least a read lock.
+func (s *TaskStore) recordBase(hash [sha256.Size]byte) {
+	snapshot := s.snapshotLocked()
+
+	s.baseMu.Lock()
+	defer s.baseMu.Unlock()
+	s.base = snapshot
+	s.baseHash = hash
+}
+
+// ReloadFromDisk merges external changes to TASKS.md into the in-memory board.
+// Unlike Load, unsaved in-memory edits are kept: each task is merged field by
+// field against the last loaded/saved snapshot. Fields changed on both sides
+// keep the value from disk and are returned (and reported to the merge
+// conflict callback) so the discarded edit is not silently lost.
+func (s *TaskStore) ReloadFromDisk() ([]models.MergeConflict, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.baseMu.Lock()
+	hasBase := s.base != nil
+	s.baseMu.Unlock()
+	if !hasBase {
+		return nil, s.loadLocked()
+	}
+
+	conflicts, localChanges, err := s.mergeFromDiskLocked()
+	if err != nil {
+		return nil, err
+	}
+	s.notifyMergeConflicts(conflicts)
+
+	if localChanges {
+		if err := s.saveLocked(); err != nil {
+			return conflicts, err
+		}
+	}
+
+	return conflicts, nil
+}
+
+// mergeFromDiskLocked performs a three-way merge of the files on disk into the
+// in-memory board. Returns the conflicts found and whether the merged board
+// contains changes that are not on disk yet. Caller must hold the write lock.
+func (s *TaskStore) mergeFromDiskLocked() ([]models.MergeConflict, bool, error) {
+	theirs, hash, err := s.storage.Load()
+	if err != nil {
+		if os.IsNotExist(err) {
+			// File removed (e.g. mid git checkout): keep the in-memory board
+			return nil, true, nil
+		}
+		return nil, false, err
+	}
+
+	s.baseMu.Lock()
+	base := s.base
+	unchanged := hash == s.baseHash
+	s.baseMu.Unlock()
+
+	if unchanged || base == nil {
+		// Our own save echoed back by the file watcher, or nothing has been
+		// loaded yet to merge against
+		return nil, false, nil
+	}
+
+	conflicts, localChanges := s.mergeBoardLocked(base, theirs)
+	s.taskLineNumbers = theirs.lineNumbers
+	s.refreshFromGit()
+
+	// The disk content is the new base. theirs is not referenced by the
+	// merged board (tasks are copied), so it can be stored directly.
+	s.baseMu.Lock()
+	s.base = theirs
+	s.baseHash = hash
+	s.baseMu.Unlock()
+
+	if s.ensureDefaultColumnsLocked() {
+		localChanges = true
+	}
+	if s.normalizeTasksLocked() {
+		localChanges = true
+	}
+	if s.refreshDerivedLocked() {
+		localChanges = true
+	}
+
+	return conflicts, localChanges, nil
+}
+
+// mergeBoardLocked merges theirs into the in-memory board using base as the
+// common ancestor. In-memory tasks are updated in place so pointers handed
+// out by other methods stay valid. Caller must hold the write lock.
+func (s *TaskStore) mergeBoardLocked(base, theirs *boardSnapshot) ([]models.MergeConflict, bool) {
+	conflicts, localChanges := mergeTaskMaps(base.tasks, s.tasks, theirs.tasks)
+
+	columnConflicts, columnsLocal := s.mergeColumnsLocked(base, theirs)
+	conflicts = append(conflicts, columnConflicts...)
+	if columnsLocal {
+		localChanges = true
+	}
+
+	settingsConflicts, settingsLocal := s.mergeSettingsLocked(base, theirs)
+	conflicts = append(conflicts, settingsConflicts...)
+	if settingsLocal {
+		localChanges = true
+	}
+
+	// The header and preamble are only edited on disk, so take them as-is
+	s.header = theirs.header
+	s.preamble = append([]string(nil), theirs.preamble...)
+	s.includes = theirs.includes
+
+	return conflicts, localChanges
+}
+
+// mergeTaskMaps merges the tasks in theirs into ours (in place) using base as
+// the common ancestor. Returns the conflicts found and whether ours holds
+// changes that are not in theirs.
+func mergeTaskMaps(base, ours, theirs map[string]*models.Task) ([]models.MergeConflict, bool) {
+	var conflicts []models.MergeConflict
+	localChanges := false
+
+	// Visit task IDs in a stable order so conflicts are reported deterministically
+	idSet := make(map[string]bool)
+	for id := range base {
+		idSet[id] = true
+	}
+	for id := range ours {
+		idSet[id] = true
+	}
+	for id := range theirs {
+		idSet[id] = true
+	}
+	ids := make([]string, 0, len(idSet))
+	for id := range idSet {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		b, inBase := base[id]
+		o, inOurs := ours[id]
+		t, inTheirs := theirs[id]
+
+		switch {
+		case inOurs && inTheirs:
+			if !inBase {
+				// Added on both sides with the same ID
+				b = &models.Task{}
+			}
+			taskConflicts, local := mergeTask(b, o, t)
+			conflicts = append(conflicts, taskConflicts...)
+			if local {
+				localChanges = true
+			}
+
+		case inOurs && !inTheirs:
+			if !inBase {
+				// Created in memory, not saved yet
+				localChanges = true
+			} else if o.Revision() == b.Revision() {
+				// Deleted on disk, untouched in memory
+				delete(ours, id)
+			} else {
+				localChanges = true
+				conflicts = append(conflicts, models.MergeConflict{
+					TaskID:     id,
+					Title:      o.Title,
+					Field:      "task",
+					Base:       "exists",
+					Ours:       "modified",
+					Theirs:     "deleted",
+					Resolution: resolutionKeptOurs,
+				})
+			}
+
+		case !inOurs && inTheirs:
+			if !inBase {
+				// Added on disk
+				ours[id] = copyTask(t)
+			} else if t.Revision() == b.Revision() {
+				// Deleted in memory, untouched on disk: stays deleted
+				localChanges = true
+			} else {
+				ours[id] = copyTask(t)
+				conflicts = append(conflicts, models.MergeConflict{
+					TaskID:     id,
+					Title:      t.Title,
+					Field:      "task",
+					Base:       "exists",
+					Ours:       "deleted",
+					Theirs:     "modified",
+					Resolution: resolutionKeptTheirs,
+				})
+			}
+
+		default:
+			// Deleted on both sides (or only in base): nothing to do
+		}
+	}
+
+	return conflicts, localChanges
+}
+
+// taskMergeField describes one independently mergeable part of a task
+type taskMergeField struct {
+	name  string
+	value func(*models.Task) string   // Canonical value used for comparison
+	take  func(dst, src *models.Task) // Copies the field from src into dst
+}
+
+// taskMergeFields lists the task fields merged independently of each other.
+// Timestamps, authors and order are resolved separately in mergeTask.
+var taskMergeFields = []taskMergeField{
+	{
+		name:  "title",
+		value: func(t *models.Task) string { return t.Title },
+		take:  func(dst, src *models.Task) { dst.Title = src.Title },
+	},
+	{
+		name:  "acceptance_criteria",
+		value: func(t *models.Task) string { return t.AcceptanceCriteria },
+		take:  func(dst, src *models.Task) { dst.AcceptanceCriteria = src.AcceptanceCriteria },
+	},
+	{
+		name:  "description",
+		value: func(t *models.Task) string { return t.Description },
+		take:  func(dst, src *models.Task) { dst.Description = src.Description },
+	},
+	{
+		name: "checklist",
+		value: func(t *models.Task) string {
+			items := make([]string, len(t.Checklist))
+			for i, item := range t.Checklist {
+				mark := " "
+				if item.Done {
+					mark = "x"
+				}
+				items[i] = fmt.Sprintf("[%s] %s", mark, item.Text)
+			}
+			return strings.Join(items, "\n")
+		},
+		take: func(dst, src *models.Task) { dst.Checklist = append([]models.ChecklistItem(nil), src.Checklist...) },
+	},
+	{
+		name:  "priority",
+		value: func(t *models.Task) string { return string(t.Priority) },
+		take:  func(dst, src *models.Task) { dst.Priority = src.Priority },
+	},
+	{
+		name:  "column",
+		value: func(t *models.Task) string { return string(t.Column) },
+		take:  func(dst, src *models.Task) { dst.Column = src.Column },
+	},
+	{
+		name:  "source",
+		value: func(t *models.Task) string { return t.Source },
+		take:  func(dst, src *models.Task) { dst.Source = src.Source },
+	},
+	{
+		name:  "tags",
+		value: func(t *models.Task) string { return strings.Join(t.Tags, ", ") },
+		take:  func(dst, src *models.Task) { dst.Tags = append([]string(nil), src.Tags...) },
+	},
+	{
+		name:  "parent",
+		value: func(t *models.Task) string { return t.Parent },
+		take:  func(dst, src *models.Task) { dst.Parent = src.Parent },
+	},
+	{
+		name:  "blocked_by",
+		value: func(t *models.Task) string { return strings.Join(t.BlockedBy, ", ") },
+		take:  func(dst, src *models.Task) { dst.BlockedBy = append([]string(nil), src.BlockedBy...) },
+	},
+	{
+		name:  "requires_test",
+		value: func(t *models.Task) string { return fmt.Sprintf("%t", t.RequiresTest) },
+		take:  func(dst, src *models.Task) { dst.RequiresTest = src.RequiresTest },
+	},
+	{
+		name: "tests",
+		value: func(t *models.Task) string {
+			specs := make([]string, len(t.Tests))
+			for i, test := range t.Tests {
+				specs[i] = test.String()
+			}
+			return strings.Join(specs, ", ")
+		},
+		take: func(dst, src *models.Task) { dst.Tests = append([]models.TestSpec(nil), src.Tests...) },
+	},
+	{
+		name:  "test_status",
+		value: func(t *models.Task) string { return t.CheckboxChar() },
+		take:  func(dst, src *models.Task) { dst.TestStatus = src.TestStatus },
+	},
+	{
+		name:  "test_results",
+		value: func(t *models.Task) string { return fmt.Sprintf("%d/%d", t.TestsPassed, t.TestsTotal) },
+		take: func(dst, src *models.Task) {
+			dst.TestsPassed = src.TestsPassed
+			dst.TestsTotal = src.TestsTotal
+		},
+	},
+	{
+		name:  "notes",
+		value: func(t *models.Task) string { return strings.Join(t.ExtraLines, "\n") },
+		take:  func(dst, src *models.Task) { dst.ExtraLines = append([]string(nil), src.ExtraLines...) },
+	},
+	{
+		name:  "created_at",
+		value: func(t *models.Task) string { return t.CreatedAt.UTC().Format("2006-01-02T15:04:05Z") },
+		take:  func(dst, src *models.Task) { dst.CreatedAt = src.CreatedAt },
+	},
+}
+
+// mergeTask merges theirs into ours (in place) field by field against base.
+// Returns conflicts and whether ours still holds changes not present on disk.
+func mergeTask(base, ours, theirs *models.Task) ([]models.MergeConflict, bool) {
+	var conflicts []models.MergeConflict
+	localChanges := false
+
+	for _, field := range taskMergeFields {
+		b, o, t := field.value(base), field.value(ours), field.value(theirs)
+		switch {
+		case o == t:
+			// Same on both sides
+		case o == b:
+			// Only changed on disk
+			field.take(ours, theirs)
+		case t == b:
+			// Only changed in memory
+			localChanges = true
+		default:
+			// Changed on both sides: the file on disk wins, the in-memory
+			// value is reported so it can be re-applied
+			field.take(ours, theirs)
+			conflicts = append(conflicts, models.MergeConflict{
+				TaskID:     ours.ID,
+				Title:      ours.Title,
+				Field:      field.name,
+				Base:       b,
+				Ours:       o,
+				Theirs:     t,
+				Resolution: resolutionKeptTheirs,
+			})
+		}
+	}
+
+	// Keep the most recent update timestamp and its author
+	if theirs.UpdatedAt.After(ours.UpdatedAt) {
+		ours.UpdatedAt = theirs.UpdatedAt
+		ours.UpdatedBy = theirs.UpdatedBy
+	}
+
+	// Follow the file order unless the task was reordered in memory
+	if ours.Order == base.Order {
+		ours.Order = theirs.Order
+	} else {
+		localChanges = true
+	}
+
+	return conflicts, localChanges
+}
+
+// mergeColumnsLocked merges column definitions. If both sides changed the
+// columns, the disk layout is used and in-memory-only columns are appended.
+// Caller must hold the write lock.
+func (s *TaskStore) mergeColumnsLocked(base, theirs *boardSnapshot) ([]models.MergeConflict, bool) {
+	ours := columnsString(s.columns)
+	b := columnsString(base.columns)
+	t := columnsString(theirs.columns)
+
+	switch {
+	case ours == t:
+		return nil, false
+	case ours == b:
+		s.columns = append([]models.ColumnDefinition(nil), theirs.columns...)
+		return nil, false
+	case t == b:
+		return nil, true
+	}
+
+	merged := append([]models.ColumnDefinition(nil), theirs.columns...)
+	existing := make(map[string]bool)
+	maxOrder := -1
+	for _, col := range merged {
+		existing[col.Slug] = true
+		if col.Order > maxOrder {
+			maxOrder = col.Order
+		}
+	}
+	for _, col := range s.getSortedColumns() {
+		if !existing[col.Slug] {
+			maxOrder++
+			col.Order = maxOrder
+			merged = append(merged, col)
+		}
+	}
+	s.columns = merged
+
+	return []models.MergeConflict{{
+		Field:      "columns",
+		Base:       b,
+		Ours:       ours,
+		Theirs:     t,
+		Resolution: resolutionMerged,
+	}}, columnsString(merged) != t
+}
+
+// columnsString renders columns in order for comparison
+func columnsString(columns []models.ColumnDefinition) string {
+	sorted := append([]models.ColumnDefinition(nil), columns...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Order < sorted[j].Order
+	})
+	names := make([]string, len(sorted))
+	for i, col := range sorted {
+		names[i] = col.Name
+		if len(col.ExtraLines) > 0 {
+			names[i] += " (" + strings.Join(col.ExtraLines, "\n") + ")"
+		}
+		for _, note := range col.Notes {
+			names[i] += " (" + note.After + ": " + strings.Join(note.Lines, "\n") + ")"
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// mergeSettingsLocked merges the YAML front matter settings as a whole.
+// Caller must hold the write lock.
+func (s *TaskStore) mergeSettingsLocked(base, theirs *boardSnapshot) ([]models.MergeConflict, bool) {
+	ours := settingsString(s.settings)
+	b := settingsString(base.settings)
+	t := settingsString(theirs.settings)
+
+	switch {
+	case ours == t:
+		return nil, false
+	case ours == b:
+		s.settings = theirs.settings
+		return nil, false
+	case t == b:
+		return nil, true
+	}
+
+	s.settings = theirs.settings
+	return []models.MergeConflict{{
+		Field:      "settings",
+		Base:       b,
+		Ours:       ours,
+		Theirs:     t,
+		Resolution: resolutionKeptTheirs,
+	}}, false
+}
+
+// settingsString renders settings as YAML for comparison
+func settingsString(settings Settings) string {
+	data, err := yaml.Marshal(&settings)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
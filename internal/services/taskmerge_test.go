@@ -0,0 +1,213 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"kantext/internal/models"
+)
+
+const mergeTestContent = `---
+stale_threshold_days: 7
+test_runner:
+  command: go test -v -count=1 -run ^{testFunc}$ {testPath}
+  pass_string: PASS
+  fail_string: FAIL
+  no_tests_string: no tests to run
+---
+# Kantext Tasks
+
+## Inbox
+- [ ] First task
+  - id: task-merge01
+  - priority: medium
+  - requires_test: false
+  - created_at: 2025-01-01T00:00:00Z
+  - updated_at: 2025-01-01T00:00:00Z
+- [ ] Second task
+  - id: task-merge02
+  - priority: medium
+  - requires_test: false
+  - created_at: 2025-01-01T00:00:00Z
+  - updated_at: 2025-01-01T00:00:00Z
+
+## In Progress
+
+## Done
+
+`
+
+// setupMergeEnv loads mergeTestContent and waits for the initial async save
+func setupMergeEnv(t *testing.T) (*TaskStore, string, func()) {
+	t.Helper()
+
+	store, cleanup := setupTaskStoreEnv(t, mergeTestContent)
+	time.Sleep(100 * time.Millisecond)
+
+	return store, filepath.Join(store.GetWorkingDir(), "TASKS.md"), cleanup
+}
+
+// editInMemory changes a task without triggering a save, simulating an edit
+// that the background saver has not flushed yet
+func editInMemory(store *TaskStore, id string, edit func(*models.Task)) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	edit(store.tasks[id])
+}
+
+// editOnDisk rewrites TASKS.md with a string replacement, simulating an editor
+func editOnDisk(t *testing.T, path, old, new string) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if !strings.Contains(string(data), old) {
+		t.Fatalf("TASKS.md does not contain %q:\n%s", old, data)
+	}
+	updated := strings.Replace(string(data), old, new, 1)
+	if err := os.WriteFile(path, []byte(updated), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+}
+
+func TestTaskStore_ReloadFromDisk_MergesDifferentTasks(t *testing.T) {
+	store, path, cleanup := setupMergeEnv(t)
+	defer cleanup()
+
+	editInMemory(store, "task-merge02", func(task *models.Task) {
+		task.Priority = models.PriorityHigh
+	})
+	editOnDisk(t, path, "- [ ] First task", "- [ ] First task (edited on disk)")
+
+	conflicts, err := store.ReloadFromDisk()
+	if err != nil {
+		t.Fatalf("ReloadFromDisk failed: %v", err)
+	}
+	if len(conflicts) != 0 {
+		t.Errorf("Expected no conflicts, got %+v", conflicts)
+	}
+
+	first, _ := store.Get("task-merge01")
+	second, _ := store.Get("task-merge02")
+	if first.Title != "First task (edited on disk)" {
+		t.Errorf("Expected disk edit to be merged, got title %q", first.Title)
+	}
+	if second.Priority != models.PriorityHigh {
+		t.Errorf("Expected in-memory edit to be kept, got priority %q", second.Priority)
+	}
+}
+
+func TestTaskStore_ReloadFromDisk_MergesDifferentFields(t *testing.T) {
+	store, path, cleanup := setupMergeEnv(t)
+	defer cleanup()
+
+	editInMemory(store, "task-merge01", func(task *models.Task) {
+		task.Priority = models.PriorityLow
+	})
+	editOnDisk(t, path, "- [ ] First task", "- [ ] Renamed on disk")
+
+	conflicts, err := store.ReloadFromDisk()
+	if err != nil {
+		t.Fatalf("ReloadFromDisk failed: %v", err)
+	}
+	if len(conflicts) != 0 {
+		t.Errorf("Expected no conflicts, got %+v", conflicts)
+	}
+
+	task, _ := store.Get("task-merge01")
+	if task.Title != "Renamed on disk" {
+		t.Errorf("Expected title from disk, got %q", task.Title)
+	}
+	if task.Priority != models.PriorityLow {
+		t.Errorf("Expected priority from memory, got %q", task.Priority)
+	}
+}
+
+func TestTaskStore_ReloadFromDisk_ReportsConflicts(t *testing.T) {
+	store, path, cleanup := setupMergeEnv(t)
+	defer cleanup()
+
+	reported := make(chan []models.MergeConflict, 1)
+	store.SetOnMergeConflicts(func(conflicts []models.MergeConflict) {
+		reported <- conflicts
+	})
+
+	editInMemory(store, "task-merge01", func(task *models.Task) {
+		task.Title = "Renamed in memory"
+	})
+	editOnDisk(t, path, "- [ ] First task", "- [ ] Renamed on disk")
+
+	conflicts, err := store.ReloadFromDisk()
+	if err != nil {
+		t.Fatalf("ReloadFromDisk failed: %v", err)
+	}
+	if len(conflicts) != 1 {
+		t.Fatalf("Expected 1 conflict, got %+v", conflicts)
+	}
+
+	c := conflicts[0]
+	if c.TaskID != "task-merge01" || c.Field != "title" {
+		t.Errorf("Unexpected conflict %+v", c)
+	}
+	if c.Ours != "Renamed in memory" || c.Theirs != "Renamed on disk" || c.Base != "First task" {
+		t.Errorf("Unexpected conflict values %+v", c)
+	}
+
+	select {
+	case got := <-reported:
+		if len(got) != 1 {
+			t.Errorf("Expected callback with 1 conflict, got %d", len(got))
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected merge conflict callback to be called")
+	}
+}
+
+func TestTaskStore_ReloadFromDisk_DeletedOnDisk(t *testing.T) {
+	store, path, cleanup := setupMergeEnv(t)
+	defer cleanup()
+
+	editOnDisk(t, path, `- [ ] Second task
+  - id: task-merge02
+`, "- [ ] Second task\n  - id: task-gone\n")
+
+	if _, err := store.ReloadFromDisk(); err != nil {
+		t.Fatalf("ReloadFromDisk failed: %v", err)
+	}
+
+	if _, err := store.Get("task-merge02"); err == nil {
+		t.Error("Expected task removed on disk to be removed from the store")
+	}
+	if _, err := store.Get("task-gone"); err != nil {
+		t.Error("Expected task added on disk to be added to the store")
+	}
+}
+
+func TestTaskStore_ReloadFromDisk_IgnoresOwnSave(t *testing.T) {
+	store, _, cleanup := setupMergeEnv(t)
+	defer cleanup()
+
+	title := "Saved by the store"
+	if _, err := store.Update("task-merge01", models.UpdateTaskRequest{Title: &title}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	conflicts, err := store.ReloadFromDisk()
+	if err != nil {
+		t.Fatalf("ReloadFromDisk failed: %v", err)
+	}
+	if len(conflicts) != 0 {
+		t.Errorf("Expected no conflicts from our own save, got %+v", conflicts)
+	}
+
+	task, _ := store.Get("task-merge01")
+	if task.Title != title {
+		t.Errorf("Expected title %q, got %q", title, task.Title)
+	}
+}
@@ -2,19 +2,24 @@ package services
 
 import (
 	"bufio"
+	"bytes"
 	"crypto/rand"
+	"crypto/sha256"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"math/big"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"kantext/internal/config"
 	"kantext/internal/models"
 
 	"gopkg.in/yaml.v3"
@@ -22,11 +27,14 @@ import (
 
 // Default settings values
 const (
-	DefaultStaleThresholdDays = 7
-	DefaultTestCommand        = "go test -v -count=1 -run ^{testFunc}$ {testPath}"
-	DefaultPassString         = "PASS"
-	DefaultFailString         = "FAIL"
-	DefaultNoTestsString      = "no tests to run"
+	DefaultStaleThresholdDays   = 7
+	DefaultArchiveRetentionDays = 30
+	DefaultTestCommand          = "go test -json -count=1 -run ^{testFunc}$ {testPath}"
+	DefaultTestFormat           = TestFormatGoJSON
+	DefaultPassString           = "PASS"
+	DefaultFailString           = "FAIL"
+	DefaultNoTestsString        = "no tests to run"
+	DefaultTestParallel         = 1
 )
 
 // Short ID configuration
@@ -56,22 +64,48 @@ func generateShortID() string {
 
 // TestRunnerSettings holds test runner configuration from YAML front matter
 type TestRunnerSettings struct {
-	Command       string `yaml:"command,omitempty"`
-	PassString    string `yaml:"pass_string,omitempty"`
-	FailString    string `yaml:"fail_string,omitempty"`
-	NoTestsString string `yaml:"no_tests_string,omitempty"`
+	Command       string            `yaml:"command,omitempty" json:"command,omitempty"`
+	PassString    string            `yaml:"pass_string,omitempty" json:"pass_string,omitempty"`
+	FailString    string            `yaml:"fail_string,omitempty" json:"fail_string,omitempty"`
+	NoTestsString string            `yaml:"no_tests_string,omitempty" json:"no_tests_string,omitempty"`
+	Format        string            `yaml:"format,omitempty" json:"format,omitempty"`     // Result format: go-json, junit or tap; empty matches the pass/fail strings
+	Report        string            `yaml:"report,omitempty" json:"report,omitempty"`     // Report file written by the command, relative to its directory; empty parses its output
+	Batch         bool              `yaml:"batch,omitempty" json:"batch,omitempty"`       // Run a task's tests in one command per package; needs a format
+	Parallel      int               `yaml:"parallel,omitempty" json:"parallel,omitempty"` // Packages tested at once, defaults to 1
+	Dir           string            `yaml:"dir,omitempty" json:"dir,omitempty"`           // Directory the command runs in, relative to the working directory
+	Env           map[string]string `yaml:"env,omitempty" json:"env,omitempty"`           // Extra environment variables for the command
+}
+
+// TestRunnerProfile is a named test runner for some of a board's tests, so
+// one board can hold tasks tested with different tools
+type TestRunnerProfile struct {
+	TestRunnerSettings `yaml:",inline"`
+	Match              []string `yaml:"match,omitempty" json:"match,omitempty"` // Globs for the test files it runs, e.g. "*.py" or "web/**/*.test.ts"
 }
 
 // AIQueueSettings holds AI queue configuration from YAML front matter
 type AIQueueSettings struct {
-	ActiveTaskID string `yaml:"active_task_id,omitempty"`
+	ActiveTaskID string `yaml:"active_task_id,omitempty" json:"active_task_id,omitempty"`
+}
+
+// GitSettings holds git integration configuration from YAML front matter
+type GitSettings struct {
+	AutoCommit  bool   `yaml:"auto_commit,omitempty" json:"auto_commit,omitempty"`   // Commit TASKS.md after board changes
+	AuthorName  string `yaml:"author_name,omitempty" json:"author_name,omitempty"`   // Commit author, defaults to git's user.name
+	AuthorEmail string `yaml:"author_email,omitempty" json:"author_email,omitempty"` // Commit author email, defaults to git's user.email
+	MainBranch  string `yaml:"main_branch,omitempty" json:"main_branch,omitempty"`   // Branch where closing commits land, defaults to main or master
+	CloseColumn string `yaml:"close_column,omitempty" json:"close_column,omitempty"` // Column for tasks closed by a commit ("Closes task-…"), empty to disable
 }
 
 // Settings holds all configurable settings stored in YAML front matter
 type Settings struct {
-	StaleThresholdDays int                `yaml:"stale_threshold_days,omitempty"`
-	TestRunner         TestRunnerSettings `yaml:"test_runner,omitempty"`
-	AIQueue            AIQueueSettings    `yaml:"ai_queue,omitempty"`
+	StaleThresholdDays   int                          `yaml:"stale_threshold_days,omitempty" json:"stale_threshold_days,omitempty"`
+	ArchiveRetentionDays int                          `yaml:"archive_retention_days,omitempty" json:"archive_retention_days,omitempty"` // Days archived tasks are kept before being purged
+	TestRunner           TestRunnerSettings           `yaml:"test_runner,omitempty" json:"test_runner,omitempty"`
+	TestRunners          map[string]TestRunnerProfile `yaml:"test_runners,omitempty" json:"test_runners,omitempty"` // Named runner profiles, chosen per test
+	AIQueue              AIQueueSettings              `yaml:"ai_queue,omitempty" json:"ai_queue,omitempty"`
+	Git                  GitSettings                  `yaml:"git,omitempty" json:"git,omitempty"`
+	Include              []string                     `yaml:"include,omitempty" json:"include,omitempty"` // Other task files shown on this board, relative to this file
 }
 
 // GetStaleThresholdDays returns the stale threshold, or default if not set
@@ -82,6 +116,14 @@ func (s *Settings) GetStaleThresholdDays() int {
 	return s.StaleThresholdDays
 }
 
+// GetArchiveRetentionDays returns how long archived tasks are kept, or default if not set
+func (s *Settings) GetArchiveRetentionDays() int {
+	if s.ArchiveRetentionDays <= 0 {
+		return DefaultArchiveRetentionDays
+	}
+	return s.ArchiveRetentionDays
+}
+
 // GetTestCommand returns the test command, or default if not set
 func (s *Settings) GetTestCommand() string {
 	if s.TestRunner.Command == "" {
@@ -90,6 +132,21 @@ func (s *Settings) GetTestCommand() string {
 	return s.TestRunner.Command
 }
 
+// GetTestFormat returns the test result format. The default test command
+// reports go-json when no command is set.
+func (s *Settings) GetTestFormat() string {
+	if s.TestRunner.Command == "" {
+		return DefaultTestFormat
+	}
+	return s.TestRunner.Format
+}
+
+// GetTestBatch reports whether a task's tests in one package run together,
+// as they do with the default test command when no command is set
+func (s *Settings) GetTestBatch() bool {
+	return s.TestRunner.Command == "" || s.TestRunner.Batch
+}
+
 // GetPassString returns the pass string, or default if not set
 func (s *Settings) GetPassString() string {
 	if s.TestRunner.PassString == "" {
@@ -114,42 +171,115 @@ func (s *Settings) GetNoTestsString() string {
 	return s.TestRunner.NoTestsString
 }
 
+// GetTestParallel returns how many packages are tested at once, or default if not set
+func (s *Settings) GetTestParallel() int {
+	if s.TestRunner.Parallel < 1 {
+		return DefaultTestParallel
+	}
+	return s.TestRunner.Parallel
+}
+
 // Pre-compiled regex patterns for parsing task files
 var (
 	columnRegex             = regexp.MustCompile(`^## (.+)$`)
 	taskTitleRegex          = regexp.MustCompile(`^- \[([ x-])\] (.+)$`)
 	metadataRegex           = regexp.MustCompile(`^  - ([^:]+): (.*)$`)
+	checklistItemRegex      = regexp.MustCompile(`^  - \[([ xX])\] (.+)$`)
 	legacyTaskWithTestRegex = regexp.MustCompile(`^- \[([ x-])\] \[(high|medium|low)\] (.+?) \| ([^:]+):([^ ]+) \| (.+?)(?:\s*<!-- id:([a-f0-9-]+) -->)?$`)
 	legacyTaskNoTestRegex   = regexp.MustCompile(`^- \[([ x-])\] \[(high|medium|low)\] (.+?) \| (.+?)(?:\s*<!-- id:([a-f0-9-]+) -->)?$`)
 	legacyOldTaskRegex      = regexp.MustCompile(`^- \[([ x-])\] (.+?) \| ([^:]+):([^ ]+) \| (.+?)(?:\s*<!-- id:([a-f0-9-]+) -->)?$`)
 )
 
-// TaskStore manages reading and writing tasks to a markdown file
+// TaskStore keeps a board in memory and persists it through a Storage,
+// by default a markdown file
 type TaskStore struct {
 	filePath        string
-	workingDir      string // Working directory for test execution
+	storage         Storage // Reads and writes the board in the tasks file's format
+	workingDir      string  // Working directory for test execution
 	mu              sync.RWMutex
 	tasks           map[string]*models.Task
 	columns         []models.ColumnDefinition
-	settings        Settings                   // Settings from YAML front matter
-	taskLineNumbers map[string]int             // Maps task ID to line number for git blame
+	settings        Settings                // Settings from YAML front matter
+	header          string                  // Title line at the top of the file
+	preamble        []string                // Unrecognised content before the first column
+	includes        map[string]includedFile // Title and preamble of each included file
+	taskLineNumbers map[string]int          // Maps task ID to line number for git blame
+	gitAuthors      gitAuthorCache          // Task authors from git history, cached per HEAD commit
+	gitLinks        gitLinkCache            // Commits and branches mentioning tasks, cached per branch state
 
 	// AI Queue state (in-memory only, not persisted to TASKS.md)
-	aiQueue      []string             // Ordered list of task IDs in the AI queue
-	activeTaskID string               // Currently active task ID (not persisted)
-	aiSession    *models.AISession    // Current AI conversation
+	aiQueue      []string          // Ordered list of task IDs in the AI queue
+	activeTaskID string            // Currently active task ID (not persisted)
+	aiSession    *models.AISession // Current AI conversation
 
 	// Async save infrastructure
-	saveChan  chan struct{}  // Channel to trigger background saves
-	saveErr   error          // Last save error (for monitoring)
-	saveErrMu sync.RWMutex   // Protects saveErr
+	saveChan    chan struct{} // Channel to trigger background saves
+	saverDone   chan struct{} // Closed when the background saver has exited
+	durable     bool          // When true, saves are written synchronously before returning
+	unsaved     bool          // Changes are waiting for the background saver (protected by mu)
+	closed      bool          // Close has stopped the background saver; later saves are synchronous (protected by mu)
+	saveErr     error         // Last save error (for monitoring)
+	saveErrAt   time.Time     // When the last save error occurred
+	lastSavedAt time.Time     // When the last successful save completed
+	saveErrMu   sync.RWMutex  // Protects saveErr, saveErrAt and lastSavedAt
+	autoCommit  autoCommitter // Batches saves into git commits when git.auto_commit is on
+
+	// Merge base: the board as it was last read from or written to disk
+	base             *boardSnapshot
+	baseHash         [sha256.Size]byte
+	baseMu           sync.Mutex                   // Protects base and baseHash
+	onMergeConflicts func([]models.MergeConflict) // Called when external edits conflict with in-memory edits
+	onUndoRedo       func(models.UndoResult)      // Called after a change is undone or redone
+
+	history     *HistoryLog     // Append-only log of task events in .kantext/history.jsonl
+	testHistory *TestHistoryLog // Append-only log of test outcomes in .kantext/test-history.jsonl
+
+	// Deleted tasks, kept in .kantext/archive.md until restored or purged
+	archive     map[string]*models.Task
+	archivePath string
+
+	// Undo state (in-memory only): most recent change last
+	undoStack []*undoEntry
+	redoStack []*undoEntry
+}
+
+// ErrSaveFailed is returned by mutating TaskStore methods in durable mode
+// when the change could not be written to disk.
+var ErrSaveFailed = errors.New("failed to save tasks file")
+
+// ErrRevisionConflict is returned by Update when the caller's expected revision
+// does not match the task's current revision.
+var ErrRevisionConflict = errors.New("revision conflict")
+
+// SaveStatus reports the health of TASKS.md persistence
+type SaveStatus struct {
+	Durable     bool       `json:"durable"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+	LastSavedAt *time.Time `json:"last_saved_at,omitempty"`
+}
+
+// Healthy returns true if the most recent save attempt succeeded
+func (st SaveStatus) Healthy() bool {
+	return st.LastError == ""
 }
 
-// NewTaskStore creates a new TaskStore with the specified working directory
+// NewTaskStore creates a new TaskStore with the specified working directory,
+// reading TASKS.md from it
 func NewTaskStore(workingDir string) *TaskStore {
-	filePath := filepath.Join(workingDir, "TASKS.md")
+	return NewTaskStoreWithFile(workingDir, config.DefaultTasksFileName)
+}
+
+// NewTaskStoreWithFile creates a new TaskStore reading tasks from tasksFile.
+// A relative tasksFile is resolved against the working directory.
+func NewTaskStoreWithFile(workingDir, tasksFile string) *TaskStore {
+	filePath := tasksFile
+	if !filepath.IsAbs(filePath) {
+		filePath = filepath.Join(workingDir, tasksFile)
+	}
 	store := &TaskStore{
 		filePath:        filePath,
+		storage:         newStorage(filePath),
 		workingDir:      workingDir,
 		tasks:           make(map[string]*models.Task),
 		columns:         []models.ColumnDefinition{},
@@ -157,15 +287,67 @@ func NewTaskStore(workingDir string) *TaskStore {
 		taskLineNumbers: make(map[string]int),
 		aiQueue:         []string{},
 		saveChan:        make(chan struct{}, 1), // Buffered channel of 1 for coalescing saves
+		saverDone:       make(chan struct{}),
+		history:         NewHistoryLog(filepath.Join(workingDir, ".kantext", "history.jsonl")),
+		testHistory:     NewTestHistoryLog(filepath.Join(workingDir, ".kantext", "test-history.jsonl")),
+		archive:         make(map[string]*models.Task),
+		archivePath:     filepath.Join(workingDir, ".kantext", "archive.md"),
 	}
 	store.Load()
 
+	store.purgeArchiveOnStartup()
+
 	// Start background saver goroutine
 	go store.backgroundSaver()
 
 	return store
 }
 
+// SetDurableWrites enables or disables durable-write mode. In durable mode,
+// mutating methods write TASKS.md synchronously and return ErrSaveFailed if
+// the write fails, instead of handing the save off to the background saver.
+func (s *TaskStore) SetDurableWrites(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.durable = enabled
+}
+
+// GetSaveStatus returns the last save error and last successful save time
+func (s *TaskStore) GetSaveStatus() SaveStatus {
+	s.mu.RLock()
+	durable := s.durable
+	s.mu.RUnlock()
+
+	s.saveErrMu.RLock()
+	defer s.saveErrMu.RUnlock()
+
+	status := SaveStatus{Durable: durable}
+	if s.saveErr != nil {
+		status.LastError = s.saveErr.Error()
+		errAt := s.saveErrAt
+		status.LastErrorAt = &errAt
+	}
+	if !s.lastSavedAt.IsZero() {
+		savedAt := s.lastSavedAt
+		status.LastSavedAt = &savedAt
+	}
+	return status
+}
+
+// recordSaveResult stores the outcome of a save attempt for GetSaveStatus
+func (s *TaskStore) recordSaveResult(err error) {
+	s.saveErrMu.Lock()
+	defer s.saveErrMu.Unlock()
+
+	now := time.Now().UTC()
+	s.saveErr = err
+	if err != nil {
+		s.saveErrAt = now
+	} else {
+		s.lastSavedAt = now
+	}
+}
+
 // GetSettings returns the current settings (thread-safe)
 func (s *TaskStore) GetSettings() Settings {
 	s.mu.RLock()
@@ -178,16 +360,33 @@ func (s *TaskStore) GetWorkingDir() string {
 	return s.workingDir
 }
 
+// GetTasksFile returns the path of the board's main tasks file
+func (s *TaskStore) GetTasksFile() string {
+	return s.filePath
+}
+
+// Files returns the files holding the board: the tasks file and, for a
+// markdown board, the files it includes
+func (s *TaskStore) Files() []string {
+	return s.storage.Files()
+}
+
 // UpdateSettings updates the settings and saves to file
 func (s *TaskStore) UpdateSettings(settings Settings) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	previous := s.settings
 	s.settings = settings
-	return s.saveLocked()
+	if err := s.saveLocked(); err != nil {
+		s.settings = previous
+		return err
+	}
+	return nil
 }
 
 // applyMetadata parses a metadata key-value pair and applies it to the task.
-func applyMetadata(task *models.Task, key, value string) {
+// Returns false if the key is not recognised, so the caller can preserve it.
+func applyMetadata(task *models.Task, key, value string) bool {
 	switch key {
 	case "id":
 		task.ID = value
@@ -205,20 +404,34 @@ func applyMetadata(task *models.Task, key, value string) {
 	case "requires_test":
 		task.RequiresTest = value == "true"
 	case "test":
-		// Parse test: path/to/file:TestFunc and append to Tests array
+		// Parse test: [@profile ]path/to/file:TestFunc and append to Tests array
+		var runner string
+		if strings.HasPrefix(value, "@") {
+			runner, value, _ = strings.Cut(value[1:], " ")
+		}
 		parts := strings.SplitN(value, ":", 2)
 		if len(parts) == 2 {
 			task.Tests = append(task.Tests, models.TestSpec{
-				File: parts[0],
-				Func: parts[1],
+				File:   parts[0],
+				Func:   parts[1],
+				Runner: runner,
 			})
 		}
+	case "parent":
+		task.Parent = value
+	case "blocked_by":
+		task.BlockedBy = parseIDList(value)
+	case "blocks":
+		// Inverse of blocked_by; folded into the blocked tasks after parsing
+		task.Blocks = parseIDList(value)
 	case "tests_passed":
 		fmt.Sscanf(value, "%d", &task.TestsPassed)
 	case "tests_total":
 		fmt.Sscanf(value, "%d", &task.TestsTotal)
 	case "criteria":
 		task.AcceptanceCriteria = value
+	case "description":
+		task.Description = value
 	case "created_at":
 		if t, err := time.Parse("2006-01-02T15:04:05Z", value); err == nil {
 			task.CreatedAt = t
@@ -231,7 +444,12 @@ func applyMetadata(task *models.Task, key, value string) {
 		}
 	case "updated_by":
 		task.UpdatedBy = value
+	case "test_status":
+		// Legacy key: the checkbox on the title line carries the test status
+	default:
+		return false
 	}
+	return true
 }
 
 // parseCheckboxStatus converts a checkbox character to TestStatus.
@@ -249,8 +467,13 @@ func parseCheckboxStatus(checkbox string) models.TestStatus {
 // getSortedColumns returns a copy of columns sorted by order.
 // Must be called with at least a read lock held.
 func (s *TaskStore) getSortedColumns() []models.ColumnDefinition {
-	sorted := make([]models.ColumnDefinition, len(s.columns))
-	copy(sorted, s.columns)
+	return sortColumns(s.columns)
+}
+
+// sortColumns returns a copy of columns in display order
+func sortColumns(columns []models.ColumnDefinition) []models.ColumnDefinition {
+	sorted := make([]models.ColumnDefinition, len(columns))
+	copy(sorted, columns)
 	sort.Slice(sorted, func(i, j int) bool {
 		return sorted[i].Order < sorted[j].Order
 	})
@@ -289,44 +512,113 @@ func (s *TaskStore) getMaxColumnOrder() int {
 	return maxOrder
 }
 
-// Load reads tasks from the markdown file
+// Load reads tasks from the markdown file, replacing the in-memory board.
+// Use ReloadFromDisk to merge external edits while keeping unsaved changes.
 func (s *TaskStore) Load() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	return s.loadLocked()
+}
 
-	file, err := os.Open(s.filePath)
+// loadLocked reads tasks from the markdown file and the files it includes.
+// Caller must hold the write lock.
+func (s *TaskStore) loadLocked() error {
+	board, hash, err := s.storage.Load()
 	if err != nil {
 		if os.IsNotExist(err) {
 			return s.createInitialFile()
 		}
 		return err
 	}
-	defer file.Close()
 
-	s.tasks = make(map[string]*models.Task)
-	s.columns = []models.ColumnDefinition{}
-	s.settings = Settings{} // Reset settings
+	s.tasks = board.tasks
+	s.columns = board.columns
+	s.settings = board.settings
+	s.header = board.header
+	s.preamble = board.preamble
+	s.includes = board.includes
 	// Note: aiQueue is NOT reset here - it's in-memory only and persists across file reloads
 	// It only resets when the server restarts (in NewTaskStore)
-	scanner := bufio.NewScanner(file)
-	var currentColumn models.Column
-	columnOrder := 0
-	taskOrder := 0
-	var currentTask *models.Task
-	var currentTaskLine int // 1-indexed line number where current task starts
-	var lines []string
-	inAIQueueSection := false // Track if we're parsing the AI Queue section (to skip it)
+	s.taskLineNumbers = board.lineNumbers
+
+	// Enrich tasks with authors, commits and branches from git
+	s.refreshFromGit()
+
+	// Remember what is on disk as the base for merging external edits
+	s.recordBase(hash)
+
+	// Ensure default columns exist (regardless of what was parsed)
+	columnsChanged := s.ensureDefaultColumnsLocked()
 
-	// Reset line numbers map for git blame lookup
-	s.taskLineNumbers = make(map[string]int)
+	// Normalize tasks - fill in missing required fields
+	tasksChanged := s.normalizeTasksLocked()
+
+	// Fill in dependency and subtask fields; parents' test status rolls up
+	// from their subtasks, which may need writing back
+	derivedChanged := s.refreshDerivedLocked()
+
+	// Check if settings need to be initialized with defaults
+	settingsNeedInit := s.settingsNeedInitializationLocked()
 
-	// Read all lines first
+	// Move tasks closed by commits that have landed on the main branch
+	closed := s.closeTasksLocked()
+
+	// If changes were made, save the file
+	if columnsChanged || tasksChanged || derivedChanged || settingsNeedInit || len(closed) > 0 {
+		if err := s.saveLocked(); err != nil {
+			return fmt.Errorf("failed to save after normalization: %w", err)
+		}
+	}
+	s.recordHistory(closed...)
+
+	return nil
+}
+
+// splitLines splits file content into lines without trailing newlines
+func splitLines(data []byte) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
 	for scanner.Scan() {
 		lines = append(lines, scanner.Text())
 	}
 	if err := scanner.Err(); err != nil {
-		return err
+		return nil, err
+	}
+	return lines, nil
+}
+
+// defaultBoardHeader is the title line written at the top of a new tasks file
+const defaultBoardHeader = "# Kantext Tasks"
+
+// boardSnapshot holds the parsed contents of a tasks file
+type boardSnapshot struct {
+	tasks       map[string]*models.Task
+	columns     []models.ColumnDefinition
+	settings    Settings
+	header      string                  // Title line before the first column (e.g. "# Kantext Tasks")
+	preamble    []string                // Unrecognised content between the title and the first column
+	lineNumbers map[string]int          // Maps task ID to line number for git blame
+	includes    map[string]includedFile // Title and preamble of each included file
+}
+
+// parseBoard parses the lines of a tasks file (including YAML front matter)
+// into a board snapshot. It does not touch any TaskStore state.
+func parseBoard(lines []string) *boardSnapshot {
+	board := &boardSnapshot{
+		tasks:       make(map[string]*models.Task),
+		columns:     []models.ColumnDefinition{},
+		header:      defaultBoardHeader,
+		lineNumbers: make(map[string]int),
 	}
+	headerSeen := false
+
+	var currentColumn models.Column
+	columnOrder := 0
+	taskOrder := 0
+	var currentTask *models.Task
+	var currentTaskLine int   // 1-indexed line number where current task starts
+	var previousTask string   // ID of the last task read in the current column
+	inAIQueueSection := false // Track if we're parsing the AI Queue section (to skip it)
 
 	// Parse YAML front matter if present
 	startLine := 0
@@ -345,7 +637,7 @@ func (s *TaskStore) Load() error {
 			yamlContent := strings.Join(yamlLines, "\n")
 
 			// Parse YAML into settings
-			if err := yaml.Unmarshal([]byte(yamlContent), &s.settings); err != nil {
+			if err := yaml.Unmarshal([]byte(yamlContent), &board.settings); err != nil {
 				log.Printf("Warning: failed to parse YAML front matter: %v", err)
 				// Continue with default settings
 			}
@@ -363,10 +655,11 @@ func (s *TaskStore) Load() error {
 			}
 			currentTask.Order = taskOrder
 			taskOrder++
-			s.tasks[currentTask.ID] = currentTask
+			board.tasks[currentTask.ID] = currentTask
+			previousTask = currentTask.ID
 			// Store the line number for git blame lookup
 			if currentTaskLine > 0 {
-				s.taskLineNumbers[currentTask.ID] = currentTaskLine
+				board.lineNumbers[currentTask.ID] = currentTaskLine
 			}
 			currentTask = nil
 			currentTaskLine = 0
@@ -392,8 +685,9 @@ func (s *TaskStore) Load() error {
 			inAIQueueSection = false
 			slug := models.NameToSlug(columnName)
 			currentColumn = models.Column(slug)
+			previousTask = ""
 
-			s.columns = append(s.columns, models.ColumnDefinition{
+			board.columns = append(board.columns, models.ColumnDefinition{
 				Slug:  slug,
 				Name:  columnName,
 				Order: columnOrder,
@@ -407,10 +701,30 @@ func (s *TaskStore) Load() error {
 			continue
 		}
 
+		// Check for checklist item (nested "- [ ]" under the task)
+		if currentTask != nil {
+			if matches := checklistItemRegex.FindStringSubmatch(line); matches != nil {
+				currentTask.Checklist = append(currentTask.Checklist, models.ChecklistItem{
+					Text: strings.TrimSpace(matches[2]),
+					Done: matches[1] != " ",
+				})
+				continue
+			}
+		}
+
 		// Check for metadata line (indented with 2 spaces)
 		if strings.HasPrefix(line, "  - ") && currentTask != nil {
 			if matches := metadataRegex.FindStringSubmatch(line); matches != nil {
-				applyMetadata(currentTask, strings.TrimSpace(matches[1]), strings.TrimSpace(matches[2]))
+				key, value := strings.TrimSpace(matches[1]), strings.TrimSpace(matches[2])
+				if blockMetadataKeys[key] && value == "|" {
+					// Multi-line value: consume the indented lines that follow
+					value, i = readBlockValue(lines, i+1)
+				}
+				currentTask.MetadataKeys = append(currentTask.MetadataKeys, key)
+				if !applyMetadata(currentTask, key, value) {
+					// Unknown key: keep the line so it survives the next save
+					currentTask.ExtraLines = append(currentTask.ExtraLines, line)
+				}
 				continue
 			}
 		}
@@ -422,19 +736,19 @@ func (s *TaskStore) Load() error {
 				// Try legacy formats first
 				if legacyMatches := legacyTaskWithTestRegex.FindStringSubmatch(trimmedLine); legacyMatches != nil {
 					finalizeTask()
-					currentTask = s.parseLegacyTaskWithTest(legacyMatches, currentColumn)
+					currentTask = parseLegacyTaskWithTest(legacyMatches, currentColumn)
 					currentTaskLine = i + 1
 					continue
 				}
 				if legacyMatches := legacyTaskNoTestRegex.FindStringSubmatch(trimmedLine); legacyMatches != nil {
 					finalizeTask()
-					currentTask = s.parseLegacyTaskNoTest(legacyMatches, currentColumn)
+					currentTask = parseLegacyTaskNoTest(legacyMatches, currentColumn)
 					currentTaskLine = i + 1
 					continue
 				}
 				if legacyMatches := legacyOldTaskRegex.FindStringSubmatch(trimmedLine); legacyMatches != nil {
 					finalizeTask()
-					currentTask = s.parseLegacyOldTask(legacyMatches, currentColumn)
+					currentTask = parseLegacyOldTask(legacyMatches, currentColumn)
 					currentTaskLine = i + 1
 					continue
 				}
@@ -451,31 +765,166 @@ func (s *TaskStore) Load() error {
 			currentTaskLine = i + 1 // 1-indexed for git blame
 			continue
 		}
+
+		// Anything else is content we don't model (notes, paragraphs, HTML
+		// comments, headings). Attach it to the closest task, column or the
+		// board preamble so it is written back in place. Unindented content
+		// after a blank line ends the task: it is a note in the column.
+		indented := strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+		afterBlank := i > startLine && strings.TrimSpace(lines[i-1]) == ""
+		if currentTask != nil && trimmedLine != "" && !indented && afterBlank {
+			finalizeTask()
+		}
+		switch {
+		case currentTask != nil:
+			currentTask.ExtraLines = append(currentTask.ExtraLines, line)
+		case len(board.columns) > 0 && previousTask != "":
+			col := &board.columns[len(board.columns)-1]
+			if n := len(col.Notes); n > 0 && col.Notes[n-1].After == previousTask {
+				col.Notes[n-1].Lines = append(col.Notes[n-1].Lines, line)
+			} else {
+				col.Notes = append(col.Notes, models.ColumnNote{After: previousTask, Lines: []string{line}})
+			}
+		case len(board.columns) > 0:
+			col := &board.columns[len(board.columns)-1]
+			col.ExtraLines = append(col.ExtraLines, line)
+		case !headerSeen && strings.HasPrefix(trimmedLine, "# "):
+			board.header = trimmedLine
+			headerSeen = true
+		default:
+			board.preamble = append(board.preamble, line)
+		}
 	}
 
 	// Finalize last task
 	finalizeTask()
 
-	// Enrich tasks with git blame author information
-	s.refreshGitBlame()
+	// "blocks" is accepted as a convenience; blocked_by is what gets saved
+	foldBlocksMetadata(board.tasks)
+
+	// Surrounding blank lines are layout, not content: the writer adds its own
+	board.preamble = trimBlankLines(board.preamble)
+	for i := range board.columns {
+		col := &board.columns[i]
+		col.ExtraLines = trimBlankLines(col.ExtraLines)
+		notes := col.Notes[:0]
+		for _, note := range col.Notes {
+			if note.Lines = trimBlankLines(note.Lines); len(note.Lines) > 0 {
+				notes = append(notes, note)
+			}
+		}
+		col.Notes = notes
+		if len(notes) == 0 {
+			col.Notes = nil
+		}
+	}
+	for _, task := range board.tasks {
+		task.ExtraLines = trimBlankLines(task.ExtraLines)
+	}
 
-	// Ensure default columns exist (regardless of what was parsed)
-	columnsChanged := s.ensureDefaultColumnsLocked()
+	return board
+}
 
-	// Normalize tasks - fill in missing required fields
-	tasksChanged := s.normalizeTasksLocked()
+// blockIndent prefixes each line of a multi-line metadata value
+const blockIndent = "    "
 
-	// Check if settings need to be initialized with defaults
-	settingsNeedInit := s.settingsNeedInitializationLocked()
+// blockMetadataKeys are the metadata keys whose values may span several lines.
+// Such values are written as "  - key: |" followed by the value's lines,
+// each indented by blockIndent, so checklists and code blocks survive intact.
+var blockMetadataKeys = map[string]bool{
+	"criteria":    true,
+	"description": true,
+}
 
-	// If changes were made, save the file
-	if columnsChanged || tasksChanged || settingsNeedInit {
-		if err := s.saveLocked(); err != nil {
-			return fmt.Errorf("failed to save after normalization: %w", err)
+// readBlockValue reads the indented lines of a multi-line metadata value
+// starting at lines[start]. Blank lines inside the block are kept; trailing
+// blank lines are left for the caller. Returns the value and the index of the
+// last line consumed (start-1 if the block is empty).
+func readBlockValue(lines []string, start int) (string, int) {
+	var block []string
+	last := start - 1
+	for i := start; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" {
+			block = append(block, "")
+			continue
 		}
+		if !strings.HasPrefix(line, blockIndent) {
+			break
+		}
+		block = append(block, strings.TrimPrefix(line, blockIndent))
+		last = i
 	}
+	// Drop blank lines after the last indented line; they separate the
+	// block from whatever follows rather than belonging to the value
+	block = block[:last-start+1]
+	return strings.Join(block, "\n"), last
+}
 
-	return nil
+// writeMetadataValue writes a "  - key: value" metadata line, switching to the
+// indented block form when the value spans several lines or would otherwise
+// be read back as a block marker.
+func writeMetadataValue(w io.Writer, key, value string) {
+	if !strings.Contains(value, "\n") && value != "|" {
+		fmt.Fprintf(w, "  - %s: %s\n", key, value)
+		return
+	}
+	fmt.Fprintf(w, "  - %s: |\n", key)
+	for _, line := range strings.Split(value, "\n") {
+		if strings.TrimSpace(line) == "" {
+			fmt.Fprintln(w, "")
+			continue
+		}
+		fmt.Fprintf(w, "%s%s\n", blockIndent, line)
+	}
+}
+
+// normalizeText converts line endings to "\n" and trims trailing whitespace
+// from each line, and surrounding whitespace from the whole text, so free-form
+// fields round-trip through TASKS.md unchanged.
+func normalizeText(s string) string {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// trimBlankLines removes leading and trailing blank lines.
+// Returns nil if nothing but blank lines remain.
+func trimBlankLines(lines []string) []string {
+	start, end := 0, len(lines)
+	for start < end && strings.TrimSpace(lines[start]) == "" {
+		start++
+	}
+	for end > start && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+	if start == end {
+		return nil
+	}
+	return lines[start:end]
+}
+
+// writeExtraLines writes preserved content verbatim. Blocks that start at the
+// left margin are separated by blank lines so they don't run into list items;
+// an indented block keeps one blank line if it had any before it.
+func writeExtraLines(w io.Writer, lines []string) {
+	blankBefore := len(lines) > 0 && strings.TrimSpace(lines[0]) == ""
+	lines = trimBlankLines(lines)
+	if len(lines) == 0 {
+		return
+	}
+	indented := strings.HasPrefix(lines[0], " ") || strings.HasPrefix(lines[0], "\t")
+	if !indented || blankBefore {
+		fmt.Fprintln(w, "")
+	}
+	for _, line := range lines {
+		fmt.Fprintln(w, line)
+	}
+	if !indented {
+		fmt.Fprintln(w, "")
+	}
 }
 
 // settingsNeedInitializationLocked checks if settings are missing values that need defaults.
@@ -567,7 +1016,7 @@ func (s *TaskStore) ensureDefaultColumnsLocked() bool {
 }
 
 // parseLegacyTaskWithTest parses the old format with test reference
-func (s *TaskStore) parseLegacyTaskWithTest(matches []string, column models.Column) *models.Task {
+func parseLegacyTaskWithTest(matches []string, column models.Column) *models.Task {
 	id := matches[7]
 	if id == "" {
 		id = generateShortID()
@@ -590,7 +1039,7 @@ func (s *TaskStore) parseLegacyTaskWithTest(matches []string, column models.Colu
 }
 
 // parseLegacyTaskNoTest parses the old format without test reference
-func (s *TaskStore) parseLegacyTaskNoTest(matches []string, column models.Column) *models.Task {
+func parseLegacyTaskNoTest(matches []string, column models.Column) *models.Task {
 	id := matches[5]
 	if id == "" {
 		id = generateShortID()
@@ -609,7 +1058,7 @@ func (s *TaskStore) parseLegacyTaskNoTest(matches []string, column models.Column
 }
 
 // parseLegacyOldTask parses the oldest format (no priority brackets)
-func (s *TaskStore) parseLegacyOldTask(matches []string, column models.Column) *models.Task {
+func parseLegacyOldTask(matches []string, column models.Column) *models.Task {
 	id := matches[6]
 	if id == "" {
 		id = generateShortID()
@@ -633,15 +1082,40 @@ func (s *TaskStore) parseLegacyOldTask(matches []string, column models.Column) *
 
 // Save writes all tasks to the markdown file (acquires lock)
 func (s *TaskStore) Save() error {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return s.saveLocked()
 }
 
-// saveLocked triggers an async save. Returns immediately without waiting for I/O.
-// Caller must hold at least a read lock.
+// saveLocked triggers an async save. Returns immediately without waiting for I/O,
+// unless durable-write mode is enabled, in which case the file is written before
+// returning and any failure is reported as ErrSaveFailed.
+// Caller must hold the write lock.
 func (s *TaskStore) saveLocked() error {
+	// Every mutation ends here, so keep the derived fields current
+	s.refreshDerivedLocked()
+
+	if s.durable || s.closed {
+		// Fold in edits made to the file on disk first so they are not clobbered
+		conflicts, _, err := s.mergeFromDiskLocked()
+		if err != nil {
+			log.Printf("Error merging external changes: %v", err)
+		}
+		s.notifyMergeConflicts(conflicts)
+
+		err = s.saveToFile()
+		s.recordSaveResult(err)
+		if err != nil {
+			log.Printf("Error saving tasks: %v", err)
+			return fmt.Errorf("%w: %v", ErrSaveFailed, err)
+		}
+		s.unsaved = false
+		s.scheduleAutoCommit()
+		return nil
+	}
+
 	// Non-blocking send - if channel is full, a save is already pending
+	s.unsaved = true
 	select {
 	case s.saveChan <- struct{}{}:
 	default:
@@ -650,93 +1124,356 @@ func (s *TaskStore) saveLocked() error {
 	return nil
 }
 
+// rollbackLocked returns a function that reverts the tasks changed since
+// snapshot was taken, for a change whose save failed, so a failed write never
+// lingers in memory to be saved by the next one. Call it before saving: tasks
+// that only external edits merged in by the save changed keep those edits.
+// Tasks are reverted in place so pointers handed out stay valid.
+// Caller must hold the write lock.
+func (s *TaskStore) rollbackLocked(snapshot map[string]*models.Task) func() {
+	changed := make(map[string]*models.Task) // nil for tasks added since the snapshot
+	for id, before := range snapshot {
+		if after, ok := s.tasks[id]; !ok || !reflect.DeepEqual(before, after) {
+			changed[id] = before
+		}
+	}
+	for id := range s.tasks {
+		if _, ok := snapshot[id]; !ok {
+			changed[id] = nil
+		}
+	}
+
+	return func() {
+		for id, before := range changed {
+			switch current, ok := s.tasks[id]; {
+			case before == nil:
+				delete(s.tasks, id)
+			case ok:
+				*current = *before
+			default:
+				s.tasks[id] = before
+			}
+		}
+		s.refreshDerivedLocked()
+	}
+}
+
 // backgroundSaver runs in a goroutine and handles file writes asynchronously.
 // This prevents blocking API responses during disk I/O.
 func (s *TaskStore) backgroundSaver() {
+	defer close(s.saverDone)
 	for range s.saveChan {
-		s.mu.RLock()
-		err := s.saveToFile()
-		s.mu.RUnlock()
+		// Merge edits made to the file on disk since our last save, so the
+		// in-memory board does not overwrite them. The lock is held until the
+		// file is written, so an edit made after the merge cannot be lost.
+		s.mu.Lock()
+		if !s.unsaved {
+			// A synchronous save has already written the changes
+			s.mu.Unlock()
+			continue
+		}
+		conflicts, _, err := s.mergeFromDiskLocked()
+		if err != nil {
+			log.Printf("Error merging external changes: %v", err)
+		}
+		err = s.saveToFile()
+		if err == nil {
+			s.unsaved = false
+		}
+		s.mu.Unlock()
+		s.notifyMergeConflicts(conflicts)
 
+		s.recordSaveResult(err)
 		if err != nil {
-			s.saveErrMu.Lock()
-			s.saveErr = err
-			s.saveErrMu.Unlock()
 			log.Printf("Error saving tasks: %v", err)
+			continue
 		}
+		s.scheduleAutoCommit()
 	}
 }
 
-// Close stops the background saver and performs a final synchronous save.
+// Close stops the background saver, saves changes it has not written yet and
+// commits any auto-commit batch still waiting. Servers call it on shutdown.
 func (s *TaskStore) Close() error {
+	s.mu.Lock()
+	s.closed = true
 	close(s.saveChan)
-	// Do one final save to ensure all changes are persisted
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	return s.saveToFile()
+	s.mu.Unlock()
+	// Wait for a save in flight, so it cannot overwrite the file after we return
+	<-s.saverDone
+
+	// Do one final save to ensure all changes are persisted, merging edits
+	// made on disk first like the background saver does
+	s.mu.Lock()
+	var err error
+	if s.unsaved {
+		if _, _, mergeErr := s.mergeFromDiskLocked(); mergeErr != nil {
+			log.Printf("Error merging external changes: %v", mergeErr)
+		}
+		if err = s.saveToFile(); err == nil {
+			s.unsaved = false
+		}
+	}
+	s.mu.Unlock()
+	s.recordSaveResult(err)
+	if err != nil {
+		return err
+	}
+
+	// Commit changes still waiting for the auto-commit batch delay
+	s.scheduleAutoCommit()
+	if err := s.flushAutoCommit(); err != nil {
+		log.Printf("Error auto-committing tasks: %v", err)
+	}
+	return nil
 }
 
-// saveToFile writes all tasks to the markdown file synchronously.
+// saveToFile writes the board to storage synchronously.
 // Caller must hold at least a read lock.
 func (s *TaskStore) saveToFile() error {
-	file, err := os.Create(s.filePath)
+	hash, err := s.storage.Save(s.boardLocked())
 	if err != nil {
 		return err
 	}
-	defer file.Close()
 
-	// Ensure we have default settings to write
-	settingsToWrite := s.settings
-	if settingsToWrite.StaleThresholdDays == 0 {
-		settingsToWrite.StaleThresholdDays = DefaultStaleThresholdDays
+	// What we just wrote becomes the base for merging later external edits
+	s.recordBase(hash)
+	return nil
+}
+
+// boardLocked returns the in-memory board as a snapshot without copying it,
+// for reading only. Caller must hold at least a read lock for as long as the
+// snapshot is used.
+func (s *TaskStore) boardLocked() *boardSnapshot {
+	return &boardSnapshot{
+		tasks:       s.tasks,
+		columns:     s.columns,
+		settings:    s.settings,
+		header:      s.header,
+		preamble:    s.preamble,
+		lineNumbers: s.taskLineNumbers,
+		includes:    s.includes,
+	}
+}
+
+// writeFileAtomic writes a file with the content produced by write and
+// returns its hash. The content is written to a temporary file in the same
+// directory, fsynced, and then renamed over the original so readers never
+// see a partial file.
+func writeFileAtomic(path string, write func(io.Writer) error) ([sha256.Size]byte, error) {
+	var hash [sha256.Size]byte
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return hash, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	renamed := false
+	defer func() {
+		if !renamed {
+			tmp.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	// Preserve the permissions of the existing file (CreateTemp uses 0600)
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		return hash, fmt.Errorf("failed to set temp file permissions: %w", err)
+	}
+
+	hasher := sha256.New()
+	buf := bufio.NewWriter(io.MultiWriter(tmp, hasher))
+	if err := write(buf); err != nil {
+		return hash, err
+	}
+	if err := buf.Flush(); err != nil {
+		return hash, fmt.Errorf("failed to write tasks file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return hash, fmt.Errorf("failed to sync tasks file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return hash, fmt.Errorf("failed to close temp file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return hash, fmt.Errorf("failed to replace tasks file: %w", err)
+	}
+	renamed = true
+	copy(hash[:], hasher.Sum(nil))
+
+	// Sync the directory so the rename itself is durable. Not every platform
+	// supports fsync on directories, so failures here are ignored.
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	return hash, nil
+}
+
+// errWriter wraps an io.Writer and remembers the first write error, so a
+// sequence of Fprintf calls can be checked once at the end.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) Write(p []byte) (int, error) {
+	if ew.err != nil {
+		return 0, ew.err
+	}
+	n, err := ew.w.Write(p)
+	if err != nil {
+		ew.err = err
+	}
+	return n, err
+}
+
+// settingsWithDefaults fills in the default for every unset setting that is
+// written out, so the file shows what can be configured
+func settingsWithDefaults(settings Settings) Settings {
+	if settings.StaleThresholdDays == 0 {
+		settings.StaleThresholdDays = DefaultStaleThresholdDays
 	}
-	if settingsToWrite.TestRunner.Command == "" {
-		settingsToWrite.TestRunner.Command = DefaultTestCommand
+	if settings.TestRunner.Command == "" {
+		settings.TestRunner.Command = DefaultTestCommand
+		settings.TestRunner.Format = DefaultTestFormat
+		settings.TestRunner.Batch = true
 	}
-	if settingsToWrite.TestRunner.PassString == "" {
-		settingsToWrite.TestRunner.PassString = DefaultPassString
+	if settings.TestRunner.PassString == "" {
+		settings.TestRunner.PassString = DefaultPassString
 	}
-	if settingsToWrite.TestRunner.FailString == "" {
-		settingsToWrite.TestRunner.FailString = DefaultFailString
+	if settings.TestRunner.FailString == "" {
+		settings.TestRunner.FailString = DefaultFailString
 	}
-	if settingsToWrite.TestRunner.NoTestsString == "" {
-		settingsToWrite.TestRunner.NoTestsString = DefaultNoTestsString
+	if settings.TestRunner.NoTestsString == "" {
+		settings.TestRunner.NoTestsString = DefaultNoTestsString
 	}
+	return settings
+}
+
+// writeMarkdownBoard writes the full markdown document (front matter, header
+// and columns) of a board to w. Tasks from included files are left out.
+func writeMarkdownBoard(w io.Writer, board *boardSnapshot) error {
+	ew := &errWriter{w: w}
 
 	// Write YAML front matter
-	fmt.Fprintln(file, "---")
+	settingsToWrite := settingsWithDefaults(board.settings)
+	fmt.Fprintln(ew, "---")
 	yamlBytes, err := yaml.Marshal(&settingsToWrite)
 	if err != nil {
 		return fmt.Errorf("failed to marshal settings: %w", err)
 	}
 	// Trim trailing newline from YAML output since we add our own
 	yamlStr := strings.TrimSuffix(string(yamlBytes), "\n")
-	fmt.Fprintln(file, yamlStr)
-	fmt.Fprintln(file, "---")
-
-	// Write header
-	fmt.Fprintln(file, "# Kantext Tasks")
-	fmt.Fprintln(file, "")
+	fmt.Fprintln(ew, yamlStr)
+	fmt.Fprintln(ew, "---")
+
+	// Write header and any content preserved before the first column
+	header := board.header
+	if header == "" {
+		header = defaultBoardHeader
+	}
+	fmt.Fprintln(ew, header)
+	fmt.Fprintln(ew, "")
+	if len(board.preamble) > 0 {
+		for _, line := range board.preamble {
+			fmt.Fprintln(ew, line)
+		}
+		fmt.Fprintln(ew, "")
+	}
 
 	// Write each column section
-	for _, col := range s.getSortedColumns() {
-		tasks := s.getTasksByColumn(models.Column(col.Slug))
-
-		fmt.Fprintf(file, "## %s\n", col.Name)
-		for _, task := range tasks {
-			s.writeTask(file, task)
+	for _, col := range sortColumns(board.columns) {
+		var tasks []*models.Task
+		for _, task := range columnTasks(board.tasks, models.Column(col.Slug)) {
+			if task.Source == "" { // Others are written back to their included file
+				tasks = append(tasks, task)
+			}
+		}
+		if col.Source != "" && len(tasks) == 0 {
+			continue // Only kept in the included file it comes from
+		}
+		if err := writeColumn(ew, col, tasks); err != nil {
+			return err
 		}
-		fmt.Fprintln(file, "")
 	}
 
 	// AI Queue is now in-memory only, not written to TASKS.md
 
+	if ew.err != nil {
+		return fmt.Errorf("failed to write tasks file: %w", ew.err)
+	}
 	return nil
 }
 
+// writeColumn writes a column section: its header, the notes under it and
+// the given tasks, each followed by the notes that followed it when read.
+// Notes whose task has left the column are written after its tasks.
+func writeColumn(w io.Writer, col models.ColumnDefinition, tasks []*models.Task) error {
+	inColumn := make(map[string]bool, len(tasks))
+	for _, task := range tasks {
+		inColumn[task.ID] = true
+	}
+	var leftover [][]string
+	for _, note := range col.Notes {
+		if !inColumn[note.After] {
+			leftover = append(leftover, note.Lines)
+		}
+	}
+
+	fmt.Fprintf(w, "## %s\n", col.Name)
+	if len(tasks) == 0 {
+		writeExtraLines(w, joinBlocks(append([][]string{col.ExtraLines}, leftover...)))
+	} else {
+		writeExtraLines(w, col.ExtraLines)
+	}
+	for i, task := range tasks {
+		if err := writeTask(w, task); err != nil {
+			return err
+		}
+		var notes [][]string
+		for _, note := range col.Notes {
+			if note.After == task.ID {
+				notes = append(notes, note.Lines)
+			}
+		}
+		if i == len(tasks)-1 {
+			notes = append(notes, leftover...)
+		}
+		writeExtraLines(w, joinBlocks(notes))
+	}
+	fmt.Fprintln(w, "")
+	return nil
+}
+
+// joinBlocks joins blocks of lines into one, separated by blank lines
+func joinBlocks(blocks [][]string) []string {
+	var lines []string
+	for _, block := range blocks {
+		if len(block) == 0 {
+			continue
+		}
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, block...)
+	}
+	return lines
+}
+
 func (s *TaskStore) getTasksByColumn(column models.Column) []*models.Task {
+	return columnTasks(s.tasks, column)
+}
+
+// columnTasks returns the tasks in a column, in file order
+func columnTasks(all map[string]*models.Task, column models.Column) []*models.Task {
 	var tasks []*models.Task
-	for _, task := range s.tasks {
+	for _, task := range all {
 		if task.Column == column {
 			tasks = append(tasks, task)
 		}
@@ -748,49 +1485,141 @@ func (s *TaskStore) getTasksByColumn(column models.Column) []*models.Task {
 	return tasks
 }
 
-func (s *TaskStore) writeTask(file *os.File, task *models.Task) {
+// writeTask writes a single task and its metadata to w. Unknown metadata is
+// written after the known key it followed when read.
+// Returns the first write error encountered, if any.
+func writeTask(w io.Writer, task *models.Task) error {
+	ew := &errWriter{w: w}
+	placed, rest := placeUnknownMetadata(task)
+	writeUnknown := func(after string) {
+		for _, line := range placed[after] {
+			fmt.Fprintln(ew, line)
+		}
+	}
+
 	// Write task title line
-	fmt.Fprintf(file, "- [%s] %s\n", task.CheckboxChar(), task.Title)
+	fmt.Fprintf(ew, "- [%s] %s\n", task.CheckboxChar(), task.Title)
+	writeUnknown("")
 
 	// Write metadata as nested bullet points
-	fmt.Fprintf(file, "  - id: %s\n", task.ID)
-	fmt.Fprintf(file, "  - priority: %s\n", task.Priority)
+	fmt.Fprintf(ew, "  - id: %s\n", task.ID)
+	writeUnknown("id")
+	fmt.Fprintf(ew, "  - priority: %s\n", task.Priority)
+	writeUnknown("priority")
+	if task.Parent != "" {
+		fmt.Fprintf(ew, "  - parent: %s\n", task.Parent)
+	}
+	writeUnknown("parent")
 
 	// Write tags as comma-separated values
 	if len(task.Tags) > 0 {
-		fmt.Fprintf(file, "  - tags: %s\n", strings.Join(task.Tags, ", "))
+		fmt.Fprintf(ew, "  - tags: %s\n", strings.Join(task.Tags, ", "))
 	}
+	writeUnknown("tags")
 
-	fmt.Fprintf(file, "  - requires_test: %t\n", task.RequiresTest)
+	fmt.Fprintf(ew, "  - requires_test: %t\n", task.RequiresTest)
+	writeUnknown("requires_test")
 
 	// Write all tests
 	for _, test := range task.Tests {
-		fmt.Fprintf(file, "  - test: %s:%s\n", test.File, test.Func)
+		fmt.Fprintf(ew, "  - test: %s\n", test)
+	}
+	writeUnknown("test")
+
+	if len(task.BlockedBy) > 0 {
+		fmt.Fprintf(ew, "  - blocked_by: %s\n", strings.Join(task.BlockedBy, ", "))
 	}
+	writeUnknown("blocked_by")
 
 	// Write test results if available
 	if task.TestsTotal > 0 {
-		fmt.Fprintf(file, "  - tests_passed: %d\n", task.TestsPassed)
-		fmt.Fprintf(file, "  - tests_total: %d\n", task.TestsTotal)
+		fmt.Fprintf(ew, "  - tests_passed: %d\n", task.TestsPassed)
+		fmt.Fprintf(ew, "  - tests_total: %d\n", task.TestsTotal)
 	}
+	writeUnknown("tests_passed")
+	writeUnknown("tests_total")
 
 	if task.AcceptanceCriteria != "" {
-		fmt.Fprintf(file, "  - criteria: %s\n", task.AcceptanceCriteria)
+		writeMetadataValue(ew, "criteria", task.AcceptanceCriteria)
+	}
+	writeUnknown("criteria")
+	if task.Description != "" {
+		writeMetadataValue(ew, "description", task.Description)
+	}
+	writeUnknown("description")
+
+	// Write checklist items as nested checkboxes
+	for _, item := range task.Checklist {
+		check := " "
+		if item.Done {
+			check = "x"
+		}
+		fmt.Fprintf(ew, "  - [%s] %s\n", check, item.Text)
 	}
 
 	// Write timestamp metadata
 	if !task.CreatedAt.IsZero() {
-		fmt.Fprintf(file, "  - created_at: %s\n", task.CreatedAt.Format("2006-01-02T15:04:05Z"))
+		fmt.Fprintf(ew, "  - created_at: %s\n", task.CreatedAt.Format("2006-01-02T15:04:05Z"))
 	}
+	writeUnknown("created_at")
 	if task.CreatedBy != "" {
-		fmt.Fprintf(file, "  - created_by: %s\n", task.CreatedBy)
+		fmt.Fprintf(ew, "  - created_by: %s\n", task.CreatedBy)
 	}
+	writeUnknown("created_by")
 	if !task.UpdatedAt.IsZero() {
-		fmt.Fprintf(file, "  - updated_at: %s\n", task.UpdatedAt.Format("2006-01-02T15:04:05Z"))
+		fmt.Fprintf(ew, "  - updated_at: %s\n", task.UpdatedAt.Format("2006-01-02T15:04:05Z"))
 	}
+	writeUnknown("updated_at")
 	if task.UpdatedBy != "" {
-		fmt.Fprintf(file, "  - updated_by: %s\n", task.UpdatedBy)
+		fmt.Fprintf(ew, "  - updated_by: %s\n", task.UpdatedBy)
 	}
+	writeUnknown("updated_by")
+
+	// Write back the remaining unknown metadata and notes exactly as they were read
+	writeExtraLines(ew, rest)
+
+	return ew.err
+}
+
+// writtenMetadataKeys are the metadata keys writeTask writes, in order
+var writtenMetadataKeys = map[string]bool{
+	"id": true, "priority": true, "parent": true, "tags": true, "requires_test": true,
+	"test": true, "blocked_by": true, "tests_passed": true, "tests_total": true,
+	"criteria": true, "description": true,
+	"created_at": true, "created_by": true, "updated_at": true, "updated_by": true,
+}
+
+// placeUnknownMetadata splits a task's extra lines into the unknown metadata
+// that can go back where it was read, keyed by the written key it followed
+// ("" for right after the title), and the rest
+func placeUnknownMetadata(task *models.Task) (map[string][]string, []string) {
+	after := make(map[string]string)
+	previous := ""
+	for _, key := range task.MetadataKeys {
+		if writtenMetadataKeys[key] {
+			previous = key
+		} else if _, seen := after[key]; !seen {
+			after[key] = previous
+		}
+	}
+
+	var placed map[string][]string
+	var rest []string
+	for _, line := range task.ExtraLines {
+		if strings.HasPrefix(line, "  - ") {
+			if matches := metadataRegex.FindStringSubmatch(line); matches != nil {
+				if key, ok := after[strings.TrimSpace(matches[1])]; ok {
+					if placed == nil {
+						placed = make(map[string][]string)
+					}
+					placed[key] = append(placed[key], line)
+					continue
+				}
+			}
+		}
+		rest = append(rest, line)
+	}
+	return placed, rest
 }
 
 func (s *TaskStore) createInitialFile() error {
@@ -798,6 +1627,11 @@ func (s *TaskStore) createInitialFile() error {
 	copy(s.columns, models.DefaultColumns)
 	s.settings = Settings{} // Initialize with defaults (will be filled on save)
 
+	// The tasks file may be configured in a directory that does not exist yet
+	if err := os.MkdirAll(filepath.Dir(s.filePath), 0755); err != nil {
+		return fmt.Errorf("failed to create tasks file directory: %w", err)
+	}
+
 	// Use saveLocked to write the file with proper default settings
 	return s.saveLocked()
 }
@@ -859,6 +1693,8 @@ func (s *TaskStore) UpdateColumn(slug string, newName string) (*models.ColumnDef
 			}
 
 			// Update column
+			previousColumns := append([]models.ColumnDefinition(nil), s.columns...)
+			snapshot := s.snapshotTasksLocked()
 			s.columns[i].Name = newName
 			s.columns[i].Slug = newSlug
 
@@ -870,7 +1706,10 @@ func (s *TaskStore) UpdateColumn(slug string, newName string) (*models.ColumnDef
 			}
 
 			// Save to file
+			rollback := s.rollbackLocked(snapshot)
 			if err := s.saveLocked(); err != nil {
+				s.columns = previousColumns
+				rollback()
 				return nil, err
 			}
 
@@ -917,10 +1756,15 @@ func (s *TaskStore) DeleteColumn(slug string) error {
 	}
 
 	// Remove column
-	s.columns = append(s.columns[:idx], s.columns[idx+1:]...)
+	previousColumns := append([]models.ColumnDefinition(nil), s.columns...)
+	s.columns = append(s.columns[:idx:idx], s.columns[idx+1:]...)
 
 	// Save to file
-	return s.saveLocked()
+	if err := s.saveLocked(); err != nil {
+		s.columns = previousColumns
+		return err
+	}
+	return nil
 }
 
 // ReorderColumns sets the order of columns
@@ -945,6 +1789,7 @@ func (s *TaskStore) ReorderColumns(slugs []string) error {
 	}
 
 	// Update order
+	previousColumns := append([]models.ColumnDefinition(nil), s.columns...)
 	for i, slug := range slugs {
 		for j := range s.columns {
 			if s.columns[j].Slug == slug {
@@ -955,7 +1800,11 @@ func (s *TaskStore) ReorderColumns(slugs []string) error {
 	}
 
 	// Save to file
-	return s.saveLocked()
+	if err := s.saveLocked(); err != nil {
+		s.columns = previousColumns
+		return err
+	}
+	return nil
 }
 
 // GetAll returns all tasks in file order
@@ -963,9 +1812,6 @@ func (s *TaskStore) GetAll() []*models.Task {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	// Refresh git blame data to pick up any new commits
-	s.refreshGitBlame()
-
 	tasks := make([]*models.Task, 0, len(s.tasks))
 	for _, task := range s.tasks {
 		tasks = append(tasks, task)
@@ -1011,11 +1857,24 @@ func (s *TaskStore) Create(req models.CreateTaskRequest) (*models.Task, error) {
 		column = models.Column(firstCol.Slug)
 	}
 
+	blockedBy, err := s.validateBlockedByLocked("", req.BlockedBy)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateParentLocked("", req.Parent); err != nil {
+		return nil, err
+	}
+
+	snapshot := s.snapshotTasksLocked()
 	now := time.Now().UTC()
 	task := &models.Task{
 		ID:                 generateShortID(),
 		Title:              req.Title,
-		AcceptanceCriteria: req.AcceptanceCriteria,
+		AcceptanceCriteria: normalizeText(req.AcceptanceCriteria),
+		Description:        normalizeText(req.Description),
+		Checklist:          normalizeChecklist(req.Checklist),
+		BlockedBy:          blockedBy,
+		Parent:             req.Parent,
 		Priority:           priority,
 		Tags:               req.Tags,
 		RequiresTest:       requiresTest,
@@ -1028,12 +1887,22 @@ func (s *TaskStore) Create(req models.CreateTaskRequest) (*models.Task, error) {
 	}
 
 	s.tasks[task.ID] = task
+	undo := s.undoEntryLocked(fmt.Sprintf("Create %q", task.Title), snapshot)
 
 	// Save to file
+	rollback := s.rollbackLocked(snapshot)
 	if err := s.saveLocked(); err != nil {
-		delete(s.tasks, task.ID)
+		rollback()
 		return nil, err
 	}
+	s.pushUndoLocked(undo)
+
+	s.recordHistory(models.HistoryEvent{
+		TaskID:  task.ID,
+		Type:    models.HistoryCreated,
+		Author:  req.Author,
+		Summary: task.Title,
+	})
 
 	return task, nil
 }
@@ -1048,11 +1917,42 @@ func (s *TaskStore) Update(id string, req models.UpdateTaskRequest) (*models.Tas
 		return nil, fmt.Errorf("task not found: %s", id)
 	}
 
+	// Reject the update if the task changed since the caller last read it
+	if req.ExpectedRevision != "" {
+		if current := task.Revision(); current != req.ExpectedRevision {
+			return nil, fmt.Errorf("%w: task %s is at revision %s, expected %s", ErrRevisionConflict, id, current, req.ExpectedRevision)
+		}
+	}
+
+	// Validate dependencies before changing anything
+	blockedBy := task.BlockedBy
+	if req.BlockedBy != nil {
+		var err error
+		if blockedBy, err = s.validateBlockedByLocked(id, req.BlockedBy); err != nil {
+			return nil, err
+		}
+	}
+	if req.Column != nil {
+		if err := s.checkCanMoveLocked(task, *req.Column, blockedBy); err != nil {
+			return nil, err
+		}
+	}
+	if req.Parent != nil {
+		if err := s.validateParentLocked(id, *req.Parent); err != nil {
+			return nil, err
+		}
+	}
+
+	snapshot := s.snapshotTasksLocked()
+	before := snapshot[id]
 	if req.Title != nil {
 		task.Title = *req.Title
 	}
 	if req.AcceptanceCriteria != nil {
-		task.AcceptanceCriteria = *req.AcceptanceCriteria
+		task.AcceptanceCriteria = normalizeText(*req.AcceptanceCriteria)
+	}
+	if req.Description != nil {
+		task.Description = normalizeText(*req.Description)
 	}
 	if req.Priority != nil {
 		task.Priority = *req.Priority
@@ -1069,34 +1969,89 @@ func (s *TaskStore) Update(id string, req models.UpdateTaskRequest) (*models.Tas
 	if req.Tests != nil {
 		task.Tests = req.Tests
 	}
+	if req.Checklist != nil {
+		task.Checklist = normalizeChecklist(req.Checklist)
+	}
+	if req.BlockedBy != nil {
+		task.BlockedBy = blockedBy
+	}
+	if req.Parent != nil {
+		task.Parent = *req.Parent
+	}
 
 	// Update timestamp metadata
 	task.UpdatedAt = time.Now().UTC()
 	if req.Author != "" {
 		task.UpdatedBy = req.Author
 	}
+	undo := s.undoEntryLocked(updateDescription(before, task), snapshot)
 
 	// Save to file
+	rollback := s.rollbackLocked(snapshot)
 	if err := s.saveLocked(); err != nil {
+		rollback()
 		return nil, err
 	}
+	s.pushUndoLocked(undo)
+
+	s.recordHistory(taskChangeEvents(before, task, req.Author)...)
 
 	return task, nil
 }
 
-// Delete removes a task
+// Delete removes a task from the board and moves it to the archive, from
+// where it can be restored until it is purged
 func (s *TaskStore) Delete(id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, ok := s.tasks[id]; !ok {
+	task, ok := s.tasks[id]
+	if !ok {
 		return fmt.Errorf("task not found: %s", id)
 	}
 
+	// Write the archive first so a failure never loses the task
+	if err := s.loadArchiveLocked(); err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+	s.archive[id] = archivedCopy(task, time.Now().UTC())
+	if err := s.saveArchiveLocked(); err != nil {
+		delete(s.archive, id)
+		return fmt.Errorf("%w: %v", ErrSaveFailed, err)
+	}
+
+	snapshot := s.snapshotTasksLocked()
 	delete(s.tasks, id)
 
-	// Save to file
-	return s.saveLocked()
+	// Tasks waiting on the deleted task are no longer blocked by it
+	s.removeDependencyLocked(id)
+
+	// Its subtasks move up a level rather than pointing at a missing parent
+	s.reparentChildrenLocked(task)
+	undo := s.undoEntryLocked(fmt.Sprintf("Delete %q", task.Title), snapshot)
+
+	// Save to file, taking the task back out of the archive if that fails
+	rollback := s.rollbackLocked(snapshot)
+	if err := s.saveLocked(); err != nil {
+		rollback()
+		delete(s.archive, id)
+		if archiveErr := s.saveArchiveLocked(); archiveErr != nil {
+			log.Printf("Error saving task archive: %v", archiveErr)
+		}
+		return err
+	}
+	if undo != nil {
+		undo.archive = true
+	}
+	s.pushUndoLocked(undo)
+
+	s.recordHistory(models.HistoryEvent{
+		TaskID:  id,
+		Type:    models.HistoryArchived,
+		From:    string(task.Column),
+		Summary: task.Title,
+	})
+	return nil
 }
 
 // UpdateTestResult updates a task's test status and output (for single test)
@@ -1109,6 +2064,8 @@ func (s *TaskStore) UpdateTestResult(id string, result models.TestResult) (*mode
 		return nil, fmt.Errorf("task not found: %s", id)
 	}
 
+	snapshot := s.snapshotTasksLocked()
+	previousColumn := task.Column
 	if result.Passed {
 		task.TestStatus = models.TestStatusPassed
 		// Auto-move to last column on pass
@@ -1122,10 +2079,14 @@ func (s *TaskStore) UpdateTestResult(id string, result models.TestResult) (*mode
 	task.LastOutput = result.Output
 
 	// Save to file
+	rollback := s.rollbackLocked(snapshot)
 	if err := s.saveLocked(); err != nil {
+		rollback()
 		return nil, err
 	}
 
+	s.recordHistory(testRunEvents(task, previousColumn, fmt.Sprintf("Test %s", task.TestStatus))...)
+
 	return task, nil
 }
 
@@ -1140,6 +2101,7 @@ func (s *TaskStore) UpdateTestResults(id string, results models.TestResults) (*m
 	}
 
 	// Calculate tests passed/total
+	snapshot := s.snapshotTasksLocked()
 	passed := 0
 	for _, r := range results.Results {
 		if r.Passed {
@@ -1149,6 +2111,7 @@ func (s *TaskStore) UpdateTestResults(id string, results models.TestResults) (*m
 	task.TestsPassed = passed
 	task.TestsTotal = len(results.Results)
 
+	previousColumn := task.Column
 	if results.AllPassed {
 		task.TestStatus = models.TestStatusPassed
 		// Auto-move to last column on pass
@@ -1163,7 +2126,7 @@ func (s *TaskStore) UpdateTestResults(id string, results models.TestResults) (*m
 	var outputs []string
 	for i, result := range results.Results {
 		if len(task.Tests) > i {
-			outputs = append(outputs, fmt.Sprintf("=== %s:%s ===\n%s", task.Tests[i].File, task.Tests[i].Func, result.Output))
+			outputs = append(outputs, fmt.Sprintf("=== %s ===\n%s", task.Tests[i], result.Output))
 		} else {
 			outputs = append(outputs, result.Output)
 		}
@@ -1171,10 +2134,15 @@ func (s *TaskStore) UpdateTestResults(id string, results models.TestResults) (*m
 	task.LastOutput = strings.Join(outputs, "\n\n")
 
 	// Save to file
+	rollback := s.rollbackLocked(snapshot)
 	if err := s.saveLocked(); err != nil {
+		rollback()
 		return nil, err
 	}
 
+	s.recordHistory(testRunEvents(task, previousColumn, fmt.Sprintf("%d/%d tests passed", task.TestsPassed, task.TestsTotal))...)
+	s.recordTestRuns(task, results)
+
 	return task, nil
 }
 
@@ -1192,6 +2160,23 @@ func (s *TaskStore) SetTestRunning(id string) error {
 	return nil
 }
 
+// RestoreTestStatus puts back a task's test status after a test run was
+// canceled, unless something else has changed it since the run started
+func (s *TaskStore) RestoreTestStatus(id string, status models.TestStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.tasks[id]
+	if !ok {
+		return fmt.Errorf("task not found: %s", id)
+	}
+
+	if task.TestStatus == models.TestStatusRunning {
+		task.TestStatus = status
+	}
+	return nil
+}
+
 // Reorder moves a task to a specific position within a column
 func (s *TaskStore) Reorder(id string, column models.Column, position int) (*models.Task, error) {
 	s.mu.Lock()
@@ -1202,7 +2187,14 @@ func (s *TaskStore) Reorder(id string, column models.Column, position int) (*mod
 		return nil, fmt.Errorf("task not found: %s", id)
 	}
 
+	// Blocked tasks can't be started
+	if err := s.checkCanMoveLocked(task, column, task.BlockedBy); err != nil {
+		return nil, err
+	}
+
 	// Update the column and timestamp
+	snapshot := s.snapshotTasksLocked()
+	previousColumn := task.Column
 	task.Column = column
 	task.UpdatedAt = time.Now().UTC()
 
@@ -1245,103 +2237,26 @@ func (s *TaskStore) Reorder(id string, column models.Column, position int) (*mod
 		}
 	}
 	task.Order = baseOrder + position
+	undo := s.undoEntryLocked(fmt.Sprintf("Move %q to %s", task.Title, column), snapshot)
 
 	// Save to file
+	rollback := s.rollbackLocked(snapshot)
 	if err := s.saveLocked(); err != nil {
+		rollback()
 		return nil, err
 	}
+	s.pushUndoLocked(undo)
 
-	return task, nil
-}
-
-// refreshGitBlame updates task author information from git blame.
-// It searches for each task's ID in the blame output to find the correct author,
-// which handles cases where line numbers shift due to added/removed tasks.
-func (s *TaskStore) refreshGitBlame() {
-	// Get blame data with line content
-	blameData := s.getGitBlameWithContent()
-	if len(blameData) == 0 {
-		return // Git blame not available
-	}
-
-	// Build a map of task ID -> author by searching for ID lines in blame output
-	taskAuthors := make(map[string]string)
-	for _, entry := range blameData {
-		// Look for lines like "  - id: <uuid>"
-		if strings.Contains(entry.Content, "  - id: ") {
-			// Extract the ID from the line
-			parts := strings.SplitN(entry.Content, "  - id: ", 2)
-			if len(parts) == 2 {
-				id := strings.TrimSpace(parts[1])
-				taskAuthors[id] = entry.Author
-			}
-		}
-	}
-
-	// Update each task's author based on the blame data
-	for taskID, author := range taskAuthors {
-		if task, exists := s.tasks[taskID]; exists {
-			task.UpdatedBy = author
-			task.CreatedBy = author
-		}
-	}
-}
-
-// BlameEntry represents a single line from git blame output
-type BlameEntry struct {
-	LineNum int
-	Author  string
-	Content string
-}
-
-// getGitBlameWithContent runs git blame on HEAD and returns entries with author and content.
-// Using HEAD ignores uncommitted changes, so we only see the last committed author.
-// This allows us to search for specific content (like task IDs) regardless of line numbers.
-func (s *TaskStore) getGitBlameWithContent() []BlameEntry {
-	var entries []BlameEntry
-
-	dir := filepath.Dir(s.filePath)
-	// Use HEAD to only look at committed changes, not working directory
-	cmd := exec.Command("git", "blame", "--porcelain", "HEAD", "--", s.filePath)
-	cmd.Dir = dir
-
-	output, err := cmd.Output()
-	if err != nil {
-		return entries
+	if column != previousColumn {
+		s.recordHistory(models.HistoryEvent{
+			TaskID: id,
+			Type:   models.HistoryMoved,
+			From:   string(previousColumn),
+			To:     string(column),
+		})
 	}
 
-	lines := strings.Split(string(output), "\n")
-	var currentLine int
-	var currentAuthor string
-
-	for i := 0; i < len(lines); i++ {
-		line := lines[i]
-
-		// SHA line starts a new blame entry
-		if len(line) >= 40 && !strings.HasPrefix(line, "\t") && !strings.Contains(line[:40], " ") {
-			parts := strings.Fields(line)
-			if len(parts) >= 3 {
-				fmt.Sscanf(parts[2], "%d", &currentLine)
-			}
-		}
-
-		// Author line
-		if strings.HasPrefix(line, "author ") {
-			currentAuthor = strings.TrimPrefix(line, "author ")
-		}
-
-		// Content line (starts with tab)
-		if strings.HasPrefix(line, "\t") && currentLine > 0 {
-			content := strings.TrimPrefix(line, "\t")
-			entries = append(entries, BlameEntry{
-				LineNum: currentLine,
-				Author:  currentAuthor,
-				Content: content,
-			})
-		}
-	}
-
-	return entries
+	return task, nil
 }
 
 // ===== AI Queue Management =====
@@ -1504,11 +2419,40 @@ func (s *TaskStore) StartNextTask() (string, error) {
 		return "", fmt.Errorf("already working on a task")
 	}
 
-	taskID := s.aiQueue[0]
+	// Pick the first queued task that isn't waiting on another task
+	next := -1
+	for i, id := range s.aiQueue {
+		task, ok := s.tasks[id]
+		if !ok || len(s.unfinishedBlockersLocked(task.BlockedBy)) == 0 {
+			next = i
+			break
+		}
+	}
+	if next < 0 {
+		return "", fmt.Errorf("%w: every queued task is waiting on another task", ErrTaskBlocked)
+	}
+
+	// The active task is always at the front of the queue
+	snapshot := s.snapshotTasksLocked()
+	previousQueue := append([]string(nil), s.aiQueue...)
+	taskID := s.aiQueue[next]
+	if next > 0 {
+		copy(s.aiQueue[1:next+1], s.aiQueue[:next])
+		s.aiQueue[0] = taskID
+	}
 	s.activeTaskID = taskID
 
 	// Move task to in_progress column (this needs to be persisted)
+	events := []models.HistoryEvent{{TaskID: taskID, Type: models.HistoryAIStarted}}
 	if task, ok := s.tasks[taskID]; ok {
+		if task.Column != models.ColumnInProgress {
+			events = append(events, models.HistoryEvent{
+				TaskID: taskID,
+				Type:   models.HistoryMoved,
+				From:   string(task.Column),
+				To:     string(models.ColumnInProgress),
+			})
+		}
 		task.Column = models.Column("in_progress")
 		task.UpdatedAt = time.Now().UTC()
 	}
@@ -1521,10 +2465,17 @@ func (s *TaskStore) StartNextTask() (string, error) {
 	}
 
 	// Save to persist the task column change
+	rollback := s.rollbackLocked(snapshot)
 	if err := s.saveLocked(); err != nil {
+		rollback()
+		s.aiQueue = previousQueue
+		s.activeTaskID = ""
+		s.aiSession = nil
 		return "", err
 	}
 
+	s.recordHistory(events...)
+
 	return taskID, nil
 }
 
@@ -1546,6 +2497,8 @@ func (s *TaskStore) StopCurrentTask() error {
 		}
 	}
 
+	s.recordHistory(models.HistoryEvent{TaskID: s.activeTaskID, Type: models.HistoryAIStopped})
+
 	s.activeTaskID = ""
 	s.aiSession = nil
 
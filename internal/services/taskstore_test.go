@@ -1,6 +1,7 @@
 package services
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
@@ -410,6 +411,46 @@ stale_threshold_days: 7
 	}
 }
 
+func TestTaskStore_Update_ExpectedRevision(t *testing.T) {
+	content := `---
+stale_threshold_days: 7
+---
+# Kantext Tasks
+
+## Inbox
+
+- [ ] Shared task
+  - id: task-rev01
+`
+	store, cleanup := setupTaskStoreEnv(t, content)
+	defer cleanup()
+
+	task, _ := store.Get("task-rev01")
+	revision := task.Revision()
+
+	// First writer succeeds with the current revision
+	title := "First writer"
+	updated, err := store.Update("task-rev01", models.UpdateTaskRequest{Title: &title, ExpectedRevision: revision})
+	if err != nil {
+		t.Fatalf("Update with current revision failed: %v", err)
+	}
+	if updated.Revision() == revision {
+		t.Error("Expected revision to change after update")
+	}
+
+	// Second writer with the stale revision is rejected
+	stale := "Second writer"
+	_, err = store.Update("task-rev01", models.UpdateTaskRequest{Title: &stale, ExpectedRevision: revision})
+	if !errors.Is(err, ErrRevisionConflict) {
+		t.Fatalf("Expected ErrRevisionConflict, got %v", err)
+	}
+
+	current, _ := store.Get("task-rev01")
+	if current.Title != "First writer" {
+		t.Errorf("Expected first writer's title to be kept, got %q", current.Title)
+	}
+}
+
 func TestTaskStore_Update_NotFound(t *testing.T) {
 	content := `---
 stale_threshold_days: 7
@@ -520,6 +561,10 @@ test_runner:
 	if settings.GetFailString() != "failed" {
 		t.Errorf("Expected fail_string 'failed', got %q", settings.GetFailString())
 	}
+	if settings.GetTestFormat() != "" || settings.GetTestBatch() {
+		t.Errorf("Expected a custom command to match pass/fail strings one test at a time, got format %q and batch %t",
+			settings.GetTestFormat(), settings.GetTestBatch())
+	}
 }
 
 func TestTaskStore_Load_WithTestSpecs(t *testing.T) {
@@ -806,6 +851,49 @@ func TestTaskStore_Settings_Defaults(t *testing.T) {
 	if settings.GetNoTestsString() != DefaultNoTestsString {
 		t.Errorf("Expected default no tests string, got %q", settings.GetNoTestsString())
 	}
+	if settings.GetTestFormat() != TestFormatGoJSON || !settings.GetTestBatch() {
+		t.Errorf("Expected the default runner to batch go-json runs, got format %q and batch %t",
+			settings.GetTestFormat(), settings.GetTestBatch())
+	}
+}
+
+func TestTaskStore_Settings_ExistingCommandKept(t *testing.T) {
+	content := `---
+stale_threshold_days: 7
+test_runner:
+    command: go test -v -count=1 -run ^{testFunc}$ {testPath}
+    pass_string: PASS
+    fail_string: FAIL
+    no_tests_string: no tests to run
+---
+# Kantext Tasks
+
+## Inbox
+
+## In Progress
+
+## Done
+
+`
+	store, cleanup := setupTaskStoreEnv(t, content)
+	defer cleanup()
+
+	// A board with a command keeps it, and its pass/fail strings, unbatched
+	settings := store.GetSettings()
+	if settings.GetTestCommand() != "go test -v -count=1 -run ^{testFunc}$ {testPath}" || settings.GetTestFormat() != "" || settings.GetTestBatch() {
+		t.Errorf("Expected the board's own runner, got command %q, format %q and batch %t",
+			settings.GetTestCommand(), settings.GetTestFormat(), settings.GetTestBatch())
+	}
+
+	// Loading it does not rewrite the file
+	time.Sleep(100 * time.Millisecond)
+	data, err := os.ReadFile(store.filePath)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if string(data) != content {
+		t.Errorf("Expected the file to be left alone, got:\n%s", data)
+	}
 }
 
 func TestTaskStore_WorkingDir(t *testing.T) {
@@ -917,6 +1005,447 @@ stale_threshold_days: 7
 	}
 }
 
+func TestTaskStore_SaveToFile_Atomic(t *testing.T) {
+	content := `---
+stale_threshold_days: 7
+---
+# Kantext Tasks
+
+## Inbox
+
+- [ ] Existing task
+  - id: task-atomic01
+`
+	store, cleanup := setupTaskStoreEnv(t, content)
+	defer cleanup()
+
+	tasksPath := filepath.Join(store.GetWorkingDir(), "TASKS.md")
+	if err := os.Chmod(tasksPath, 0640); err != nil {
+		t.Fatalf("Chmod failed: %v", err)
+	}
+
+	// Wait for async saves from Load to finish, then save while holding
+	// the write lock so the background saver cannot run concurrently
+	time.Sleep(100 * time.Millisecond)
+	store.mu.Lock()
+	err := store.saveToFile()
+	store.mu.Unlock()
+	if err != nil {
+		t.Fatalf("saveToFile failed: %v", err)
+	}
+
+	// No temp files should be left behind
+	entries, err := os.ReadDir(store.GetWorkingDir())
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	for _, entry := range entries {
+		if entry.Name() != "TASKS.md" {
+			t.Errorf("Unexpected file left in directory: %s", entry.Name())
+		}
+	}
+
+	info, err := os.Stat(tasksPath)
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Errorf("Expected permissions 0640 to be preserved, got %o", info.Mode().Perm())
+	}
+
+	data, err := os.ReadFile(tasksPath)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if !strings.Contains(string(data), "  - id: task-atomic01") {
+		t.Errorf("Expected saved file to contain task, got:\n%s", data)
+	}
+}
+
+func TestTaskStore_SaveToFile_MissingDirectory(t *testing.T) {
+	content := `---
+stale_threshold_days: 7
+---
+# Kantext Tasks
+
+## Inbox
+`
+	store, cleanup := setupTaskStoreEnv(t, content)
+	defer cleanup()
+
+	store.mu.Lock()
+	store.filePath = filepath.Join(store.GetWorkingDir(), "missing", "TASKS.md")
+	store.storage = newStorage(store.filePath)
+	err := store.saveToFile()
+	store.mu.Unlock()
+
+	if err == nil {
+		t.Error("Expected error when saving into a missing directory")
+	}
+}
+
+// failingWriter fails every write after the first limit bytes
+type failingWriter struct {
+	limit   int
+	written int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.written+len(p) > w.limit {
+		return 0, os.ErrClosed
+	}
+	w.written += len(p)
+	return len(p), nil
+}
+
+func TestTaskStore_WriteTask_ReportsWriteErrors(t *testing.T) {
+	task := &models.Task{
+		ID:                 "task-write01",
+		Title:              "Write error task",
+		Priority:           models.PriorityMedium,
+		AcceptanceCriteria: "Should not be silently dropped",
+	}
+
+	// Fail partway through the metadata lines
+	if err := writeTask(&failingWriter{limit: 40}, task); err == nil {
+		t.Error("Expected writeTask to return the write error")
+	}
+
+	// A writer with enough room succeeds
+	if err := writeTask(&failingWriter{limit: 4096}, task); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+func TestTaskStore_DurableWrites_SavesBeforeReturning(t *testing.T) {
+	content := `---
+stale_threshold_days: 7
+---
+# Kantext Tasks
+
+## Inbox
+`
+	store, cleanup := setupTaskStoreEnv(t, content)
+	defer cleanup()
+
+	store.SetDurableWrites(true)
+
+	task, err := store.Create(models.CreateTaskRequest{Title: "Durable Task"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// No sleep: the file must already contain the task
+	data, err := os.ReadFile(filepath.Join(store.GetWorkingDir(), "TASKS.md"))
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if !strings.Contains(string(data), "  - id: "+task.ID) {
+		t.Errorf("Expected task to be on disk immediately, got:\n%s", data)
+	}
+
+	status := store.GetSaveStatus()
+	if !status.Healthy() {
+		t.Errorf("Expected healthy save status, got error %q", status.LastError)
+	}
+	if !status.Durable {
+		t.Error("Expected status to report durable mode")
+	}
+	if status.LastSavedAt == nil {
+		t.Error("Expected last saved time to be set")
+	}
+}
+
+func TestTaskStore_DurableWrites_ReportsSaveFailure(t *testing.T) {
+	content := `---
+stale_threshold_days: 7
+---
+# Kantext Tasks
+
+## Inbox
+
+- [ ] Saved Task
+  - id: task-saved001
+  - priority: medium
+  - acceptance: Stays as it is
+
+- [ ] Other Task
+  - id: task-saved002
+  - priority: low
+  - acceptance: Stays first
+
+## In Progress
+
+## Done
+`
+	store, cleanup := setupTaskStoreEnv(t, content)
+	defer cleanup()
+
+	store.SetDurableWrites(true)
+	store.mu.Lock()
+	store.filePath = filepath.Join(store.GetWorkingDir(), "missing", "TASKS.md")
+	store.storage = newStorage(store.filePath)
+	store.mu.Unlock()
+
+	_, err := store.Create(models.CreateTaskRequest{Title: "Unsaved Task"})
+	if !errors.Is(err, ErrSaveFailed) {
+		t.Fatalf("Expected ErrSaveFailed, got %v", err)
+	}
+
+	if len(store.GetAll()) != 2 {
+		t.Error("Expected failed create to be rolled back")
+	}
+
+	status := store.GetSaveStatus()
+	if status.Healthy() {
+		t.Error("Expected unhealthy save status after failed save")
+	}
+	if status.LastErrorAt == nil {
+		t.Error("Expected last error time to be set")
+	}
+
+	// Failed updates, deletes and moves are rolled back too, so the next
+	// successful save cannot write them
+	title := "Unsaved Title"
+	if _, err := store.Update("task-saved001", models.UpdateTaskRequest{Title: &title}); !errors.Is(err, ErrSaveFailed) {
+		t.Errorf("Expected ErrSaveFailed from Update, got %v", err)
+	}
+	if task, _ := store.Get("task-saved001"); task.Title != "Saved Task" {
+		t.Errorf("Expected failed update to be rolled back, got title %q", task.Title)
+	}
+
+	if _, err := store.Reorder("task-saved002", models.ColumnInProgress, 0); !errors.Is(err, ErrSaveFailed) {
+		t.Errorf("Expected ErrSaveFailed from Reorder, got %v", err)
+	}
+	if task, _ := store.Get("task-saved002"); task.Column != models.ColumnInbox {
+		t.Errorf("Expected failed move to be rolled back, got column %s", task.Column)
+	}
+
+	if err := store.Delete("task-saved001"); !errors.Is(err, ErrSaveFailed) {
+		t.Errorf("Expected ErrSaveFailed from Delete, got %v", err)
+	}
+	if _, err := store.Get("task-saved001"); err != nil {
+		t.Errorf("Expected failed delete to be rolled back, got %v", err)
+	}
+	if archived, _ := store.GetArchive(); len(archived) != 0 {
+		t.Errorf("Expected failed delete to leave the archive empty, got %d tasks", len(archived))
+	}
+
+	// None of the failed changes can be undone or appear in history
+	if _, err := store.Undo(); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("Expected nothing to undo after failed saves, got %v", err)
+	}
+	if events, _ := store.GetHistory("task-saved001"); len(events) != 0 {
+		t.Errorf("Expected no history for failed changes, got %+v", events)
+	}
+}
+
+func TestTaskStore_Save_PreservesUnknownContent(t *testing.T) {
+	content := `---
+stale_threshold_days: 7
+---
+# Project Board
+
+Design notes live in this file.
+<!-- do not remove: parsed by release tooling -->
+
+## Inbox
+Triage new requests here every Monday.
+
+- [ ] Task with extras
+  - id: task-extra01
+  - priority: high
+  - estimate: 3d
+  - owner_team: platform
+
+  Follow-up paragraph about the task.
+
+## Done
+`
+	store, cleanup := setupTaskStoreEnv(t, content)
+	defer cleanup()
+
+	task, err := store.Get("task-extra01")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if task.Priority != models.PriorityHigh {
+		t.Errorf("Expected priority 'high', got %q", task.Priority)
+	}
+
+	// Force a rewrite of the file
+	title := "Task with extras (edited)"
+	if _, err := store.Update("task-extra01", models.UpdateTaskRequest{Title: &title}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	data, err := os.ReadFile(filepath.Join(store.GetWorkingDir(), "TASKS.md"))
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	saved := string(data)
+
+	expected := []string{
+		"# Project Board\n\nDesign notes live in this file.\n<!-- do not remove: parsed by release tooling -->\n\n## Inbox\n",
+		"## Inbox\n\nTriage new requests here every Monday.\n\n- [ ] Task with extras (edited)\n",
+		"  - priority: high\n  - estimate: 3d\n  - owner_team: platform\n  - requires_test: false\n",
+		"Z\n\n  Follow-up paragraph about the task.\n",
+	}
+	for _, want := range expected {
+		if !strings.Contains(saved, want) {
+			t.Errorf("Expected saved file to contain %q, got:\n%s", want, saved)
+		}
+	}
+	if strings.Contains(saved, "# Kantext Tasks") {
+		t.Errorf("Expected custom header to replace the default, got:\n%s", saved)
+	}
+
+	// A second load and save must be stable (no growing blank lines)
+	store2 := NewTaskStore(store.GetWorkingDir())
+	defer store2.Close()
+	store2.mu.Lock()
+	err = store2.saveToFile()
+	store2.mu.Unlock()
+	if err != nil {
+		t.Fatalf("saveToFile failed: %v", err)
+	}
+	data2, err := os.ReadFile(filepath.Join(store.GetWorkingDir(), "TASKS.md"))
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if string(data2) != saved {
+		t.Errorf("Expected stable round trip, got:\n%s\nwant:\n%s", data2, saved)
+	}
+}
+
+func TestTaskStore_Save_KeepsColumnNotesInPlace(t *testing.T) {
+	content := `# Kantext Tasks
+
+## Inbox
+- [ ] First task
+  - id: task-note0001
+  - priority: low
+  - estimate: 3
+  - requires_test: false
+  - created_at: 2026-01-02T10:00:00Z
+  - updated_at: 2026-01-02T10:00:00Z
+
+Note after the first task
+
+- [ ] Second task
+  - id: task-note0002
+  - priority: low
+  - requires_test: false
+
+Column trailing note
+
+## In Progress
+
+## Done
+`
+	store, cleanup := setupTaskStoreEnv(t, content)
+	defer cleanup()
+	store.SetDurableWrites(true)
+
+	task, _ := store.Get("task-note0002")
+	if len(task.ExtraLines) != 0 {
+		t.Errorf("Expected notes after a blank line to belong to the column, got %q", task.ExtraLines)
+	}
+
+	// Moving and archiving tasks leaves the notes in their column
+	if _, err := store.Reorder("task-note0002", models.ColumnDone, 0); err != nil {
+		t.Fatalf("Reorder failed: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(store.GetWorkingDir(), "TASKS.md"))
+	inbox := string(data)[strings.Index(string(data), "## Inbox"):strings.Index(string(data), "## In Progress")]
+	for _, want := range []string{
+		"  - priority: low\n  - estimate: 3\n  - requires_test: false\n",
+		"  - updated_at: 2026-01-02T10:00:00Z\n\nNote after the first task\n\nColumn trailing note\n",
+	} {
+		if !strings.Contains(inbox, want) {
+			t.Errorf("Expected the Inbox to contain %q, got:\n%s", want, inbox)
+		}
+	}
+
+	if err := store.Delete("task-note0001"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	data, _ = os.ReadFile(filepath.Join(store.GetWorkingDir(), "TASKS.md"))
+	inbox = string(data)[strings.Index(string(data), "## Inbox"):strings.Index(string(data), "## In Progress")]
+	if want := "## Inbox\n\nNote after the first task\n\nColumn trailing note\n"; !strings.HasPrefix(inbox, want) {
+		t.Errorf("Expected the notes to stay in the Inbox, got:\n%s", data)
+	}
+}
+
+func TestTaskStore_MultiLineCriteriaAndDescription_RoundTrip(t *testing.T) {
+	store, cleanup := setupTaskStoreEnv(t, "")
+	defer cleanup()
+
+	criteria := "Given a logged-in user:\n- [ ] the dashboard loads\n- [x] the menu shows their name\n\n```go\nfunc TestLogin(t *testing.T) {\n\tt.Skip()\n}\n```"
+	description := "Background:\n\n    indented code sample\nLast line"
+
+	task, err := store.Create(models.CreateTaskRequest{
+		Title:              "Multi-line task",
+		AcceptanceCriteria: criteria + "\r\n",
+		Description:        description,
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if task.AcceptanceCriteria != criteria {
+		t.Errorf("Expected criteria to be normalized, got %q", task.AcceptanceCriteria)
+	}
+	other, err := store.Create(models.CreateTaskRequest{Title: "Pipe criteria", AcceptanceCriteria: "|"})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	revision := task.Revision()
+
+	store.mu.Lock()
+	err = store.saveToFile()
+	store.mu.Unlock()
+	if err != nil {
+		t.Fatalf("saveToFile failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(store.GetWorkingDir(), "TASKS.md"))
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if !strings.Contains(string(data), "  - criteria: |\n    Given a logged-in user:\n    - [ ] the dashboard loads\n") {
+		t.Errorf("Expected criteria to be written as an indented block, got:\n%s", data)
+	}
+
+	store2 := NewTaskStore(store.GetWorkingDir())
+	defer store2.Close()
+
+	reloaded, err := store2.Get(task.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if reloaded.AcceptanceCriteria != criteria {
+		t.Errorf("Criteria did not round-trip:\ngot  %q\nwant %q", reloaded.AcceptanceCriteria, criteria)
+	}
+	if reloaded.Description != description {
+		t.Errorf("Description did not round-trip:\ngot  %q\nwant %q", reloaded.Description, description)
+	}
+	if len(reloaded.ExtraLines) != 0 {
+		t.Errorf("Expected no extra lines, got %q", reloaded.ExtraLines)
+	}
+	if reloaded.Revision() != revision {
+		t.Errorf("Expected revision %s after reload, got %s", revision, reloaded.Revision())
+	}
+
+	reloadedOther, err := store2.Get(other.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if reloadedOther.AcceptanceCriteria != "|" {
+		t.Errorf("Expected criteria %q, got %q", "|", reloadedOther.AcceptanceCriteria)
+	}
+}
+
 // ============================================================================
 // Test Status Tests
 // ============================================================================
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"kantext/internal/models"
+)
+
+// testHistoryWindow is how many of a test's most recent runs its statistics cover
+const testHistoryWindow = 20
+
+// TestHistoryLog is an append-only JSON Lines log of each test's outcome in
+// each run, kept in a sidecar file next to TASKS.md
+type TestHistoryLog struct {
+	path string
+	mu   sync.Mutex // Serialises appends
+}
+
+// NewTestHistoryLog creates a test history log stored at path. The file and
+// its directory are created on the first append.
+func NewTestHistoryLog(path string) *TestHistoryLog {
+	return &TestHistoryLog{path: path}
+}
+
+// Append writes records to the end of the log, one JSON object per line
+func (h *TestHistoryLog) Append(records ...models.TestRunRecord) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return appendJSONLines(h.path, records)
+}
+
+// ForTask returns the test runs recorded for a task, oldest first
+func (h *TestHistoryLog) ForTask(id string) ([]models.TestRunRecord, error) {
+	return readJSONLines(h.path, func(record models.TestRunRecord) bool {
+		return record.TaskID == id
+	})
+}
+
+// recordTestRuns appends the outcome of each of a task's tests to the test
+// history. Like recordHistory, a failed write is only logged.
+func (s *TaskStore) recordTestRuns(task *models.Task, results models.TestResults) {
+	now := time.Now().UTC()
+	var records []models.TestRunRecord
+	for i, result := range results.Results {
+		if i >= len(task.Tests) {
+			break
+		}
+		records = append(records, models.TestRunRecord{
+			Time:     now,
+			TaskID:   task.ID,
+			Test:     task.Tests[i].String(),
+			Passed:   result.Passed,
+			Duration: result.RunTime,
+			Commit:   results.Commit,
+			Changes:  results.Changes,
+			Error:    result.Error,
+		})
+	}
+	if err := s.testHistory.Append(records...); err != nil {
+		log.Printf("Error writing test history: %v", err)
+	}
+}
+
+// GetTestHistory returns the recorded test runs of a task with statistics
+// for each test: its current tests first, then tests it no longer has.
+// Like GetHistory, it is only an error if nothing was recorded and the task
+// does not exist.
+func (s *TaskStore) GetTestHistory(id string) (*models.TestHistory, error) {
+	records, err := s.testHistory.ForTask(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read test history: %w", err)
+	}
+
+	var tests []string
+	task, err := s.Get(id)
+	if err == nil {
+		for _, test := range task.Tests {
+			tests = append(tests, test.String())
+		}
+	} else if len(records) == 0 {
+		return nil, err
+	}
+	for _, record := range records {
+		if !containsString(tests, record.Test) {
+			tests = append(tests, record.Test)
+		}
+	}
+
+	history := &models.TestHistory{
+		TaskID: id,
+		Tests:  make([]models.TestStats, 0, len(tests)),
+		Runs:   records,
+	}
+	for _, test := range tests {
+		var runs []models.TestRunRecord
+		for _, record := range records {
+			if record.Test == test {
+				runs = append(runs, record)
+			}
+		}
+		history.Tests = append(history.Tests, testStats(test, runs))
+	}
+	return history, nil
+}
+
+// testStats summarises a test's most recent runs. Only consecutive runs on
+// the same known code are compared for flips, since a test that starts
+// passing after a commit was most likely fixed rather than flaky.
+func testStats(test string, runs []models.TestRunRecord) models.TestStats {
+	if len(runs) > testHistoryWindow {
+		runs = runs[len(runs)-testHistoryWindow:]
+	}
+
+	stats := models.TestStats{Test: test, Runs: len(runs)}
+	var totalDuration int64
+	pairs := 0
+	for i, run := range runs {
+		if run.Passed {
+			stats.Passed++
+		}
+		totalDuration += run.Duration
+		if i == 0 {
+			continue
+		}
+		prev := runs[i-1]
+		if run.Commit == "" || run.Commit != prev.Commit || run.Changes != prev.Changes {
+			continue
+		}
+		pairs++
+		if run.Passed != prev.Passed {
+			stats.Flips++
+		}
+	}
+
+	if len(runs) > 0 {
+		stats.AvgDuration = totalDuration / int64(len(runs))
+		stats.LastPassed = runs[len(runs)-1].Passed
+	}
+	if pairs > 0 {
+		stats.Flakiness = float64(stats.Flips) / float64(pairs)
+	}
+	stats.Flaky = stats.Flips > 0
+	return stats
+}
+
+// codeState identifies the code that tests run against: the HEAD commit and
+// a hash of uncommitted changes to tracked files, leaving out the board's
+// own files and .kantext, which change when test results are saved. Both
+// are empty outside a git repository. Untracked files are not included.
+func (s *TaskStore) codeState() (commit, changes string) {
+	out, err := gitOutput(s.workingDir, "rev-parse", "HEAD")
+	if err != nil {
+		return "", ""
+	}
+	commit = strings.TrimSpace(string(out))
+
+	args := []string{"diff", "HEAD", "--", ".", ":(exclude).kantext"}
+	for _, file := range s.Files() {
+		if rel, err := filepath.Rel(s.workingDir, file); err == nil {
+			args = append(args, ":(exclude)"+filepath.ToSlash(rel))
+		}
+	}
+	if diff, err := gitOutput(s.workingDir, args...); err == nil && len(diff) > 0 {
+		sum := sha256.Sum256(diff)
+		changes = hex.EncodeToString(sum[:8])
+	}
+	return commit, changes
+}
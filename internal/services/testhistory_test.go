@@ -0,0 +1,134 @@
+package services
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"kantext/internal/models"
+)
+
+const testHistoryTestContent = `---
+stale_threshold_days: 7
+---
+# Kantext Tasks
+
+## Inbox
+
+- [ ] Login
+  - id: task-flaky01
+  - priority: high
+  - requires_test: true
+  - test: auth_test.go:TestLogin
+  - test: auth_test.go:TestLogout
+
+## In Progress
+
+## Done
+`
+
+func TestTaskStore_TestHistory(t *testing.T) {
+	store, cleanup := setupTaskStoreEnv(t, testHistoryTestContent)
+	defer cleanup()
+
+	// TestLogin flips on the same commit; TestLogout only fails before a new commit
+	runs := []struct {
+		commit        string
+		login, logout bool
+	}{
+		{"c1", true, false},
+		{"c1", false, false},
+		{"c2", true, true},
+		{"c2", true, true},
+	}
+	for _, run := range runs {
+		results := models.TestResults{
+			AllPassed: run.login && run.logout,
+			Results:   []models.TestResult{{Passed: run.login, RunTime: 100}, {Passed: run.logout, RunTime: 50}},
+			Commit:    run.commit,
+		}
+		if _, err := store.UpdateTestResults("task-flaky01", results); err != nil {
+			t.Fatalf("UpdateTestResults failed: %v", err)
+		}
+	}
+
+	history, err := store.GetTestHistory("task-flaky01")
+	if err != nil {
+		t.Fatalf("GetTestHistory failed: %v", err)
+	}
+	if len(history.Runs) != 8 || len(history.Tests) != 2 {
+		t.Fatalf("Expected 8 runs of 2 tests, got %+v", history)
+	}
+
+	login, logout := history.Tests[0], history.Tests[1]
+	if login.Test != "auth_test.go:TestLogin" || !login.Flaky || login.Flips != 1 || login.Flakiness != 0.5 {
+		t.Errorf("Expected TestLogin to be flaky, got %+v", login)
+	}
+	if login.Runs != 4 || login.Passed != 3 || login.AvgDuration != 100 || !login.LastPassed {
+		t.Errorf("Unexpected TestLogin stats: %+v", login)
+	}
+	if logout.Flaky || logout.Flips != 0 {
+		t.Errorf("A test fixed by a commit is not flaky, got %+v", logout)
+	}
+
+	// The log is a sidecar, not part of TASKS.md
+	if _, err := os.Stat(filepath.Join(store.GetWorkingDir(), ".kantext", "test-history.jsonl")); err != nil {
+		t.Errorf("Expected a test history sidecar: %v", err)
+	}
+
+	if _, err := store.GetTestHistory("task-missing"); err == nil {
+		t.Error("Expected an error for an unknown task")
+	}
+}
+
+func TestTestStats_UnknownCodeIsNotCompared(t *testing.T) {
+	runs := []models.TestRunRecord{
+		{Passed: true},
+		{Passed: false},
+		{Passed: true, Commit: "c1", Changes: "d1"},
+		{Passed: false, Commit: "c1", Changes: "d2"},
+	}
+	if stats := testStats("a_test.go:TestA", runs); stats.Flaky || stats.Flakiness != 0 {
+		t.Errorf("Expected no flips without a known, identical code state, got %+v", stats)
+	}
+}
+
+func TestTaskStore_CodeState(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", "-b", "main", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v\n%s", err, out)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	gitRun(t, dir, "Alice", "2024-01-01T00:00:00Z", "add", "main.go")
+	gitCommitAs(t, dir, testHistoryTestContent, "Alice", "2024-01-01T00:00:00Z")
+
+	store := NewTaskStore(dir)
+	store.SetDurableWrites(true)
+	defer store.Close()
+
+	commit, changes := store.codeState()
+	if len(commit) != 40 || changes != "" {
+		t.Fatalf("Expected a clean commit, got %q %q", commit, changes)
+	}
+
+	// Saving test results changes TASKS.md, which is not code
+	if _, err := store.UpdateTestResults("task-flaky01", models.TestResults{Results: []models.TestResult{{Passed: true}}}); err != nil {
+		t.Fatalf("UpdateTestResults failed: %v", err)
+	}
+	if _, changes := store.codeState(); changes != "" {
+		t.Errorf("Expected board files to be ignored, got changes %q", changes)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if again, changes := store.codeState(); again != commit || changes == "" {
+		t.Errorf("Expected uncommitted changes on the same commit, got %q %q", again, changes)
+	}
+}
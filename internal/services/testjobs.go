@@ -0,0 +1,258 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"kantext/internal/models"
+)
+
+// Test job message types
+const (
+	MsgTypeTestJob    = "test_job"    // A test job started or finished
+	MsgTypeTestOutput = "test_output" // A line of a running test job's output
+)
+
+// Test job limits
+const (
+	testJobTimeout      = 30 * time.Minute
+	maxTestJobOutput    = 1 << 20 // Bytes of output kept per job; later lines are only streamed
+	maxFinishedTestJobs = 100     // Finished jobs kept for lookup
+)
+
+// Test job errors
+var (
+	ErrNoTests         = errors.New("task does not have any tests associated with it")
+	ErrTestJobNotFound = errors.New("test job not found")
+	ErrTestJobFinished = errors.New("test job has already finished")
+)
+
+// truncatedOutputNote ends a job's kept output once it reaches maxTestJobOutput
+const truncatedOutputNote = "[output truncated; later lines were only streamed]\n"
+
+// testJob is a test job with what is needed to follow and stop it
+type testJob struct {
+	job       models.TestJob
+	output    strings.Builder
+	truncated bool
+	cancel    context.CancelFunc
+	done      chan struct{} // Closed when the job has finished
+}
+
+// snapshot returns a copy of the job. Callers must hold the manager's lock.
+func (j *testJob) snapshot() *models.TestJob {
+	job := j.job
+	job.Output = j.output.String()
+	return &job
+}
+
+// TestJobManager runs tasks' tests in the background, streaming their output
+// to WebSocket clients as it is written. A task has at most one running job.
+type TestJobManager struct {
+	store  *TaskStore
+	runner *TestRunner
+	wsHub  *WSHub
+
+	mu       sync.Mutex
+	jobs     map[string]*testJob
+	finished []string // IDs of finished jobs, oldest first
+}
+
+// NewTestJobManager creates a TestJobManager
+func NewTestJobManager(store *TaskStore, runner *TestRunner, wsHub *WSHub) *TestJobManager {
+	return &TestJobManager{
+		store:  store,
+		runner: runner,
+		wsHub:  wsHub,
+		jobs:   make(map[string]*testJob),
+	}
+}
+
+// Start runs a task's tests in the background and returns the new job. When
+// the task's tests are already running, the running job is returned instead.
+func (m *TestJobManager) Start(taskID string) (*models.TestJob, error) {
+	task, err := m.store.Get(taskID)
+	if err != nil {
+		return nil, err
+	}
+	if !task.HasTest() {
+		return nil, ErrNoTests
+	}
+
+	m.mu.Lock()
+	for _, j := range m.jobs {
+		if j.job.TaskID == taskID && j.job.Status == models.TestJobRunning {
+			job := j.snapshot()
+			m.mu.Unlock()
+			return job, nil
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), testJobTimeout)
+	j := &testJob{
+		job: models.TestJob{
+			ID:        "job-" + strings.TrimPrefix(generateShortID(), "task-"),
+			TaskID:    taskID,
+			Status:    models.TestJobRunning,
+			StartedAt: time.Now(),
+		},
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	m.jobs[j.job.ID] = j
+	job := j.snapshot()
+	m.mu.Unlock()
+
+	previousStatus := task.TestStatus
+	m.store.SetTestRunning(taskID)
+	log.Printf("Started test job %s for task %s", job.ID, taskID)
+	m.broadcastJob(job)
+
+	go m.run(ctx, j, task.Tests, previousStatus)
+	return job, nil
+}
+
+// run runs a job's tests and records the results on its task. A canceled
+// job records nothing, and the task's test status is put back.
+func (m *TestJobManager) run(ctx context.Context, j *testJob, tests []models.TestSpec, previousStatus models.TestStatus) {
+	defer j.cancel()
+
+	results := m.runner.RunAllStreaming(ctx, tests, func(test, stream, line string) {
+		m.mu.Lock()
+		j.job.Lines++
+		seq := j.job.Lines
+		switch {
+		case j.output.Len()+len(line) < maxTestJobOutput:
+			j.output.WriteString(line)
+			j.output.WriteByte('\n')
+		case !j.truncated:
+			j.output.WriteString(truncatedOutputNote)
+			j.truncated = true
+		}
+		m.mu.Unlock()
+
+		m.wsHub.Broadcast(WSMessage{
+			Type: MsgTypeTestOutput,
+			Data: models.TestOutputLine{
+				JobID:  j.job.ID,
+				TaskID: j.job.TaskID,
+				Seq:    seq,
+				Test:   test,
+				Stream: stream,
+				Line:   line,
+			},
+		})
+	})
+
+	status := models.TestJobFailed
+	if results.AllPassed {
+		status = models.TestJobPassed
+	}
+	if errors.Is(ctx.Err(), context.Canceled) {
+		status = models.TestJobCanceled
+		m.store.RestoreTestStatus(j.job.TaskID, previousStatus)
+	} else if _, err := m.store.UpdateTestResults(j.job.TaskID, results); err != nil {
+		log.Printf("Failed to record results of test job %s: %v", j.job.ID, err)
+	}
+
+	m.mu.Lock()
+	now := time.Now()
+	j.job.Status = status
+	j.job.FinishedAt = &now
+	if status != models.TestJobCanceled {
+		j.job.Results = &results
+	}
+	m.finished = append(m.finished, j.job.ID)
+	if len(m.finished) > maxFinishedTestJobs {
+		delete(m.jobs, m.finished[0])
+		m.finished = m.finished[1:]
+	}
+	job := j.snapshot()
+	m.mu.Unlock()
+	close(j.done)
+
+	log.Printf("Test job %s for task %s %s", job.ID, job.TaskID, job.Status)
+	m.broadcastJob(job)
+	m.wsHub.NotifyTasksUpdated()
+}
+
+// broadcastJob tells clients a job started or finished. The output is left
+// out, since clients receive it line by line.
+func (m *TestJobManager) broadcastJob(job *models.TestJob) {
+	summary := *job
+	summary.Output = ""
+	m.wsHub.Broadcast(WSMessage{
+		Type: MsgTypeTestJob,
+		Data: summary,
+	})
+}
+
+// Get returns a job by ID, with its output so far
+func (m *TestJobManager) Get(id string) (*models.TestJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j, ok := m.jobs[id]
+	if !ok {
+		return nil, ErrTestJobNotFound
+	}
+	return j.snapshot(), nil
+}
+
+// Cancel stops a running job and returns it once its test commands have
+// exited
+func (m *TestJobManager) Cancel(id string) (*models.TestJob, error) {
+	m.mu.Lock()
+	j, ok := m.jobs[id]
+	if !ok {
+		m.mu.Unlock()
+		return nil, ErrTestJobNotFound
+	}
+	if j.job.Status != models.TestJobRunning {
+		m.mu.Unlock()
+		return nil, ErrTestJobFinished
+	}
+	m.mu.Unlock()
+
+	j.cancel()
+	<-j.done
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return j.snapshot(), nil
+}
+
+// Wait waits for a job to finish and returns it. Returns ctx's error if ctx
+// is done first.
+func (m *TestJobManager) Wait(ctx context.Context, id string) (*models.TestJob, error) {
+	m.mu.Lock()
+	j, ok := m.jobs[id]
+	m.mu.Unlock()
+	if !ok {
+		return nil, ErrTestJobNotFound
+	}
+
+	select {
+	case <-j.done:
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return j.snapshot(), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Stop cancels all running jobs without waiting for them
+func (m *TestJobManager) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, j := range m.jobs {
+		if j.job.Status == models.TestJobRunning {
+			j.cancel()
+		}
+	}
+}
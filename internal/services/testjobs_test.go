@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"kantext/internal/models"
+)
+
+// testJobsTestContent has a task with two tests; the command is filled in by each test
+const testJobsTestContent = `---
+test_runner:
+  command: %s
+  pass_string: "PASS"
+---
+# Kantext Tasks
+
+## Inbox
+
+- [ ] Login
+  - id: task-job00001
+  - priority: high
+  - test: auth/auth_test.go:TestLogin
+  - test: auth/auth_test.go:TestLogout
+
+- [ ] Docs
+  - id: task-job00002
+  - priority: low
+
+## In Progress
+
+## Done
+`
+
+// streamedLines collects the test_output messages broadcast so far
+func streamedLines(hub *WSHub) []models.TestOutputLine {
+	var lines []models.TestOutputLine
+	for {
+		select {
+		case msg := <-hub.broadcast:
+			if line, ok := msg.Data.(models.TestOutputLine); ok && msg.Type == MsgTypeTestOutput {
+				lines = append(lines, line)
+			}
+		default:
+			return lines
+		}
+	}
+}
+
+func TestTestJobManager_Start(t *testing.T) {
+	store, cleanup := setupTestRunnerEnv(t, fmt.Sprintf(testJobsTestContent, `echo "running {testFunc}" && echo "slow setup" >&2 && echo PASS`))
+	defer cleanup()
+	hub := NewWSHub()
+	jobs := NewTestJobManager(store, NewTestRunnerWithStore(store), hub)
+
+	job, err := jobs.Start("task-job00001")
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if job.Status != models.TestJobRunning || job.TaskID != "task-job00001" {
+		t.Errorf("Expected a running job, got %+v", job)
+	}
+
+	job, err = jobs.Wait(context.Background(), job.ID)
+	if err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	if job.Status != models.TestJobPassed || job.FinishedAt == nil || job.Results == nil || !job.Results.AllPassed {
+		t.Errorf("Expected a passed job with results, got %+v", job)
+	}
+	if job.Lines != 6 || !strings.Contains(job.Output, "running TestLogin\n") || !strings.Contains(job.Output, "slow setup\n") {
+		t.Errorf("Expected 6 lines of output, got %d:\n%s", job.Lines, job.Output)
+	}
+
+	// Each line was streamed with the test that wrote it
+	lines := streamedLines(hub)
+	if len(lines) != 6 {
+		t.Fatalf("Expected 6 streamed lines, got %+v", lines)
+	}
+	seen := make(map[string]bool)
+	for _, line := range lines {
+		if line.JobID != job.ID || line.TaskID != "task-job00001" {
+			t.Errorf("Unexpected streamed line: %+v", line)
+		}
+		seen[line.Test+" "+line.Stream+" "+line.Line] = true
+	}
+	if !seen["auth/auth_test.go:TestLogout stdout running TestLogout"] || !seen["auth/auth_test.go:TestLogin stderr slow setup"] {
+		t.Errorf("Expected lines labeled by test and stream, got %+v", lines)
+	}
+
+	// The results were recorded on the task
+	task, _ := store.Get("task-job00001")
+	if task.TestStatus != models.TestStatusPassed || task.TestsPassed != 2 {
+		t.Errorf("Expected the results on the task, got status %s with %d passed", task.TestStatus, task.TestsPassed)
+	}
+
+	if _, err := jobs.Start("task-job00002"); !errors.Is(err, ErrNoTests) {
+		t.Errorf("Expected ErrNoTests for a task without tests, got %v", err)
+	}
+	if _, err := jobs.Get("job-missing"); !errors.Is(err, ErrTestJobNotFound) {
+		t.Errorf("Expected ErrTestJobNotFound, got %v", err)
+	}
+}
+
+func TestTestJobManager_Cancel(t *testing.T) {
+	store, cleanup := setupTestRunnerEnv(t, fmt.Sprintf(testJobsTestContent, `echo started; exec sleep 10`))
+	defer cleanup()
+	jobs := NewTestJobManager(store, NewTestRunnerWithStore(store), NewWSHub())
+
+	job, err := jobs.Start("task-job00001")
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	// Starting again while running returns the same job
+	again, err := jobs.Start("task-job00001")
+	if err != nil || again.ID != job.ID {
+		t.Errorf("Expected the running job %s, got %+v, %v", job.ID, again, err)
+	}
+	if task, _ := store.Get("task-job00001"); task.TestStatus != models.TestStatusRunning {
+		t.Errorf("Expected the task to be running, got %s", task.TestStatus)
+	}
+
+	start := time.Now()
+	job, err = jobs.Cancel(job.ID)
+	if err != nil {
+		t.Fatalf("Cancel failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Expected the tests to stop right away, took %v", elapsed)
+	}
+	if job.Status != models.TestJobCanceled || job.Results != nil {
+		t.Errorf("Expected a canceled job without results, got %+v", job)
+	}
+
+	// Nothing was recorded, and the task's status was put back
+	task, _ := store.Get("task-job00001")
+	if task.TestStatus != models.TestStatusPending || task.LastRun != nil {
+		t.Errorf("Expected the task's test status to be restored, got %s (last run %v)", task.TestStatus, task.LastRun)
+	}
+
+	if _, err := jobs.Cancel(job.ID); !errors.Is(err, ErrTestJobFinished) {
+		t.Errorf("Expected ErrTestJobFinished for a finished job, got %v", err)
+	}
+}
+
+func TestTestJobManager_Cancel_KillsChildProcesses(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh and ps")
+	}
+	pidFile := filepath.Join(t.TempDir(), "sleep.pid")
+	store, cleanup := setupTestRunnerEnv(t, fmt.Sprintf(testJobsTestContent, `echo started; (sleep 30 & echo $! > `+pidFile+`; wait)`))
+	defer cleanup()
+	jobs := NewTestJobManager(store, NewTestRunnerWithStore(store), NewWSHub())
+
+	job, err := jobs.Start("task-job00001")
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	var pid string
+	for deadline := time.Now().Add(5 * time.Second); pid == "" && time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		content, _ := os.ReadFile(pidFile)
+		if strings.HasSuffix(string(content), "\n") {
+			pid = strings.TrimSpace(string(content))
+		}
+	}
+	if pid == "" {
+		t.Fatal("The subshell did not start sleep")
+	}
+
+	start := time.Now()
+	if _, err := jobs.Cancel(job.ID); err != nil {
+		t.Fatalf("Cancel failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Expected the tests to stop right away, took %v", elapsed)
+	}
+
+	// sleep was killed with the shell; once killed it may linger as a zombie
+	// until it is reaped
+	var state string
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		out, _ := exec.Command("ps", "-o", "stat=", "-p", pid).Output()
+		if state = strings.TrimSpace(string(out)); state == "" || strings.HasPrefix(state, "Z") {
+			return
+		}
+	}
+	exec.Command("kill", "-9", pid).Run()
+	t.Errorf("Expected sleep (pid %s) to be killed with its job, still running in state %s", pid, state)
+}
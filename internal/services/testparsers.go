@@ -0,0 +1,329 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"kantext/internal/models"
+)
+
+// Structured test result formats for test_runner.format
+const (
+	TestFormatGoJSON = "go-json" // go test -json events
+	TestFormatJUnit  = "junit"   // JUnit XML report
+	TestFormatTAP    = "tap"     // Test Anything Protocol
+)
+
+// testReport is what a result parser found in a test command's report
+type testReport struct {
+	cases   []models.TestCaseResult
+	output  string            // Readable output to show instead of the raw report, if any
+	outputs map[string]string // Output of each case by name, if the format separates it
+}
+
+// resultParser parses a test command's report in one format
+type resultParser func(data []byte) (testReport, error)
+
+// resultParsers maps each structured format to its parser
+var resultParsers = map[string]resultParser{
+	TestFormatGoJSON: parseGoTestJSON,
+	TestFormatJUnit:  parseJUnitXML,
+	TestFormatTAP:    parseTAP,
+}
+
+// ValidTestFormat reports whether format is a known test result format.
+// The empty format matches the configured pass and fail strings instead.
+func ValidTestFormat(format string) bool {
+	_, ok := resultParsers[format]
+	return format == "" || ok
+}
+
+// goTestEvent is one line of `go test -json` output (see `go doc test2json`)
+type goTestEvent struct {
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64 // Seconds
+	Output  string
+}
+
+// parseGoTestJSON parses `go test -json` events. Each test and subtest is a
+// case; a package that fails without a failing test (a panic in TestMain,
+// say) is reported as a failed case named after the package.
+func parseGoTestJSON(data []byte) (testReport, error) {
+	report := testReport{outputs: make(map[string]string)}
+	var output strings.Builder
+	testOutput := make(map[string]*strings.Builder) // By package and test name
+	var failedPackages []string
+	events := 0
+
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var event goTestEvent
+		if line[0] != '{' || json.Unmarshal(line, &event) != nil {
+			// Not an event, e.g. an error printed by the go command itself
+			output.Write(line)
+			output.WriteByte('\n')
+			continue
+		}
+		events++
+
+		key := event.Package + " " + event.Test
+		switch event.Action {
+		case "output", "build-output":
+			output.WriteString(event.Output)
+			if testOutput[key] == nil {
+				testOutput[key] = &strings.Builder{}
+			}
+			testOutput[key].WriteString(event.Output)
+		case "pass", "fail", "skip":
+			if event.Test == "" {
+				if event.Action == "fail" {
+					failedPackages = append(failedPackages, event.Package)
+				}
+				continue
+			}
+			c := models.TestCaseResult{
+				Name:     event.Test,
+				Suite:    event.Package,
+				Status:   goTestStatus(event.Action),
+				Duration: int64(event.Elapsed * 1000),
+			}
+			if c.Status != models.TestCasePassed {
+				c.Message = goTestMessage(testOutput[key])
+			}
+			if testOutput[key] != nil {
+				report.outputs[c.Name] = testOutput[key].String()
+			}
+			report.cases = append(report.cases, c)
+		}
+	}
+	if events == 0 {
+		return report, errors.New("no go test -json events in output")
+	}
+
+	for _, pkg := range failedPackages {
+		failedTest := false
+		for _, c := range report.cases {
+			if c.Suite == pkg && c.Status == models.TestCaseFailed {
+				failedTest = true
+			}
+		}
+		if !failedTest {
+			report.cases = append(report.cases, models.TestCaseResult{
+				Name:    pkg,
+				Suite:   pkg,
+				Status:  models.TestCaseFailed,
+				Message: goTestMessage(testOutput[pkg+" "]),
+			})
+		}
+	}
+
+	report.output = output.String()
+	return report, nil
+}
+
+// goTestStatus maps a go test -json action to a test case status
+func goTestStatus(action string) string {
+	switch action {
+	case "pass":
+		return models.TestCasePassed
+	case "skip":
+		return models.TestCaseSkipped
+	default:
+		return models.TestCaseFailed
+	}
+}
+
+// goTestMessage returns a test's own output, without the "=== RUN" and
+// "--- FAIL" lines go test adds around it
+func goTestMessage(output *strings.Builder) string {
+	if output == nil {
+		return ""
+	}
+	var lines []string
+	for _, line := range strings.Split(output.String(), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "=== ") || strings.HasPrefix(trimmed, "--- ") {
+			continue
+		}
+		lines = append(lines, trimmed)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// junitSuite is a <testsuites> or <testsuite> element of a JUnit XML report
+type junitSuite struct {
+	Name   string       `xml:"name,attr"`
+	Suites []junitSuite `xml:"testsuite"`
+	Cases  []junitCase  `xml:"testcase"`
+}
+
+// junitCase is a <testcase> element of a JUnit XML report
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"` // Seconds
+	Failure   *junitProblem `xml:"failure"`
+	Error     *junitProblem `xml:"error"`
+	Skipped   *junitProblem `xml:"skipped"`
+}
+
+// junitProblem is a <failure>, <error> or <skipped> element
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// parseJUnitXML parses a JUnit XML report. Errors count as failures.
+func parseJUnitXML(data []byte) (testReport, error) {
+	var root junitSuite
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return testReport{}, fmt.Errorf("invalid JUnit XML: %w", err)
+	}
+
+	var report testReport
+	var walk func(suite junitSuite)
+	walk = func(suite junitSuite) {
+		for _, tc := range suite.Cases {
+			c := models.TestCaseResult{
+				Name:   tc.Name,
+				Suite:  tc.Classname,
+				Status: models.TestCasePassed,
+			}
+			if c.Suite == "" {
+				c.Suite = suite.Name
+			}
+			if seconds, err := strconv.ParseFloat(strings.ReplaceAll(tc.Time, ",", ""), 64); err == nil {
+				c.Duration = int64(seconds * 1000)
+			}
+			switch {
+			case tc.Failure != nil:
+				c.Status, c.Message = models.TestCaseFailed, tc.Failure.message()
+			case tc.Error != nil:
+				c.Status, c.Message = models.TestCaseFailed, tc.Error.message()
+			case tc.Skipped != nil:
+				c.Status, c.Message = models.TestCaseSkipped, tc.Skipped.message()
+			}
+			report.cases = append(report.cases, c)
+		}
+		for _, child := range suite.Suites {
+			walk(child)
+		}
+	}
+	walk(root)
+	return report, nil
+}
+
+// message combines the message attribute and the text of a problem element
+func (p *junitProblem) message() string {
+	text := strings.TrimSpace(p.Text)
+	switch {
+	case p.Message == "" || strings.HasPrefix(text, p.Message):
+		return text
+	case text == "":
+		return p.Message
+	default:
+		return p.Message + "\n" + text
+	}
+}
+
+// Patterns for parsing TAP output
+var (
+	tapTestRegex     = regexp.MustCompile(`^(not )?ok\b\s*(\d+)?\s*(?:-\s*)?([^#]*?)\s*(?:#\s*(\w+)\s*(.*))?$`)
+	tapPlanRegex     = regexp.MustCompile(`^\d+\.\.\d+`)
+	tapDurationRegex = regexp.MustCompile(`^duration_ms:\s*([\d.]+)`)
+)
+
+// parseTAP parses Test Anything Protocol output. Only top-level test lines
+// are cases; indented subtests are part of their parent. SKIP and TODO
+// directives count as skipped, and "Bail out!" as a failure.
+func parseTAP(data []byte) (testReport, error) {
+	var report testReport
+	current := -1 // Index of the case that diagnostics belong to
+	var diagnostics []string
+
+	// finish attaches the diagnostics collected for a failed case to it
+	finish := func() {
+		if current >= 0 && report.cases[current].Status == models.TestCaseFailed && len(diagnostics) > 0 {
+			report.cases[current].Message = strings.Join(diagnostics, "\n")
+		}
+		current, diagnostics = -1, nil
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(line, "ok") || strings.HasPrefix(line, "not ok"):
+			m := tapTestRegex.FindStringSubmatch(line)
+			if m == nil {
+				continue
+			}
+			finish()
+			c := models.TestCaseResult{Name: m[3], Status: models.TestCasePassed}
+			if c.Name == "" {
+				c.Name = "test " + m[2]
+			}
+			if m[1] != "" {
+				c.Status = models.TestCaseFailed
+			}
+			switch strings.ToUpper(m[4]) {
+			case "SKIP":
+				c.Status, c.Message = models.TestCaseSkipped, m[5]
+			case "TODO":
+				c.Status, c.Message = models.TestCaseSkipped, strings.TrimSpace("TODO "+m[5])
+			}
+			report.cases = append(report.cases, c)
+			current = len(report.cases) - 1
+		case strings.HasPrefix(line, "Bail out!"):
+			finish()
+			report.cases = append(report.cases, models.TestCaseResult{
+				Name:    "Bail out!",
+				Status:  models.TestCaseFailed,
+				Message: strings.TrimSpace(strings.TrimPrefix(line, "Bail out!")),
+			})
+		case tapPlanRegex.MatchString(line):
+			finish()
+		case current >= 0:
+			if m := tapDurationRegex.FindStringSubmatch(trimmed); m != nil {
+				// The first duration is the test's own; later ones are subtests of the next test
+				if ms, err := strconv.ParseFloat(m[1], 64); err == nil && report.cases[current].Duration == 0 {
+					report.cases[current].Duration = int64(ms)
+				}
+				continue
+			}
+			if isTAPDiagnostic(trimmed) {
+				diagnostics = append(diagnostics, strings.TrimSpace(strings.TrimPrefix(trimmed, "#")))
+			}
+		}
+	}
+	finish()
+
+	if len(report.cases) == 0 {
+		return report, errors.New("no TAP test lines in output")
+	}
+	return report, nil
+}
+
+// isTAPDiagnostic reports whether a line following a test is diagnostic
+// output for it, rather than a YAML block marker or a subtest of the next test
+func isTAPDiagnostic(line string) bool {
+	switch {
+	case line == "" || line == "---" || line == "...":
+		return false
+	case strings.HasPrefix(line, "ok") || strings.HasPrefix(line, "not ok") || strings.HasPrefix(line, "# Subtest:"):
+		return false
+	}
+	return true
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"kantext/internal/models"
+)
+
+// caseSummary renders cases as "name:status" pairs for comparison
+func caseSummary(cases []models.TestCaseResult) string {
+	parts := make([]string, len(cases))
+	for i, c := range cases {
+		parts[i] = c.Name + ":" + c.Status
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestParseGoTestJSON(t *testing.T) {
+	output := `{"Action":"start","Package":"example/auth"}
+{"Action":"run","Package":"example/auth","Test":"TestLogin"}
+{"Action":"output","Package":"example/auth","Test":"TestLogin","Output":"=== RUN   TestLogin\n"}
+{"Action":"output","Package":"example/auth","Test":"TestLogin","Output":"    auth_test.go:12: PASS expected, got 401\n"}
+{"Action":"output","Package":"example/auth","Test":"TestLogin","Output":"--- FAIL: TestLogin (0.25s)\n"}
+{"Action":"fail","Package":"example/auth","Test":"TestLogin","Elapsed":0.25}
+{"Action":"run","Package":"example/auth","Test":"TestLogout"}
+{"Action":"pass","Package":"example/auth","Test":"TestLogout","Elapsed":0.01}
+{"Action":"skip","Package":"example/auth","Test":"TestSSO","Elapsed":0}
+{"Action":"output","Package":"example/auth","Output":"FAIL\n"}
+{"Action":"fail","Package":"example/auth","Elapsed":0.3}
+`
+	report, err := parseGoTestJSON([]byte(output))
+	if err != nil {
+		t.Fatalf("parseGoTestJSON failed: %v", err)
+	}
+	if got := caseSummary(report.cases); got != "TestLogin:fail TestLogout:pass TestSSO:skip" {
+		t.Errorf("Unexpected cases: %s", got)
+	}
+	login := report.cases[0]
+	if login.Suite != "example/auth" || login.Duration != 250 || login.Message != "auth_test.go:12: PASS expected, got 401" {
+		t.Errorf("Unexpected failed case: %+v", login)
+	}
+	if !strings.Contains(report.output, "--- FAIL: TestLogin") || strings.Contains(report.output, `"Action"`) {
+		t.Errorf("Expected readable output, got:\n%s", report.output)
+	}
+
+	// A package that fails without a failing test is reported as a case
+	report, _ = parseGoTestJSON([]byte(`{"Action":"output","Package":"example/db","Output":"panic: no database\n"}
+{"Action":"fail","Package":"example/db","Elapsed":0.1}
+`))
+	if got := caseSummary(report.cases); got != "example/db:fail" || report.cases[0].Message != "panic: no database" {
+		t.Errorf("Expected a failed package case, got %+v", report.cases)
+	}
+
+	if _, err := parseGoTestJSON([]byte("PASS\nok  example/auth 0.1s\n")); err == nil {
+		t.Error("Expected an error for output without events")
+	}
+}
+
+func TestParseJUnitXML(t *testing.T) {
+	output := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="auth" tests="3">
+    <testcase classname="tests.test_auth" name="test_login" time="1.5">
+      <failure message="AssertionError: 401 != 200">Traceback: line 12</failure>
+    </testcase>
+    <testcase classname="tests.test_auth" name="test_logout" time="0.01"/>
+    <testcase name="test_sso" time="0">
+      <skipped message="needs network"/>
+    </testcase>
+  </testsuite>
+  <testsuite name="db">
+    <testcase classname="tests.test_db" name="test_connect">
+      <error message="ConnectionRefused"/>
+    </testcase>
+  </testsuite>
+</testsuites>`
+	report, err := parseJUnitXML([]byte(output))
+	if err != nil {
+		t.Fatalf("parseJUnitXML failed: %v", err)
+	}
+	if got := caseSummary(report.cases); got != "test_login:fail test_logout:pass test_sso:skip test_connect:fail" {
+		t.Errorf("Unexpected cases: %s", got)
+	}
+	if c := report.cases[0]; c.Suite != "tests.test_auth" || c.Duration != 1500 || c.Message != "AssertionError: 401 != 200\nTraceback: line 12" {
+		t.Errorf("Unexpected failed case: %+v", c)
+	}
+	if c := report.cases[2]; c.Suite != "auth" || c.Message != "needs network" {
+		t.Errorf("Expected the suite name and skip reason, got %+v", c)
+	}
+
+	// A single <testsuite> root works too
+	report, err = parseJUnitXML([]byte(`<testsuite name="s"><testcase name="a"/></testsuite>`))
+	if err != nil || caseSummary(report.cases) != "a:pass" {
+		t.Errorf("Expected one passing case, got %+v, %v", report.cases, err)
+	}
+}
+
+func TestParseTAP(t *testing.T) {
+	output := `TAP version 13
+# Subtest: login
+ok 1 - login
+  ---
+  duration_ms: 12.5
+  ...
+not ok 2 - logout
+  ---
+  duration_ms: 3
+  message: expected 200, got 401
+  ...
+ok 3 - sso # SKIP needs network
+not ok 4 # TODO not written yet
+1..4
+# tests 4
+# pass 1
+`
+	report, err := parseTAP([]byte(output))
+	if err != nil {
+		t.Fatalf("parseTAP failed: %v", err)
+	}
+	if got := caseSummary(report.cases); got != "login:pass logout:fail sso:skip test 4:skip" {
+		t.Errorf("Unexpected cases: %s", got)
+	}
+	if c := report.cases[1]; c.Duration != 3 || c.Message != "message: expected 200, got 401" {
+		t.Errorf("Unexpected failed case: %+v", c)
+	}
+	if c := report.cases[0]; c.Duration != 12 {
+		t.Errorf("Expected a duration of 12ms, got %d", c.Duration)
+	}
+	if c := report.cases[2]; c.Message != "needs network" {
+		t.Errorf("Expected the skip reason, got %q", c.Message)
+	}
+
+	report, _ = parseTAP([]byte("ok 1 - setup\nBail out! database down\n"))
+	if got := caseSummary(report.cases); got != "setup:pass Bail out!:fail" || report.cases[1].Message != "database down" {
+		t.Errorf("Expected a failed case for bailing out, got %+v", report.cases)
+	}
+
+	if _, err := parseTAP([]byte("PASS\n")); err == nil {
+		t.Error("Expected an error for output without test lines")
+	}
+}
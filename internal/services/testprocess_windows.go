@@ -0,0 +1,14 @@
+package services
+
+import (
+	"os/exec"
+	"strconv"
+)
+
+// killProcessGroupOnCancel makes canceling cmd kill the processes it started
+// along with it, so they do not outlive a canceled run
+func killProcessGroupOnCancel(cmd *exec.Cmd) {
+	cmd.Cancel = func() error {
+		return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+	}
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"kantext/internal/models"
+)
+
+// testRunnerFor returns the settings to run a test with, and the name of the
+// profile they come from. A test that names a profile uses it; otherwise the
+// first profile, by name, with a match glob for the test's file is used, and
+// test_runner if there is none. The parallel setting always comes from
+// test_runner, since it applies to a whole run.
+func testRunnerFor(settings Settings, test models.TestSpec) (string, Settings, error) {
+	name := test.Runner
+	if name != "" {
+		if _, ok := settings.TestRunners[name]; !ok {
+			return "", settings, fmt.Errorf("unknown test runner profile %q", name)
+		}
+	} else {
+		name = matchingTestRunner(settings.TestRunners, test.File)
+	}
+	if name == "" {
+		return "", settings, nil
+	}
+
+	parallel := settings.TestRunner.Parallel
+	settings.TestRunner = settings.TestRunners[name].TestRunnerSettings
+	settings.TestRunner.Parallel = parallel
+	return name, settings, nil
+}
+
+// matchingTestRunner returns the name of the first profile with a glob
+// matching file, or "" if none matches
+func matchingTestRunner(profiles map[string]TestRunnerProfile, file string) string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, pattern := range profiles[name].Match {
+			if matchTestFile(pattern, file) {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// matchTestFile reports whether a test file matches a glob. A glob without
+// a slash, such as "*.py", matches the file name in any directory; otherwise
+// it matches the whole path, with "**" matching any number of directories.
+func matchTestFile(pattern, file string) bool {
+	file = filepath.ToSlash(file)
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(file))
+		return ok
+	}
+	return matchPathSegments(strings.Split(pattern, "/"), strings.Split(file, "/"))
+}
+
+// matchPathSegments matches the segments of a path against those of a glob
+func matchPathSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if matchPathSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+// ValidateTestRunners checks test runner profiles before they are saved.
+// Names are written before tests in the tasks file ("@name file:func"), so
+// they can't contain spaces.
+func ValidateTestRunners(profiles map[string]TestRunnerProfile) error {
+	for name, profile := range profiles {
+		if name == "" || strings.ContainsAny(name, " \t@:") {
+			return fmt.Errorf("invalid test runner profile name %q", name)
+		}
+		if !ValidTestFormat(profile.Format) {
+			return fmt.Errorf("test runner profile %q: format must be go-json, junit, tap or empty", name)
+		}
+		for _, pattern := range profile.Match {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("test runner profile %q: invalid match glob %q", name, pattern)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"testing"
+
+	"kantext/internal/models"
+)
+
+func TestMatchTestFile(t *testing.T) {
+	tests := []struct {
+		pattern, file string
+		want          bool
+	}{
+		{"*.py", "services/api/test_users.py", true},
+		{"*.py", "internal/auth/auth_test.go", false},
+		{"web/**/*.test.ts", "web/src/app.test.ts", true},
+		{"web/**/*.test.ts", "web/app.test.ts", true},
+		{"web/**/*.test.ts", "api/src/app.test.ts", false},
+		{"web/*.test.ts", "web/src/app.test.ts", false},
+		{"**/test_*.py", "test_main.py", true},
+	}
+	for _, tt := range tests {
+		if got := matchTestFile(tt.pattern, tt.file); got != tt.want {
+			t.Errorf("matchTestFile(%q, %q) = %v, want %v", tt.pattern, tt.file, got, tt.want)
+		}
+	}
+}
+
+func TestTestRunnerFor(t *testing.T) {
+	settings := Settings{
+		TestRunner: TestRunnerSettings{Command: "go test", Parallel: 4},
+		TestRunners: map[string]TestRunnerProfile{
+			"jest":   {TestRunnerSettings: TestRunnerSettings{Command: "npx jest"}, Match: []string{"*.test.ts"}},
+			"pytest": {TestRunnerSettings: TestRunnerSettings{Command: "pytest"}, Match: []string{"*.py", "*.test.ts"}},
+		},
+	}
+
+	tests := []struct {
+		spec        models.TestSpec
+		wantProfile string
+		wantCommand string
+	}{
+		{models.TestSpec{File: "auth_test.go", Func: "TestLogin"}, "", "go test"},
+		{models.TestSpec{File: "api/test_users.py", Func: "test_list"}, "pytest", "pytest"},
+		{models.TestSpec{File: "web/app.test.ts", Func: "renders"}, "jest", "npx jest"}, // First matching profile by name
+		{models.TestSpec{File: "web/app.test.ts", Func: "renders", Runner: "pytest"}, "pytest", "pytest"},
+	}
+	for _, tt := range tests {
+		name, runner, err := testRunnerFor(settings, tt.spec)
+		if err != nil {
+			t.Fatalf("testRunnerFor(%v) failed: %v", tt.spec, err)
+		}
+		if name != tt.wantProfile || runner.TestRunner.Command != tt.wantCommand {
+			t.Errorf("testRunnerFor(%v) = %q with %q, want %q with %q", tt.spec, name, runner.TestRunner.Command, tt.wantProfile, tt.wantCommand)
+		}
+		if runner.GetTestParallel() != 4 {
+			t.Errorf("Expected parallel to come from test_runner, got %d", runner.GetTestParallel())
+		}
+	}
+
+	if _, _, err := testRunnerFor(settings, models.TestSpec{File: "a.go", Func: "TestA", Runner: "cargo"}); err == nil {
+		t.Error("Expected an error for an unknown profile")
+	}
+}
+
+func TestValidateTestRunners(t *testing.T) {
+	valid := map[string]TestRunnerProfile{"py": {Match: []string{"*.py"}}}
+	if err := ValidateTestRunners(valid); err != nil {
+		t.Errorf("Expected valid profiles, got %v", err)
+	}
+	for name, profiles := range map[string]map[string]TestRunnerProfile{
+		"name with space": {"py tests": {}},
+		"unknown format":  {"py": {TestRunnerSettings: TestRunnerSettings{Format: "xml"}}},
+		"bad glob":        {"py": {Match: []string{"[*.py"}}},
+	} {
+		if err := ValidateTestRunners(profiles); err == nil {
+			t.Errorf("Expected an error for %s", name)
+		}
+	}
+}
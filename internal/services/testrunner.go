@@ -3,9 +3,13 @@ package services
 import (
 	"bytes"
 	"context"
+	"io"
+	"log"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"kantext/internal/models"
@@ -26,55 +30,38 @@ func NewTestRunnerWithStore(store *TaskStore) *TestRunner {
 // Run executes a specific test and returns the result
 // testFile should be a path relative to the working directory (e.g., "internal/auth/auth_test.go")
 func (r *TestRunner) Run(ctx context.Context, testFile, testFunc string) models.TestResult {
-	start := time.Now()
-
-	// Get current settings from store
-	settings := r.store.GetSettings()
-	workDir := r.store.GetWorkingDir()
-
-	// Extract the directory from the test file path
-	// e.g., "internal/auth/auth_test.go" -> "internal/auth"
-	testDir := filepath.Dir(testFile)
-	if testDir == "." {
-		testDir = ""
-	}
-
-	// Build the test path
-	testPath := "./"
-	if testDir != "" {
-		testPath = "./" + testDir + "/"
-	}
-
-	// Build the command from config template
-	// Replace placeholders: {testFunc} and {testPath}
-	cmdStr := settings.GetTestCommand()
-	cmdStr = strings.ReplaceAll(cmdStr, "{testFunc}", testFunc)
-	cmdStr = strings.ReplaceAll(cmdStr, "{testPath}", testPath)
-
-	// Split command into parts for exec
-	// Use shell to handle the command properly
-	cmd := exec.CommandContext(ctx, "sh", "-c", cmdStr)
-
-	// Set the working directory if specified
-	if workDir != "" {
-		cmd.Dir = workDir
+	_, settings, err := testRunnerFor(r.store.GetSettings(), models.TestSpec{File: testFile, Func: testFunc})
+	if err != nil {
+		return models.TestResult{Error: err.Error()}
 	}
+	return r.run(ctx, settings, testFile, testFunc, nil)
+}
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
-	err := cmd.Run()
-	elapsed := time.Since(start).Milliseconds()
-
-	output := stdout.String()
-	if stderr.Len() > 0 {
-		output += "\n" + stderr.String()
-	}
+// run executes a specific test with the given settings
+func (r *TestRunner) run(ctx context.Context, settings Settings, testFile, testFunc string, onOutput OutputFunc) models.TestResult {
+	label := models.TestSpec{File: testFile, Func: testFunc}.String()
+	run := r.execTests(ctx, settings, testPathFor(settings, testFile), testFunc, label, onOutput)
+	output := run.output()
 
 	result := models.TestResult{
 		Output:  output,
-		RunTime: elapsed,
+		RunTime: run.elapsed,
+	}
+	err := run.err
+
+	// Structured formats decide the verdict from per-test results. Output
+	// without any test results falls back to matching the pass/fail strings.
+	format := settings.GetTestFormat()
+	if parse, ok := resultParsers[format]; ok {
+		if report, ok := readTestReport(parse, run.reportPath, run.stdout.Bytes()); ok {
+			if report.output != "" {
+				result.Output = run.withStderr(report.output)
+			}
+			applyTestReport(&result, report.cases, err)
+			return result
+		}
+	} else if format != "" {
+		log.Printf("Unknown test result format %q, matching pass/fail strings instead", format)
 	}
 
 	// Get configurable strings
@@ -115,19 +102,242 @@ func (r *TestRunner) Run(ctx context.Context, testFile, testFunc string) models.
 	return result
 }
 
+// testRun is one run of the test command
+type testRun struct {
+	stdout, stderr bytes.Buffer
+	err            error
+	elapsed        int64  // Milliseconds
+	reportPath     string // Absolute path of the report file, if one is configured
+}
+
+// output returns the command's stdout followed by its stderr
+func (t *testRun) output() string {
+	return t.withStderr(t.stdout.String())
+}
+
+// withStderr appends the command's stderr to output
+func (t *testRun) withStderr(output string) string {
+	if t.stderr.Len() > 0 {
+		output += "\n" + t.stderr.String()
+	}
+	return output
+}
+
+// testPathFor returns the package path of a test file for the {testPath}
+// placeholder, e.g. "internal/auth/auth_test.go" -> "./internal/auth/".
+// Files under the runner's dir are relative to it, as the command runs there.
+func testPathFor(settings Settings, testFile string) string {
+	if dir := settings.TestRunner.Dir; dir != "" {
+		if rel, err := filepath.Rel(dir, testFile); err == nil && !strings.HasPrefix(rel, "..") {
+			testFile = rel
+		}
+	}
+	testDir := filepath.Dir(testFile)
+	if testDir == "." {
+		return "./"
+	}
+	return "./" + testDir + "/"
+}
+
+// execTests runs the test command for testFunc, which may be a pattern
+// matching several tests, in the package at testPath. Output is also passed
+// to onOutput, if set, as it is written, with label naming what runs.
+func (r *TestRunner) execTests(ctx context.Context, settings Settings, testPath, testFunc, label string, onOutput OutputFunc) *testRun {
+	start := time.Now()
+	workDir := r.store.GetWorkingDir()
+	if settings.TestRunner.Dir != "" {
+		workDir = filepath.Join(workDir, settings.TestRunner.Dir)
+	}
+
+	// Build the command from config template
+	// Replace placeholders: {testFunc} and {testPath}
+	cmdStr := settings.GetTestCommand()
+	cmdStr = fillPlaceholder(cmdStr, "{testFunc}", testFunc)
+	cmdStr = strings.ReplaceAll(cmdStr, "{testPath}", testPath)
+
+	run := &testRun{}
+
+	// A report file left over from an earlier run must not be read as this run's
+	if report := settings.TestRunner.Report; report != "" {
+		run.reportPath = report
+		if !filepath.IsAbs(report) {
+			run.reportPath = filepath.Join(workDir, report)
+		}
+		os.Remove(run.reportPath)
+	}
+
+	// Use shell to handle the command properly
+	cmd := exec.CommandContext(ctx, "sh", "-c", cmdStr)
+	killProcessGroupOnCancel(cmd)
+
+	// Set the working directory if specified
+	if workDir != "" {
+		cmd.Dir = workDir
+	}
+	if len(settings.TestRunner.Env) > 0 {
+		cmd.Env = os.Environ()
+		for key, value := range settings.TestRunner.Env {
+			cmd.Env = append(cmd.Env, key+"="+value)
+		}
+	}
+	cmd.Stdout = &run.stdout
+	cmd.Stderr = &run.stderr
+	if onOutput != nil {
+		stdout := &lineWriter{emit: func(line string) { onOutput(label, "stdout", line) }}
+		stderr := &lineWriter{emit: func(line string) { onOutput(label, "stderr", line) }}
+		defer stdout.flush()
+		defer stderr.flush()
+		cmd.Stdout = io.MultiWriter(&run.stdout, stdout)
+		cmd.Stderr = io.MultiWriter(&run.stderr, stderr)
+	}
+	// A child that left the process group may still keep the canceled
+	// command's output open; stop waiting for it after a while
+	cmd.WaitDelay = testWaitDelay
+
+	run.err = cmd.Run()
+	run.elapsed = time.Since(start).Milliseconds()
+	return run
+}
+
+// fillPlaceholder replaces a placeholder in a shell command with value.
+// Where the placeholder is outside quotes and value has characters the shell
+// would interpret, such as the "|" of a batch pattern, value is quoted.
+func fillPlaceholder(cmd, placeholder, value string) string {
+	quoted := value
+	if strings.ContainsAny(value, "()|&;<>*?[]$`\\\"' \t") {
+		quoted = "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+	}
+
+	var sb strings.Builder
+	var quote byte // The open quote character, if any
+	for i := 0; i < len(cmd); i++ {
+		if strings.HasPrefix(cmd[i:], placeholder) {
+			if quote == 0 {
+				sb.WriteString(quoted)
+			} else {
+				sb.WriteString(value)
+			}
+			i += len(placeholder) - 1
+			continue
+		}
+		c := cmd[i]
+		switch {
+		case c == '\\' && quote != '\'' && i+1 < len(cmd):
+			sb.WriteByte(c)
+			i++
+			c = cmd[i]
+		case quote == 0 && (c == '\'' || c == '"'):
+			quote = c
+		case c == quote:
+			quote = 0
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+// readTestReport parses the report of a test run: the report file if one is
+// configured, otherwise the command's output. Returns false if the report is
+// missing or has no test results.
+func readTestReport(parse resultParser, reportPath string, output []byte) (testReport, bool) {
+	data := output
+	if reportPath != "" {
+		var err error
+		if data, err = os.ReadFile(reportPath); err != nil {
+			return testReport{}, false
+		}
+	}
+	report, err := parse(data)
+	if err != nil || len(report.cases) == 0 {
+		return testReport{}, false
+	}
+	return report, true
+}
+
+// applyTestReport sets the verdict of a test run from its per-test results.
+// The run passes if no test failed and at least one passed; a command that
+// fails although its tests passed (e.g. a crash after the report) fails too.
+func applyTestReport(result *models.TestResult, cases []models.TestCaseResult, runErr error) {
+	result.Cases = cases
+
+	var failed []string
+	passed := 0
+	for _, c := range cases {
+		switch c.Status {
+		case models.TestCaseFailed:
+			failed = append(failed, c.Name)
+		case models.TestCasePassed:
+			passed++
+		}
+	}
+
+	switch {
+	case len(failed) > 0:
+		result.Error = "Failed: " + strings.Join(failed, ", ")
+	case passed == 0:
+		result.Error = "All matching tests were skipped"
+	case runErr != nil:
+		result.Error = runErr.Error()
+	default:
+		result.Passed = true
+	}
+}
+
 // RunAll executes all tests in the given array and returns aggregated results
-// All tests must pass for AllPassed to be true
+// All tests must pass for AllPassed to be true. Tests are grouped by runner
+// profile and package: up to test_runner.parallel packages are tested at
+// once, and with batch each package's tests run in a single command.
 func (r *TestRunner) RunAll(ctx context.Context, tests []models.TestSpec) models.TestResults {
+	return r.RunAllStreaming(ctx, tests, nil)
+}
+
+// RunAllStreaming is RunAll, also passing each line of the test commands'
+// output to onOutput as it is written
+func (r *TestRunner) RunAllStreaming(ctx context.Context, tests []models.TestSpec, onOutput OutputFunc) models.TestResults {
 	start := time.Now()
+	settings := r.store.GetSettings()
 
 	results := models.TestResults{
 		AllPassed: true,
-		Results:   make([]models.TestResult, 0, len(tests)),
+		Results:   make([]models.TestResult, len(tests)),
 	}
+	// Recorded with the results so the test history can tell flaky tests
+	// from tests whose code changed
+	results.Commit, results.Changes = r.store.codeState()
 
-	for _, test := range tests {
-		result := r.Run(ctx, test.File, test.Func)
-		results.Results = append(results.Results, result)
+	runners := make([]Settings, len(tests))
+	keys := make([]string, len(tests))
+	parallel := settings.GetTestParallel()
+	for i, test := range tests {
+		name, runner, err := testRunnerFor(settings, test)
+		if err != nil {
+			results.Results[i] = models.TestResult{Error: err.Error()}
+			continue
+		}
+		runners[i] = runner
+		keys[i] = name + " " + testPathFor(runner, test.File)
+
+		// Every run writes the same report file, so runs can't overlap
+		if runner.TestRunner.Report != "" {
+			parallel = 1
+		}
+	}
+
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for _, group := range groupTests(keys) {
+		// Taking a slot before starting keeps packages in order
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(group []int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			r.runPackage(ctx, runners[group[0]], tests, group, results.Results, onOutput)
+		}(group)
+	}
+	wg.Wait()
+
+	for _, result := range results.Results {
 		if !result.Passed {
 			results.AllPassed = false
 		}
@@ -137,13 +347,175 @@ func (r *TestRunner) RunAll(ctx context.Context, tests []models.TestSpec) models
 	return results
 }
 
-// RunAsync runs a test asynchronously and calls the callback with the result
-func (r *TestRunner) RunAsync(testFile, testFunc string, callback func(models.TestResult)) {
-	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-		defer cancel()
+// groupTests returns the indexes of tests grouped by key, in order of first
+// appearance. Tests with an empty key are left out.
+func groupTests(keys []string) [][]int {
+	var groups [][]int
+	byKey := make(map[string]int)
+	for i, key := range keys {
+		if key == "" {
+			continue
+		}
+		g, ok := byKey[key]
+		if !ok {
+			g = len(groups)
+			byKey[key] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], i)
+	}
+	return groups
+}
+
+// runPackage runs the tests at indexes, which share a package, and stores
+// their results at the same indexes of results
+func (r *TestRunner) runPackage(ctx context.Context, settings Settings, tests []models.TestSpec, indexes []int, results []models.TestResult, onOutput OutputFunc) {
+	// Batching needs per-test results to tell the tests apart. Go splits -run
+	// patterns at slashes, so subtests can't be part of a batch.
+	_, structured := resultParsers[settings.GetTestFormat()]
+	var batch []int
+	for _, i := range indexes {
+		if structured && settings.GetTestBatch() && !strings.Contains(tests[i].Func, "/") {
+			batch = append(batch, i)
+		} else {
+			results[i] = r.run(ctx, settings, tests[i].File, tests[i].Func, onOutput)
+		}
+	}
+
+	switch len(batch) {
+	case 0:
+	case 1:
+		results[batch[0]] = r.run(ctx, settings, tests[batch[0]].File, tests[batch[0]].Func, onOutput)
+	default:
+		r.runBatch(ctx, settings, tests, batch, results, onOutput)
+	}
+}
+
+// runBatch runs the tests at indexes in a single command, with {testFunc}
+// replaced by a pattern matching all of them, e.g. "(TestA|TestB)", and
+// splits the per-test results back into one result per test
+func (r *TestRunner) runBatch(ctx context.Context, settings Settings, tests []models.TestSpec, indexes []int, results []models.TestResult, onOutput OutputFunc) {
+	var funcs []string
+	for _, i := range indexes {
+		if !containsString(funcs, tests[i].Func) {
+			funcs = append(funcs, tests[i].Func)
+		}
+	}
+	testPath := testPathFor(settings, tests[indexes[0]].File)
+	pattern := "(" + strings.Join(funcs, "|") + ")"
+	run := r.execTests(ctx, settings, testPath, pattern, testPath+" "+pattern, onOutput)
+
+	report, ok := readTestReport(resultParsers[settings.GetTestFormat()], run.reportPath, run.stdout.Bytes())
+	if !ok {
+		log.Printf("No test results in batched run of %s, running its tests one by one", testPath)
+		for _, i := range indexes {
+			results[i] = r.run(ctx, settings, tests[i].File, tests[i].Func, onOutput)
+		}
+		return
+	}
+
+	// A failed test fails the whole command, which says nothing about the
+	// other tests in the batch. The exit status only counts if no test failed.
+	runErr := run.err
+	// Failed results that belong to none of the tests, such as a package that
+	// doesn't build, fail all of them
+	var shared []models.TestCaseResult
+	for _, c := range report.cases {
+		if c.Status != models.TestCaseFailed {
+			continue
+		}
+		runErr = nil
+		if !matchesAnyTest(c.Name, funcs) {
+			shared = append(shared, c)
+		}
+	}
+
+	for _, i := range indexes {
+		var cases []models.TestCaseResult
+		for _, c := range report.cases {
+			if matchesTest(c.Name, tests[i].Func) {
+				cases = append(cases, c)
+			}
+		}
+
+		result := models.TestResult{
+			Output:  run.withStderr(batchOutput(report, run, cases)),
+			RunTime: run.elapsed,
+		}
+		if len(cases) == 0 && len(shared) == 0 {
+			result.Error = "No matching test found - test file or function may not exist"
+		} else {
+			applyTestReport(&result, append(cases, shared...), runErr)
+		}
+		results[i] = result
+	}
+}
 
-		result := r.Run(ctx, testFile, testFunc)
-		callback(result)
-	}()
+// batchOutput returns the output of the given cases of a batched run, or
+// the whole output if the format doesn't separate it by test
+func batchOutput(report testReport, run *testRun, cases []models.TestCaseResult) string {
+	var sb strings.Builder
+	for _, c := range cases {
+		sb.WriteString(report.outputs[c.Name])
+	}
+	switch {
+	case sb.Len() > 0:
+		return sb.String()
+	case report.output != "":
+		return report.output
+	default:
+		return run.stdout.String()
+	}
+}
+
+// matchesTest reports whether a test case is testFunc or one of its subtests
+func matchesTest(name, testFunc string) bool {
+	return name == testFunc || strings.HasPrefix(name, testFunc+"/")
+}
+
+// matchesAnyTest reports whether a test case belongs to any of funcs
+func matchesAnyTest(name string, funcs []string) bool {
+	for _, testFunc := range funcs {
+		if matchesTest(name, testFunc) {
+			return true
+		}
+	}
+	return false
+}
+
+// OutputFunc receives a test command's output line by line as it is
+// written. test names what the command runs: a test such as
+// "auth_test.go:TestLogin", or a package and pattern for a batch. stream is
+// "stdout" or "stderr". It may be called from several goroutines at once.
+type OutputFunc func(test, stream, line string)
+
+// testWaitDelay is how long a canceled test command's output is still read
+const testWaitDelay = 5 * time.Second
+
+// lineWriter splits what is written to it into lines
+type lineWriter struct {
+	emit    func(line string)
+	partial []byte // Start of a line whose end hasn't been written yet
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	data := append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(strings.TrimRight(string(data[:i]), "\r"))
+		data = data[i+1:]
+	}
+	w.partial = append([]byte(nil), data...)
+	return len(p), nil
+}
+
+// flush emits the last line if it did not end with a newline
+func (w *lineWriter) flush() {
+	if len(w.partial) > 0 {
+		w.emit(string(w.partial))
+		w.partial = nil
+	}
 }
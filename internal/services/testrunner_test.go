@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -445,3 +446,300 @@ test_runner:
 		t.Error("Expected stderr to be captured")
 	}
 }
+
+// TestTestRunner_Run_TAPFormat tests that a structured format decides the
+// verdict per test, even when the output happens to contain the pass string
+func TestTestRunner_Run_TAPFormat(t *testing.T) {
+	content := `---
+test_runner:
+  command: printf 'ok 1 - login\nnot ok 2 - logout\n# PASS expected\n1..2\n'
+  pass_string: "PASS"
+  format: tap
+---
+# Kantext Tasks
+
+## Inbox
+`
+	store, cleanup := setupTestRunnerEnv(t, content)
+	defer cleanup()
+
+	runner := NewTestRunnerWithStore(store)
+	result := runner.Run(context.Background(), "test.go", "TestFunc")
+
+	if result.Passed {
+		t.Error("Expected the run to fail because of the failed TAP test")
+	}
+	if len(result.Cases) != 2 || result.Cases[1].Name != "logout" || result.Cases[1].Status != models.TestCaseFailed {
+		t.Errorf("Expected per-test results, got %+v", result.Cases)
+	}
+	if !strings.Contains(result.Error, "logout") {
+		t.Errorf("Expected the error to name the failed test, got %q", result.Error)
+	}
+}
+
+// TestTestRunner_Run_JUnitReport tests reading a JUnit report file written by
+// the test command
+func TestTestRunner_Run_JUnitReport(t *testing.T) {
+	content := `---
+test_runner:
+  command: sh -c 'echo "<testsuite><testcase name=\"a\"/><testcase name=\"b\"><skipped/></testcase></testsuite>" > report.xml'
+  format: junit
+  report: report.xml
+---
+# Kantext Tasks
+
+## Inbox
+`
+	store, cleanup := setupTestRunnerEnv(t, content)
+	defer cleanup()
+
+	runner := NewTestRunnerWithStore(store)
+	result := runner.Run(context.Background(), "test.go", "TestFunc")
+
+	if !result.Passed {
+		t.Errorf("Expected the run to pass, got error: %v", result.Error)
+	}
+	if len(result.Cases) != 2 || result.Cases[1].Status != models.TestCaseSkipped {
+		t.Errorf("Expected cases from the report, got %+v", result.Cases)
+	}
+}
+
+// TestTestRunner_RunAll_Batch tests that a package's tests run in one command
+// whose per-test results are split back into one result per test
+func TestTestRunner_RunAll_Batch(t *testing.T) {
+	content := `---
+test_runner:
+  command: sh ./fake_go_test.sh {testFunc} {testPath}
+  format: go-json
+  batch: true
+---
+# Kantext Tasks
+
+## Inbox
+`
+	store, cleanup := setupTestRunnerEnv(t, content)
+	defer cleanup()
+
+	// The fake go test logs its arguments and reports the matching tests;
+	// TestB fails and the others pass
+	script := `echo "$1 $2" >> calls.log
+for name in TestA TestB TestC; do
+  echo "$name" | grep -Eq "^$1$" || continue
+  case "$name" in
+    TestB) action=fail ;;
+    *) action=pass ;;
+  esac
+  printf '%s\n' "{\"Action\":\"output\",\"Package\":\"example\",\"Test\":\"$name\",\"Output\":\"running $name\\n\"}"
+  printf '%s\n' "{\"Action\":\"$action\",\"Package\":\"example\",\"Test\":\"$name\",\"Elapsed\":0.01}"
+done
+`
+	workDir := store.GetWorkingDir()
+	if err := os.WriteFile(filepath.Join(workDir, "fake_go_test.sh"), []byte(script), 0644); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+
+	runner := NewTestRunnerWithStore(store)
+	results := runner.RunAll(context.Background(), []models.TestSpec{
+		{File: "auth/auth_test.go", Func: "TestA"},
+		{File: "db/db_test.go", Func: "TestC"},
+		{File: "auth/login_test.go", Func: "TestB"},
+	})
+
+	calls, err := os.ReadFile(filepath.Join(workDir, "calls.log"))
+	if err != nil {
+		t.Fatalf("Failed to read calls: %v", err)
+	}
+	if got := string(calls); got != "(TestA|TestB) ./auth/\nTestC ./db/\n" {
+		t.Errorf("Expected one run per package, got:\n%s", got)
+	}
+
+	if results.AllPassed || len(results.Results) != 3 {
+		t.Fatalf("Expected three results with a failure, got %+v", results)
+	}
+	if a := results.Results[0]; !a.Passed || len(a.Cases) != 1 || a.Output != "running TestA\n" {
+		t.Errorf("Expected TestA to pass with its own output, got %+v", a)
+	}
+	if c := results.Results[1]; !c.Passed {
+		t.Errorf("Expected TestC to pass, got %+v", c)
+	}
+	if b := results.Results[2]; b.Passed || b.Error != "Failed: TestB" {
+		t.Errorf("Expected TestB to fail, got %+v", b)
+	}
+}
+
+// TestTestRunner_RunAll_DefaultRunner tests that the default runner tests a
+// package's tests with a single go test -json run
+func TestTestRunner_RunAll_DefaultRunner(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not available")
+	}
+	store, cleanup := setupTestRunnerEnv(t, "# Kantext Tasks\n\n## Inbox\n")
+	defer cleanup()
+
+	// Each run of the test binary is logged by TestMain
+	workDir := store.GetWorkingDir()
+	files := map[string]string{
+		"go.mod": "module example\n\ngo 1.21\n",
+		"calc/calc_test.go": `package calc
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	f, _ := os.OpenFile("runs.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	f.WriteString("run\n")
+	f.Close()
+	os.Exit(m.Run())
+}
+
+func TestAdd(t *testing.T) {}
+
+func TestSub(t *testing.T) { t.Fatal("wrong") }
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(workDir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	runner := NewTestRunnerWithStore(store)
+	results := runner.RunAll(context.Background(), []models.TestSpec{
+		{File: "calc/calc_test.go", Func: "TestAdd"},
+		{File: "calc/calc_test.go", Func: "TestSub"},
+	})
+
+	if runs, _ := os.ReadFile(filepath.Join(workDir, "calc", "runs.log")); string(runs) != "run\n" {
+		t.Errorf("Expected one run of the package, got %q", runs)
+	}
+	if len(results.Results) != 2 || !results.Results[0].Passed || results.Results[1].Passed {
+		t.Fatalf("Expected TestAdd to pass and TestSub to fail, got %+v", results)
+	}
+	if sub := results.Results[1]; sub.Error != "Failed: TestSub" || !strings.Contains(sub.Output, "wrong") {
+		t.Errorf("Expected TestSub's own failure, got %+v", sub)
+	}
+}
+
+// TestTestRunner_RunAll_Parallel tests that packages are tested at once up to
+// the configured limit
+func TestTestRunner_RunAll_Parallel(t *testing.T) {
+	content := `---
+test_runner:
+  command: sleep 1 && echo PASS
+  pass_string: "PASS"
+  parallel: 2
+---
+# Kantext Tasks
+
+## Inbox
+`
+	store, cleanup := setupTestRunnerEnv(t, content)
+	defer cleanup()
+
+	runner := NewTestRunnerWithStore(store)
+	start := time.Now()
+	results := runner.RunAll(context.Background(), []models.TestSpec{
+		{File: "a/a_test.go", Func: "TestA"},
+		{File: "b/b_test.go", Func: "TestB"},
+	})
+
+	if !results.AllPassed {
+		t.Errorf("Expected all tests to pass, got %+v", results)
+	}
+	if elapsed := time.Since(start); elapsed > 1800*time.Millisecond {
+		t.Errorf("Expected packages to be tested at once, took %v", elapsed)
+	}
+}
+
+func TestFillPlaceholder(t *testing.T) {
+	tests := []struct {
+		cmd, value, want string
+	}{
+		{"go test -run ^{testFunc}$", "TestLogin", "go test -run ^TestLogin$"},
+		{"go test -run ^{testFunc}$", "(TestA|TestB)", "go test -run ^'(TestA|TestB)'$"},
+		{"go test -run '^{testFunc}$'", "(TestA|TestB)", "go test -run '^(TestA|TestB)$'"},
+		{`jest -t "{testFunc}"`, "(a|b)", `jest -t "(a|b)"`},
+		{`echo \' {testFunc}`, "(a|b)", `echo \' '(a|b)'`},
+	}
+	for _, tt := range tests {
+		if got := fillPlaceholder(tt.cmd, "{testFunc}", tt.value); got != tt.want {
+			t.Errorf("fillPlaceholder(%q, %q) = %q, want %q", tt.cmd, tt.value, got, tt.want)
+		}
+	}
+}
+
+// TestTestRunner_RunAll_Profiles tests that each test runs with the runner
+// profile it names or whose glob matches its file, in the profile's directory
+// and with its environment
+func TestTestRunner_RunAll_Profiles(t *testing.T) {
+	content := `---
+test_runner:
+  command: echo "go {testPath} {testFunc} PASS"
+test_runners:
+  pytest:
+    command: echo "$KIND {testPath} {testFunc} PASS" && pwd
+    dir: services/api
+    env:
+      KIND: pytest
+    match: ["*.py"]
+  shell:
+    command: echo "shell {testFunc} PASS"
+---
+# Kantext Tasks
+
+## Inbox
+
+- [ ] Cross-language task
+  - id: task-multi001
+  - test: internal/auth/auth_test.go:TestLogin
+  - test: services/api/tests/test_users.py:test_list
+  - test: @shell scripts/check.sh:lint
+`
+	store, cleanup := setupTestRunnerEnv(t, content)
+	defer cleanup()
+	if err := os.MkdirAll(filepath.Join(store.GetWorkingDir(), "services", "api"), 0755); err != nil {
+		t.Fatalf("MkdirAll failed: %v", err)
+	}
+
+	task, err := store.Get("task-multi001")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if len(task.Tests) != 3 || task.Tests[2].Runner != "shell" || task.Tests[2].File != "scripts/check.sh" {
+		t.Fatalf("Expected the runner profile to be parsed, got %+v", task.Tests)
+	}
+
+	runner := NewTestRunnerWithStore(store)
+	results := runner.RunAll(context.Background(), task.Tests)
+	if !results.AllPassed {
+		t.Fatalf("Expected all tests to pass, got %+v", results)
+	}
+
+	want := []string{
+		"go ./internal/auth/ TestLogin PASS",
+		"pytest ./tests/ test_list PASS\n" + filepath.Join(store.GetWorkingDir(), "services", "api"),
+		"shell lint PASS",
+	}
+	for i, result := range results.Results {
+		if !strings.HasPrefix(result.Output, want[i]) {
+			t.Errorf("Test %d: expected output %q, got %q", i, want[i], result.Output)
+		}
+	}
+
+	// The profile is kept when the task is saved
+	title := "Cross-language task, renamed"
+	if _, err := store.Update(task.ID, models.UpdateTaskRequest{Title: &title}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	data, err := os.ReadFile(store.GetTasksFile())
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if !strings.Contains(string(data), "  - test: @shell scripts/check.sh:lint\n") || !strings.Contains(string(data), "    match:\n") {
+		t.Errorf("Expected the profile and its settings to be saved:\n%s", data)
+	}
+}
@@ -0,0 +1,313 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"kantext/internal/models"
+)
+
+// maxUndoEntries bounds the undo stack; the oldest changes are forgotten first
+const maxUndoEntries = 50
+
+// ErrNothingToUndo is returned by Undo when no change has been recorded
+var ErrNothingToUndo = errors.New("nothing to undo")
+
+// ErrNothingToRedo is returned by Redo when no change has been undone
+var ErrNothingToRedo = errors.New("nothing to redo")
+
+// ErrUndoConflict is returned when a task touched by the change being undone
+// or redone has been modified since, e.g. by an edit to TASKS.md on disk
+var ErrUndoConflict = errors.New("change can no longer be undone")
+
+// undoEntry records one board change as the tasks it touched, before and
+// after. A nil task means it did not exist on that side of the change.
+type undoEntry struct {
+	description string
+	before      map[string]*models.Task
+	after       map[string]*models.Task
+	archive     bool // Tasks the change takes off the board go to the archive (delete and restore)
+}
+
+// SetOnUndoRedo sets a callback invoked after a change is undone or redone.
+// The callback runs in its own goroutine.
+func (s *TaskStore) SetOnUndoRedo(callback func(models.UndoResult)) {
+	s.baseMu.Lock()
+	defer s.baseMu.Unlock()
+	s.onUndoRedo = callback
+}
+
+// snapshotTasksLocked copies every task so a change can be diffed afterwards.
+// Must be called with at least a read lock held.
+func (s *TaskStore) snapshotTasksLocked() map[string]*models.Task {
+	snapshot := make(map[string]*models.Task, len(s.tasks))
+	for id, task := range s.tasks {
+		snapshot[id] = copyTask(task)
+	}
+	return snapshot
+}
+
+// undoSignature summarises the fields that undo restores, to tell whether a
+// task has changed. Test results are left out: test runs are not undoable,
+// and undo keeps a task's latest results.
+func undoSignature(task *models.Task) string {
+	var sb strings.Builder
+	for _, field := range taskMergeFields {
+		if field.name == "test_status" || field.name == "test_results" {
+			continue
+		}
+		sb.WriteString(field.value(task))
+		sb.WriteByte(0)
+	}
+	return sb.String()
+}
+
+// undoEntryLocked builds an undo entry from the tasks that differ between
+// snapshot and the current board. Returns nil if nothing changed.
+// Must be called with at least a read lock held, before saving so that
+// external edits merged in by the save are not recorded as part of the change.
+func (s *TaskStore) undoEntryLocked(description string, snapshot map[string]*models.Task) *undoEntry {
+	entry := &undoEntry{
+		description: description,
+		before:      make(map[string]*models.Task),
+		after:       make(map[string]*models.Task),
+	}
+	changed := func(id string, before, after *models.Task) {
+		if before != nil && after != nil && before.Order == after.Order && undoSignature(before) == undoSignature(after) {
+			return
+		}
+		entry.before[id] = before
+		if after != nil {
+			after = copyTask(after)
+		}
+		entry.after[id] = after
+	}
+	for id, before := range snapshot {
+		changed(id, before, s.tasks[id])
+	}
+	for id, after := range s.tasks {
+		if _, ok := snapshot[id]; !ok {
+			changed(id, nil, after)
+		}
+	}
+
+	if len(entry.before) == 0 {
+		return nil
+	}
+	return entry
+}
+
+// updateDescription names an update in undo results, e.g. Move "Login" to done
+func updateDescription(before, after *models.Task) string {
+	if before.Column != after.Column {
+		return fmt.Sprintf("Move %q to %s", after.Title, after.Column)
+	}
+	return fmt.Sprintf("Edit %q", after.Title)
+}
+
+// pushUndoLocked records a completed change and clears the redo stack.
+// Caller must hold the write lock.
+func (s *TaskStore) pushUndoLocked(entry *undoEntry) {
+	if entry == nil {
+		return
+	}
+	s.undoStack = append(s.undoStack, entry)
+	if len(s.undoStack) > maxUndoEntries {
+		s.undoStack = s.undoStack[len(s.undoStack)-maxUndoEntries:]
+	}
+	s.redoStack = nil
+}
+
+// Undo reverts the most recent change to the board
+func (s *TaskStore) Undo() (*models.UndoResult, error) {
+	return s.undoRedo(true)
+}
+
+// Redo re-applies the most recently undone change
+func (s *TaskStore) Redo() (*models.UndoResult, error) {
+	return s.undoRedo(false)
+}
+
+// undoRedo moves the top entry from one stack to the other, restoring the
+// tasks it touched to their state on the far side of the change
+func (s *TaskStore) undoRedo(undo bool) (*models.UndoResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	action, from, to := "undo", &s.undoStack, &s.redoStack
+	target := func(e *undoEntry) map[string]*models.Task { return e.before }
+	expected := func(e *undoEntry) map[string]*models.Task { return e.after }
+	if !undo {
+		action, from, to = "redo", &s.redoStack, &s.undoStack
+		target, expected = expected, target
+	}
+	if len(*from) == 0 {
+		if undo {
+			return nil, ErrNothingToUndo
+		}
+		return nil, ErrNothingToRedo
+	}
+
+	entry := (*from)[len(*from)-1]
+	*from = (*from)[:len(*from)-1]
+
+	// Only restore tasks that are still exactly as the change left them, so
+	// later edits (which were not recorded here) are never thrown away
+	for id, want := range expected(entry) {
+		current, exists := s.tasks[id]
+		if exists != (want != nil) || (exists && undoSignature(current) != undoSignature(want)) {
+			return nil, fmt.Errorf("%w: task %s has changed since %q, so that change was dropped", ErrUndoConflict, id, entry.description)
+		}
+	}
+
+	previous := make(map[string]*models.Task, len(entry.before))
+	for id := range entry.before {
+		previous[id] = nil
+		if task, ok := s.tasks[id]; ok {
+			previous[id] = copyTask(task)
+		}
+	}
+
+	if err := s.loadArchiveLocked(); err != nil {
+		*from = append(*from, entry)
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	s.restoreTasksLocked(target(entry))
+	revertArchive, err := s.syncArchiveLocked(entry, previous, target(entry))
+	if err != nil {
+		s.restoreTasksLocked(previous)
+		*from = append(*from, entry)
+		return nil, err
+	}
+	if err := s.saveLocked(); err != nil {
+		s.restoreTasksLocked(previous)
+		if revertArchive() {
+			if err := s.saveArchiveLocked(); err != nil {
+				log.Printf("Error saving task archive: %v", err)
+			}
+		}
+		*from = append(*from, entry)
+		return nil, err
+	}
+	*to = append(*to, entry)
+
+	result := models.UndoResult{
+		Action:      action,
+		Description: entry.description,
+		CanUndo:     len(s.undoStack) > 0,
+		CanRedo:     len(s.redoStack) > 0,
+	}
+	for id := range entry.before {
+		result.TaskIDs = append(result.TaskIDs, id)
+	}
+	sort.Strings(result.TaskIDs)
+
+	s.recordHistory(undoHistoryEvents(entry, previous, target(entry), action)...)
+	s.notifyUndoRedo(result)
+
+	return &result, nil
+}
+
+// restoreTasksLocked replaces tasks with copies of the given states, deleting
+// those whose state is nil. Existing tasks keep their latest test results.
+// Caller must hold the write lock.
+func (s *TaskStore) restoreTasksLocked(states map[string]*models.Task) {
+	for id, state := range states {
+		if state == nil {
+			delete(s.tasks, id)
+			continue
+		}
+		restored := copyTask(state)
+		if current, ok := s.tasks[id]; ok {
+			restored.TestStatus = current.TestStatus
+			restored.TestsPassed = current.TestsPassed
+			restored.TestsTotal = current.TestsTotal
+			restored.LastOutput = current.LastOutput
+			restored.LastRun = current.LastRun
+		}
+		s.tasks[id] = restored
+	}
+}
+
+// syncArchiveLocked keeps the archive in step with an undo or redo: tasks put
+// back on the board leave the archive, and tasks taken off it are archived if
+// the change was a delete or restore. Returns a function that reverts the
+// in-memory archive and reports whether it changed. Caller must hold the write lock.
+func (s *TaskStore) syncArchiveLocked(entry *undoEntry, previous, states map[string]*models.Task) (func() bool, error) {
+	saved := make(map[string]*models.Task, len(states))
+	changed := false
+	now := time.Now().UTC()
+	for id, state := range states {
+		saved[id] = s.archive[id]
+		if state != nil {
+			if _, ok := s.archive[id]; ok {
+				delete(s.archive, id)
+				changed = true
+			}
+		} else if entry.archive && previous[id] != nil {
+			s.archive[id] = archivedCopy(previous[id], now)
+			changed = true
+		}
+	}
+
+	revert := func() bool {
+		for id, task := range saved {
+			if task == nil {
+				delete(s.archive, id)
+			} else {
+				s.archive[id] = task
+			}
+		}
+		return changed
+	}
+	if changed {
+		if err := s.saveArchiveLocked(); err != nil {
+			revert()
+			return nil, fmt.Errorf("%w: %v", ErrSaveFailed, err)
+		}
+	}
+	return revert, nil
+}
+
+// undoHistoryEvents describes an undo or redo in the history log. Tasks a
+// delete or restore takes off the board are archived, and those it puts back
+// are restored from the archive; other tasks are created or removed for good.
+func undoHistoryEvents(entry *undoEntry, before, after map[string]*models.Task, action string) []models.HistoryEvent {
+	ids := make([]string, 0, len(after))
+	for id := range after {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var events []models.HistoryEvent
+	for _, id := range ids {
+		b, a := before[id], after[id]
+		switch {
+		case b == nil && a != nil && entry.archive:
+			events = append(events, models.HistoryEvent{TaskID: id, Type: models.HistoryRestored, To: string(a.Column), Summary: fmt.Sprintf("Restored by %s: %s", action, a.Title)})
+		case b == nil && a != nil:
+			events = append(events, models.HistoryEvent{TaskID: id, Type: models.HistoryCreated, Summary: fmt.Sprintf("Recreated by %s: %s", action, a.Title)})
+		case b != nil && a == nil && entry.archive:
+			events = append(events, models.HistoryEvent{TaskID: id, Type: models.HistoryArchived, From: string(b.Column), Summary: fmt.Sprintf("Archived by %s: %s", action, b.Title)})
+		case b != nil && a == nil:
+			events = append(events, models.HistoryEvent{TaskID: id, Type: models.HistoryDeleted, Summary: fmt.Sprintf("Removed by %s: %s", action, b.Title)})
+		case b != nil:
+			events = append(events, taskChangeEvents(b, a, "")...)
+		}
+	}
+	return events
+}
+
+// notifyUndoRedo hands an undo or redo result to the registered callback, if any
+func (s *TaskStore) notifyUndoRedo(result models.UndoResult) {
+	s.baseMu.Lock()
+	callback := s.onUndoRedo
+	s.baseMu.Unlock()
+	if callback != nil {
+		go callback(result)
+	}
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"kantext/internal/models"
+)
+
+func TestTaskStore_Undo_Delete(t *testing.T) {
+	store, cleanup := setupTaskStoreEnv(t, subtaskTestContent)
+	defer cleanup()
+
+	if err := store.Delete("task-child1"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	result, err := store.Undo()
+	if err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if result.Action != "undo" || result.Description != `Delete "Payment form"` {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	// The deleted task and the subtask that was moved up are both restored
+	if strings.Join(result.TaskIDs, ",") != "task-child1,task-grandchild" {
+		t.Errorf("Expected restored tasks task-child1,task-grandchild, got %v", result.TaskIDs)
+	}
+	if result.CanUndo || !result.CanRedo {
+		t.Errorf("Expected only redo to be available, got %+v", result)
+	}
+
+	task, err := store.Get("task-child1")
+	if err != nil {
+		t.Fatalf("Expected deleted task to be restored: %v", err)
+	}
+	if task.Title != "Payment form" || len(task.Tests) != 1 {
+		t.Errorf("Restored task lost fields: %+v", task)
+	}
+	grandchild, _ := store.Get("task-grandchild")
+	if grandchild.Parent != "task-child1" {
+		t.Errorf("Expected subtask to be back under task-child1, got %q", grandchild.Parent)
+	}
+
+	if _, err := store.Redo(); err != nil {
+		t.Fatalf("Redo failed: %v", err)
+	}
+	if _, err := store.Get("task-child1"); err == nil {
+		t.Error("Expected redo to delete the task again")
+	}
+	if _, err := store.Redo(); !errors.Is(err, ErrNothingToRedo) {
+		t.Errorf("Expected ErrNothingToRedo, got %v", err)
+	}
+}
+
+func TestTaskStore_Undo_MoveAndEdit(t *testing.T) {
+	store, cleanup := setupTaskStoreEnv(t, subtaskTestContent)
+	defer cleanup()
+
+	if _, err := store.Reorder("task-grandchild", models.ColumnDone, 0); err != nil {
+		t.Fatalf("Reorder failed: %v", err)
+	}
+	title := "Card checks"
+	if _, err := store.Update("task-grandchild", models.UpdateTaskRequest{Title: &title}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	// Changes are undone most recent first
+	result, err := store.Undo()
+	if err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if result.Description != `Edit "Card checks"` {
+		t.Errorf("Expected the edit to be undone first, got %q", result.Description)
+	}
+	task, _ := store.Get("task-grandchild")
+	if task.Title != "Card validation" || task.Column != models.ColumnDone {
+		t.Errorf("Expected title reverted and column kept, got %q in %s", task.Title, task.Column)
+	}
+
+	if _, err := store.Undo(); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	task, _ = store.Get("task-grandchild")
+	if task.Column != models.ColumnInbox {
+		t.Errorf("Expected task moved back to inbox, got %s", task.Column)
+	}
+	if _, err := store.Undo(); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("Expected ErrNothingToUndo, got %v", err)
+	}
+
+	// A new change clears the redo stack
+	if _, err := store.Update("task-grandchild", models.UpdateTaskRequest{Title: &title}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if _, err := store.Redo(); !errors.Is(err, ErrNothingToRedo) {
+		t.Errorf("Expected ErrNothingToRedo after a new change, got %v", err)
+	}
+}
+
+func TestTaskStore_Undo_Conflict(t *testing.T) {
+	store, cleanup := setupTaskStoreEnv(t, subtaskTestContent)
+	defer cleanup()
+
+	title := "Card checks"
+	if _, err := store.Update("task-grandchild", models.UpdateTaskRequest{Title: &title}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	// Simulate an edit that was not recorded, e.g. merged in from disk
+	store.mu.Lock()
+	store.tasks["task-grandchild"].Title = "Edited on disk"
+	store.mu.Unlock()
+
+	if _, err := store.Undo(); !errors.Is(err, ErrUndoConflict) {
+		t.Fatalf("Expected ErrUndoConflict, got %v", err)
+	}
+	task, _ := store.Get("task-grandchild")
+	if task.Title != "Edited on disk" {
+		t.Errorf("Expected the newer edit to be kept, got %q", task.Title)
+	}
+	if _, err := store.Undo(); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("Expected the conflicting change to be dropped, got %v", err)
+	}
+}
+
+func TestTaskStore_Undo_KeepsTestResults(t *testing.T) {
+	store, cleanup := setupTaskStoreEnv(t, subtaskTestContent)
+	defer cleanup()
+
+	title := "Card checks"
+	if _, err := store.Update("task-grandchild", models.UpdateTaskRequest{Title: &title}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if _, err := store.UpdateTestResult("task-grandchild", models.TestResult{Passed: false}); err != nil {
+		t.Fatalf("UpdateTestResult failed: %v", err)
+	}
+
+	if _, err := store.Undo(); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	task, _ := store.Get("task-grandchild")
+	if task.Title != "Card validation" || task.TestStatus != models.TestStatusFailed {
+		t.Errorf("Expected title reverted and test result kept, got %q (%s)", task.Title, task.TestStatus)
+	}
+}
+
+func TestTaskStore_Undo_Bounded(t *testing.T) {
+	store, cleanup := setupTaskStoreEnv(t, subtaskTestContent)
+	defer cleanup()
+
+	for i := 0; i < maxUndoEntries+5; i++ {
+		if _, err := store.Create(models.CreateTaskRequest{Title: "Task"}); err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+	}
+
+	undone := 0
+	for {
+		if _, err := store.Undo(); err != nil {
+			if !errors.Is(err, ErrNothingToUndo) {
+				t.Fatalf("Undo failed: %v", err)
+			}
+			break
+		}
+		undone++
+	}
+	if undone != maxUndoEntries {
+		t.Errorf("Expected %d changes to be undoable, got %d", maxUndoEntries, undone)
+	}
+	if count := len(store.GetAll()); count != 4+5 {
+		t.Errorf("Expected the 5 oldest creates to remain, got %d tasks", count)
+	}
+}
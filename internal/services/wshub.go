@@ -4,12 +4,16 @@ import (
 	"log"
 	"sync"
 
+	"kantext/internal/models"
+
 	"github.com/gorilla/websocket"
 )
 
 // Message types for WebSocket communication
 const (
-	MsgTypeTasksUpdated = "tasks_updated"
+	MsgTypeTasksUpdated   = "tasks_updated"
+	MsgTypeMergeConflicts = "merge_conflicts"
+	MsgTypeUndoRedo       = "undo_redo"
 )
 
 // WSMessage represents a WebSocket message sent to clients
@@ -106,6 +110,25 @@ func (h *WSHub) NotifyTasksUpdated() {
 	})
 }
 
+// NotifyMergeConflicts broadcasts edits to TASKS.md that could not be merged
+// automatically with in-memory changes
+func (h *WSHub) NotifyMergeConflicts(conflicts []models.MergeConflict) {
+	log.Printf("Broadcasting %d merge conflicts to %d clients", len(conflicts), h.ClientCount())
+	h.Broadcast(WSMessage{
+		Type: MsgTypeMergeConflicts,
+		Data: conflicts,
+	})
+}
+
+// NotifyUndoRedo broadcasts a board change that was undone or redone
+func (h *WSHub) NotifyUndoRedo(result models.UndoResult) {
+	log.Printf("Broadcasting %s of %q to %d clients", result.Action, result.Description, h.ClientCount())
+	h.Broadcast(WSMessage{
+		Type: MsgTypeUndoRedo,
+		Data: result,
+	})
+}
+
 // ClientCount returns the number of connected clients
 func (h *WSHub) ClientCount() int {
 	h.mu.RLock()
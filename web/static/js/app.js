@@ -1,4 +1,6 @@
-const API_BASE = '/api';
+// Boards are served under /b/{board}/; the first board is also served from /
+const BOARD_BASE = (window.location.pathname.match(/^\/b\/[^/]+/) || [''])[0];
+const API_BASE = BOARD_BASE + '/api';
 
 const DEFAULT_COLUMN_SLUGS = new Set(['inbox', 'in_progress', 'done']);
 
@@ -536,7 +538,7 @@ function fuzzyMatch(text, query) {
 
 /**
  * Check if a task matches the search query
- * Searches in: title, acceptance_criteria, author, priority, tags
+ * Searches in: title, acceptance_criteria, description, author, priority, tags
  * @param {Object} task - Task object
  * @param {string} query - Search query
  * @returns {boolean} - True if task matches
@@ -547,6 +549,7 @@ function taskMatchesSearch(task, query) {
     const searchableFields = [
         task.title || '',
         task.acceptance_criteria || '',
+        task.description || '',
         task.updated_by || task.created_by || '',
         task.priority || '',
         ...(task.tags || []) // Include all tags in searchable fields
@@ -1671,7 +1674,7 @@ if (customDialog) {
 
 function connectWebSocket() {
     const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
-    const wsUrl = `${protocol}//${window.location.host}/ws`;
+    const wsUrl = `${protocol}//${window.location.host}${BOARD_BASE}/ws`;
 
     console.log('[WS] Attempting connection to:', wsUrl);
     console.log('[WS] Current location:', window.location.href);
@@ -1723,12 +1726,32 @@ function handleWebSocketMessage(msg) {
             // Both use differential rendering to avoid flashing
             loadColumns().then(() => loadTasks());
             break;
+        case 'merge_conflicts':
+            // TASKS.md was edited on disk while the server had unsaved changes
+            handleMergeConflicts(msg.data);
+            break;
+        case 'undo_redo':
+            // A change was undone or redone (from any client or an MCP agent)
+            if (msg.data) {
+                const verb = msg.data.action === 'redo' ? 'Redid' : 'Undid';
+                showNotification(`${verb}: ${msg.data.description}`, 'info');
+            }
+            loadColumns().then(() => loadTasks());
+            break;
         case 'task_moved':
             // Handle specific task move event if server sends it
             if (msg.task_id && msg.column) {
                 handleRemoteTaskMove(msg.task_id, msg.column);
             }
             break;
+        case 'test_job':
+            // A test job started or finished (from any client)
+            loadTasks();
+            break;
+        case 'test_output':
+            // A line of a running test job's output
+            handleTestOutput(msg.data);
+            break;
         case 'ai_output':
             // Claude output streaming
             handleAIOutput(msg.data);
@@ -1750,6 +1773,27 @@ function handleWebSocketMessage(msg) {
     }
 }
 
+/**
+ * Shows a warning for each edit that could not be merged with TASKS.md on disk.
+ * The server keeps one side; the other value is shown so it can be re-applied.
+ */
+function handleMergeConflicts(conflicts) {
+    if (!Array.isArray(conflicts)) return;
+
+    for (const conflict of conflicts) {
+        const subject = conflict.title ? `"${conflict.title}"` : 'Board';
+        const kept = conflict.resolution === 'kept_ours' ? conflict.ours
+            : conflict.resolution === 'kept_theirs' ? conflict.theirs
+            : 'merged';
+        const lost = conflict.resolution === 'kept_ours' ? conflict.theirs : conflict.ours;
+        showNotification(
+            `${subject}: conflicting edit to ${conflict.field} in TASKS.md. Kept "${kept}", discarded "${lost}".`,
+            'warning',
+            10000
+        );
+    }
+}
+
 /**
  * Handles a task move event from WebSocket (another client moved a task).
  * Only updates if the local state differs from the remote state.
@@ -1848,6 +1892,27 @@ function setupEventListeners() {
         openNewTaskModal();
     });
 
+    // Global keyboard shortcuts: Ctrl/Cmd+Z to undo, Ctrl/Cmd+Shift+Z or Ctrl+Y to redo
+    document.addEventListener('keydown', (e) => {
+        if (!(e.ctrlKey || e.metaKey)) return;
+        const key = e.key.toLowerCase();
+        const isUndo = key === 'z' && !e.shiftKey;
+        const isRedo = (key === 'z' && e.shiftKey) || (key === 'y' && !e.shiftKey);
+        if (!isUndo && !isRedo) return;
+
+        // Leave text fields to the browser's own undo
+        const activeEl = document.activeElement;
+        const isTyping = activeEl && (
+            activeEl.tagName === 'INPUT' ||
+            activeEl.tagName === 'TEXTAREA' ||
+            activeEl.isContentEditable
+        );
+        if (isTyping) return;
+
+        e.preventDefault();
+        undoRedo(isUndo ? 'undo' : 'redo');
+    });
+
     // Form submission
     if (taskForm) {
         taskForm.addEventListener('submit', handleFormSubmit);
@@ -2028,7 +2093,13 @@ function tasksEqual(oldTasks, newTasks) {
             oldTask.tests_total !== newTask.tests_total ||
             oldTask.requires_test !== newTask.requires_test ||
             oldTask.acceptance_criteria !== newTask.acceptance_criteria ||
+            oldTask.description !== newTask.description ||
             oldTask.updated_at !== newTask.updated_at ||
+            oldTask.revision !== newTask.revision ||
+            oldTask.blocked !== newTask.blocked ||
+            oldTask.parent !== newTask.parent ||
+            oldTask.subtasks_done !== newTask.subtasks_done ||
+            (oldTask.children || []).join(',') !== (newTask.children || []).join(',') ||
             oldTask.updated_by !== newTask.updated_by ||
             oldTask.created_by !== newTask.created_by ||
             !testsArrayEqual(oldTask.tests, newTask.tests)) {
@@ -2049,12 +2120,33 @@ async function createTask(data) {
     return response.json();
 }
 
-async function updateTask(id, data) {
+async function updateTask(id, data, revision) {
+    const headers = { 'Content-Type': 'application/json' };
+    // Only overwrite the task if nobody else changed it since it was loaded
+    if (revision) headers['If-Match'] = `"${revision}"`;
+
     const response = await fetch(`${API_BASE}/tasks/${id}`, {
         method: 'PUT',
-        headers: { 'Content-Type': 'application/json' },
+        headers: headers,
         body: JSON.stringify(data)
     });
+    if (response.status === 409) {
+        const body = await response.json().catch(() => ({}));
+        // A 409 without the current task means the task is blocked, not stale
+        const error = new Error(body.current ? 'Task was modified elsewhere' : (body.error || 'Task is blocked'));
+        if (body.current) {
+            error.conflict = true;
+        } else {
+            error.invalid = true;
+        }
+        throw error;
+    }
+    if (response.status === 400) {
+        const body = await response.json().catch(() => ({}));
+        const error = new Error(body.error || 'Invalid task update');
+        error.invalid = true;
+        throw error;
+    }
     if (!response.ok) throw new Error('Failed to update task');
     return response.json();
 }
@@ -2066,6 +2158,51 @@ async function deleteTask(id) {
     if (!response.ok) throw new Error('Failed to delete task');
 }
 
+/**
+ * Undoes or redoes the last board change. The server broadcasts the result
+ * over the WebSocket, which shows the notification and reloads the board.
+ */
+async function undoRedo(action) {
+    try {
+        const response = await fetch(`${API_BASE}/${action}`, { method: 'POST' });
+        if (!response.ok) {
+            const body = await response.json().catch(() => ({}));
+            showNotification(body.error || `Failed to ${action}`, 'warning');
+        }
+    } catch (error) {
+        console.error(`Failed to ${action}:`, error);
+        showNotification(`Failed to ${action}`, 'error');
+    }
+}
+
+async function addCriterion(id, text) {
+    const response = await fetch(`${API_BASE}/tasks/${id}/criteria`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ text })
+    });
+    if (!response.ok) throw new Error('Failed to add checklist item');
+    return response.json();
+}
+
+async function updateCriterion(id, item, data) {
+    const response = await fetch(`${API_BASE}/tasks/${id}/criteria/${item}`, {
+        method: 'PUT',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(data)
+    });
+    if (!response.ok) throw new Error('Failed to update checklist item');
+    return response.json();
+}
+
+async function deleteCriterion(id, item) {
+    const response = await fetch(`${API_BASE}/tasks/${id}/criteria/${item}`, {
+        method: 'DELETE'
+    });
+    if (!response.ok) throw new Error('Failed to remove checklist item');
+    return response.json();
+}
+
 async function runTest(id) {
     const response = await fetch(`${API_BASE}/tasks/${id}/run`, {
         method: 'POST'
@@ -2074,6 +2211,22 @@ async function runTest(id) {
     return response.json();
 }
 
+async function fetchTestJob(id) {
+    const response = await fetch(`${API_BASE}/jobs/${id}`);
+    if (!response.ok) throw new Error('Failed to fetch test job');
+    return response.json();
+}
+
+// Polls a test job until it has finished. Output arrives over the WebSocket
+// meanwhile, so polling only needs to notice the end of the run.
+async function waitForTestJob(job) {
+    while (job.status === 'running') {
+        await new Promise(resolve => setTimeout(resolve, 1000));
+        job = await fetchTestJob(job.id);
+    }
+    return job;
+}
+
 function renderColumns() {
     if (!board) return;
     board.innerHTML = '';
@@ -2412,6 +2565,12 @@ function updateTaskCard(card, task) {
         existingStaleIcon.remove();
     }
 
+    // Handle blocked icon (title lists the blocking tasks, so always rebuild it)
+    card.querySelector('.task-blocked-icon')?.remove();
+    if (task.blocked && taskHeader) {
+        taskHeader.insertAdjacentHTML('afterbegin', createBlockedIconHTML(task));
+    }
+
     // Handle criteria icon
     const hasCriteria = task.acceptance_criteria && task.acceptance_criteria.trim() !== '';
     const existingCriteriaIcon = card.querySelector('.task-criteria-icon');
@@ -2445,6 +2604,7 @@ function updateTaskCard(card, task) {
     const authorHtml = `<span class="task-meta-item" title="Author: ${escapeHtml(fullAuthor)}">${userIcon}<span class="task-author">${escapeHtml(authorName)}</span></span>`;
     const fullDate = task.updated_at ? new Date(task.updated_at).toLocaleString() : (task.created_at ? new Date(task.created_at).toLocaleString() : '');
     const dateHtml = dateText ? `<span class="task-meta-item" title="Updated: ${escapeHtml(fullDate)}">${calendarIcon}<span class="task-date">${escapeHtml(dateText)}</span></span>` : '';
+    const checklistHtml = createChecklistProgressHTML(task) + createSubtaskProgressHTML(task);
 
     // Helper to build the new meta HTML
     const buildMetaHtml = () => {
@@ -2458,12 +2618,14 @@ function updateTaskCard(card, task) {
             return `<div class="task-meta">
                 ${authorHtml}
                 ${dateHtml}
+                ${checklistHtml}
                 ${testInfoHtml}
             </div>`;
         } else if (requiresTest) {
             return `<div class="task-meta">
                 ${authorHtml}
                 ${dateHtml}
+                ${checklistHtml}
                 <span class="task-meta-item" title="No tests configured">${beakerIcon}<span class="task-test-count no-test">No test</span></span>
             </div>`;
         } else {
@@ -2471,6 +2633,7 @@ function updateTaskCard(card, task) {
             return `<div class="task-meta">
                 ${authorHtml}
                 ${dateHtml}
+                ${checklistHtml}
             </div>`;
         }
     };
@@ -2590,6 +2753,53 @@ function createTestProgressHTML(task) {
     </span>`;
 }
 
+/**
+ * Creates the lock icon shown on cards that are waiting on other tasks
+ */
+function createBlockedIconHTML(task) {
+    const blockers = (task.blocked_by || []).map(id => {
+        const blocker = tasks.find(t => t.id === id);
+        return blocker ? blocker.title : id;
+    });
+    const title = `Blocked by: ${blockers.join(', ')}`;
+    return `<span class="task-blocked-icon" title="${escapeHtml(title)}">
+        <svg xmlns="http://www.w3.org/2000/svg" width="14" height="14" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2" stroke-linecap="round" stroke-linejoin="round">
+            <rect width="18" height="11" x="3" y="11" rx="2" ry="2"/>
+            <path d="M7 11V7a5 5 0 0 1 10 0v4"/>
+        </svg>
+    </span>`;
+}
+
+/**
+ * Creates the checklist progress indicator (e.g. "3/5") for a task card.
+ * Returns an empty string if the task has no checklist.
+ */
+function createChecklistProgressHTML(task) {
+    const items = task.checklist || [];
+    if (items.length === 0) return '';
+
+    const done = items.filter(item => item.done).length;
+    const complete = done === items.length ? ' complete' : '';
+    const checkIcon = `<svg class="meta-icon" xmlns="http://www.w3.org/2000/svg" width="12" height="12" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2" stroke-linecap="round" stroke-linejoin="round"><rect width="18" height="18" x="3" y="3" rx="2"/><path d="m9 12 2 2 4-4"/></svg>`;
+
+    return `<span class="task-meta-item task-checklist-progress${complete}" title="${done} of ${items.length} criteria done">${checkIcon}<span>${done}/${items.length}</span></span>`;
+}
+
+/**
+ * Creates the subtask progress indicator (e.g. "2/3") for a parent task card.
+ * Returns an empty string if the task has no subtasks.
+ */
+function createSubtaskProgressHTML(task) {
+    const total = (task.children || []).length;
+    if (total === 0) return '';
+
+    const done = task.subtasks_done || 0;
+    const complete = done === total ? ' complete' : '';
+    const treeIcon = `<svg class="meta-icon" xmlns="http://www.w3.org/2000/svg" width="12" height="12" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2" stroke-linecap="round" stroke-linejoin="round"><path d="M6 3v12"/><path d="M6 9h9"/><path d="M6 15h9"/><circle cx="18" cy="9" r="2"/><circle cx="18" cy="15" r="2"/></svg>`;
+
+    return `<span class="task-meta-item task-subtask-progress${complete}" title="${done} of ${total} subtasks done">${treeIcon}<span>${done}/${total}</span></span>`;
+}
+
 /**
  * Extract first name from a full name (e.g., "Tate McCormick" -> "Tate")
  * Returns "Uncommitted Task" for uncommitted tasks (empty author or "Not Committed Yet")
@@ -2854,6 +3064,7 @@ function createTaskCard(task) {
     const authorHtml = `<span class="task-meta-item" title="Author: ${escapeHtml(fullAuthor)}">${userIcon}<span class="task-author">${escapeHtml(authorName)}</span></span>`;
     const fullDate = task.updated_at ? new Date(task.updated_at).toLocaleString() : (task.created_at ? new Date(task.created_at).toLocaleString() : '');
     const dateHtml = dateText ? `<span class="task-meta-item" title="Updated: ${escapeHtml(fullDate)}">${calendarIcon}<span class="task-date">${escapeHtml(dateText)}</span></span>` : '';
+    const checklistHtml = createChecklistProgressHTML(task) + createSubtaskProgressHTML(task);
 
     if (hasTest) {
         // Task has test configured - show author, date, and test progress indicator
@@ -2866,6 +3077,7 @@ function createTaskCard(task) {
         metaHtml = `<div class="task-meta">
                ${authorHtml}
                ${dateHtml}
+               ${checklistHtml}
                ${testInfoHtml}
            </div>`;
     } else if (requiresTest) {
@@ -2873,6 +3085,7 @@ function createTaskCard(task) {
         metaHtml = `<div class="task-meta">
                ${authorHtml}
                ${dateHtml}
+               ${checklistHtml}
                <span class="task-meta-item" title="No tests configured">${beakerIcon}<span class="task-test-count no-test">No test</span></span>
            </div>`;
     } else {
@@ -2880,6 +3093,7 @@ function createTaskCard(task) {
         metaHtml = `<div class="task-meta">
                ${authorHtml}
                ${dateHtml}
+               ${checklistHtml}
            </div>`;
     }
 
@@ -2904,12 +3118,15 @@ function createTaskCard(task) {
            </span>`
         : '';
 
+    // Build blocked icon - show a lock while the task waits on other tasks
+    const blockedIconHtml = task.blocked ? createBlockedIconHTML(task) : '';
+
     // Build tags HTML - tags are escaped via escapeHtml in createTagBadgesHtml
     const tagsHtml = createTagBadgesHtml(task.tags);
 
     card.innerHTML = `
         <div class="task-header">
-            ${staleIconHtml}${criteriaIconHtml}<span class="task-title task-title-clickable${task.column === 'done' ? ' task-done' : ''}" title="Click to copy task ID">${escapeHtml(task.title)}</span>
+            ${staleIconHtml}${blockedIconHtml}${criteriaIconHtml}<span class="task-title task-title-clickable${task.column === 'done' ? ' task-done' : ''}" title="Click to copy task ID">${escapeHtml(task.title)}</span>
             ${actionsHtml}
         </div>
         ${metaHtml}
@@ -3174,6 +3391,7 @@ function showOutput(task, results = null) {
 
     // Clear previous content
     testOutput.innerHTML = '';
+    delete testOutput.dataset.jobId;
 
     // Check if output is gotestsum JSON format
     if (isGotestsumJSON(output)) {
@@ -3211,6 +3429,21 @@ function showOutput(task, results = null) {
     outputModal.showModal();
 }
 
+// Appends a streamed line of test output when the output modal shows that
+// task's run
+function handleTestOutput(line) {
+    if (!line || !outputModal.open || outputModalTask?.id !== line.task_id) return;
+    if (outputModalTask.test_status !== 'running') return;
+
+    if (testOutput.dataset.jobId !== line.job_id) {
+        testOutput.dataset.jobId = line.job_id;
+        testOutput.textContent = '';
+        testOutput.classList.remove('rich-output');
+    }
+    testOutput.textContent += line.line + '\n';
+    testOutput.parentElement.scrollTop = testOutput.parentElement.scrollHeight;
+}
+
 function formatDateTime(dateString) {
     if (!dateString) return null;
     const date = new Date(dateString);
@@ -3324,6 +3557,9 @@ function openTaskPanel(task) {
     const titleEl = document.getElementById('panel-title');
     const titleInput = document.getElementById('panel-title-input');
     const criteriaInput = document.getElementById('panel-criteria-input');
+    const descriptionInput = document.getElementById('panel-description-input');
+    const blockedByInput = document.getElementById('panel-blocked-by-input');
+    const parentInput = document.getElementById('panel-parent-input');
     const testsContainer = document.getElementById('panel-tests-container');
     const taskIdEl = document.getElementById('panel-task-id');
 
@@ -3331,6 +3567,9 @@ function openTaskPanel(task) {
     if (titleEl) titleEl.textContent = task.title || '';
     if (titleInput) titleInput.value = task.title || '';
     if (criteriaInput) criteriaInput.value = task.acceptance_criteria || '';
+    if (descriptionInput) descriptionInput.value = task.description || '';
+    if (blockedByInput) blockedByInput.value = (task.blocked_by || []).join(', ');
+    if (parentInput) parentInput.value = task.parent || '';
     if (taskIdEl) taskIdEl.textContent = task.id;
     if (panelRequiresTestCheckbox) panelRequiresTestCheckbox.checked = task.requires_test || false;
 
@@ -3400,6 +3639,9 @@ function openTaskPanel(task) {
     // Load tags into panel
     loadPanelTags(task);
 
+    // Load checklist into panel
+    renderPanelChecklist(task);
+
     // Update metadata display
     updatePanelMetadata(task);
 
@@ -3407,6 +3649,9 @@ function openTaskPanel(task) {
     panelOriginalValues = {
         title: task.title || '',
         acceptance_criteria: task.acceptance_criteria || '',
+        description: task.description || '',
+        blocked_by: (task.blocked_by || []).join(', '),
+        parent: task.parent || '',
         priority: task.priority || 'medium',
         tags: task.tags ? [...task.tags] : [],
         requires_test: task.requires_test || false,
@@ -3485,6 +3730,9 @@ async function tryCloseTaskPanel() {
 function getPanelFormValues() {
     const titleEl = document.getElementById('panel-title');
     const criteriaInput = document.getElementById('panel-criteria-input');
+    const descriptionInput = document.getElementById('panel-description-input');
+    const blockedByInput = document.getElementById('panel-blocked-by-input');
+    const parentInput = document.getElementById('panel-parent-input');
     const priorityRadio = panelTaskForm?.querySelector('input[name="panel-priority"]:checked');
 
     // Collect tests array from grouped test entries
@@ -3506,6 +3754,9 @@ function getPanelFormValues() {
     return {
         title: titleEl?.textContent || '',
         acceptance_criteria: criteriaInput?.value || '',
+        description: descriptionInput?.value || '',
+        blocked_by: blockedByInput?.value || '',
+        parent: parentInput?.value?.trim() || '',
         priority: priorityRadio?.value || 'medium',
         tags: [...panelTags],
         requires_test: panelRequiresTestCheckbox?.checked || false,
@@ -3513,6 +3764,13 @@ function getPanelFormValues() {
     };
 }
 
+/**
+ * Splits a comma-separated list of task IDs
+ */
+function parseIdList(value) {
+    return (value || '').split(',').map(id => id.trim()).filter(id => id !== '');
+}
+
 /**
  * Compares two test arrays for equality
  */
@@ -3552,6 +3810,9 @@ function panelHasChanges() {
 
     return current.title !== panelOriginalValues.title ||
            current.acceptance_criteria !== panelOriginalValues.acceptance_criteria ||
+           current.description !== panelOriginalValues.description ||
+           parseIdList(current.blocked_by).join(',') !== parseIdList(panelOriginalValues.blocked_by).join(',') ||
+           current.parent !== panelOriginalValues.parent ||
            current.priority !== panelOriginalValues.priority ||
            !tagsAreEqual(current.tags, panelOriginalValues.tags) ||
            current.requires_test !== panelOriginalValues.requires_test ||
@@ -3631,6 +3892,7 @@ async function saveTitleEdit() {
         await updateTask(currentPanelTask.id, {
             title: newTitle,
             acceptance_criteria: formData.get('acceptance_criteria'),
+            description: formData.get('description'),
             priority: formData.get('panel-priority')
         });
         showNotification('Title updated', 'success');
@@ -3652,6 +3914,50 @@ async function saveTitleEdit() {
     }
 }
 
+/**
+ * Renders the acceptance-criteria checklist for the task shown in the panel
+ */
+function renderPanelChecklist(task) {
+    const list = document.getElementById('panel-checklist');
+    const progress = document.getElementById('panel-checklist-progress');
+    if (!list) return;
+
+    const items = task.checklist || [];
+    list.innerHTML = items.map((item, index) => `
+        <li class="checklist-item${item.done ? ' done' : ''}" data-item="${index + 1}">
+            <input type="checkbox" ${item.done ? 'checked' : ''} aria-label="Mark criterion as done">
+            <span class="checklist-item-text">${escapeHtml(item.text)}</span>
+            <button type="button" class="checklist-item-remove" title="Remove item">&times;</button>
+        </li>`).join('');
+
+    if (progress) {
+        const done = items.filter(item => item.done).length;
+        progress.textContent = items.length > 0 ? `${done}/${items.length} done` : '';
+    }
+}
+
+/**
+ * Applies a checklist change from the panel. Checklist edits are saved
+ * immediately rather than with the Save button, so the panel's task (and
+ * its revision) is refreshed from the response.
+ */
+async function applyPanelChecklistChange(change) {
+    if (!currentPanelTask) return;
+    const taskId = currentPanelTask.id;
+
+    try {
+        const updated = await change(taskId);
+        currentPanelTask = updated;
+        renderPanelChecklist(updated);
+        await loadTasks();
+        currentPanelTask = tasks.find(t => t.id === taskId) || updated;
+    } catch (error) {
+        console.error('Failed to update checklist:', error);
+        showNotification(error.message, 'error');
+        renderPanelChecklist(currentPanelTask);
+    }
+}
+
 /**
  * Updates the metadata display in the panel
  */
@@ -3716,6 +4022,9 @@ async function handlePanelFormSubmit(e) {
     const data = {
         title: title,
         acceptance_criteria: formData.get('acceptance_criteria'),
+        description: formData.get('description'),
+        blocked_by: parseIdList(formValues.blocked_by),
+        parent: formValues.parent,
         priority: formData.get('panel-priority'),
         tags: panelTags,
         requires_test: panelRequiresTestCheckbox?.checked || false,
@@ -3723,12 +4032,21 @@ async function handlePanelFormSubmit(e) {
     };
 
     try {
-        await updateTask(currentPanelTask.id, data);
+        await updateTask(currentPanelTask.id, data, currentPanelTask.revision);
         showNotification(`"${data.title}" was updated successfully`, 'success');
         closeTaskPanel();
         await loadTasks();
     } catch (error) {
         console.error('Failed to update task:', error);
+        if (error.invalid) {
+            showNotification(error.message, 'error');
+            return;
+        }
+        if (error.conflict) {
+            showNotification('This task was changed elsewhere. Reopen it to see the latest version.', 'error');
+            await loadTasks();
+            return;
+        }
         showNotification('Failed to update task. Please try again.', 'error');
     }
 }
@@ -3883,11 +4201,51 @@ function initTaskPanel() {
         await tryCloseTaskPanel();
     });
 
+    // Checklist: toggle, remove and add items
+    const checklistList = document.getElementById('panel-checklist');
+    const checklistInput = document.getElementById('panel-checklist-input');
+    const checklistAddBtn = document.getElementById('panel-checklist-add-btn');
+
+    checklistList?.addEventListener('change', (e) => {
+        if (e.target.type !== 'checkbox') return;
+        const item = e.target.closest('.checklist-item')?.dataset.item;
+        if (!item) return;
+        const done = e.target.checked;
+        applyPanelChecklistChange(id => updateCriterion(id, item, { done }));
+    });
+
+    checklistList?.addEventListener('click', (e) => {
+        if (!e.target.closest('.checklist-item-remove')) return;
+        const item = e.target.closest('.checklist-item')?.dataset.item;
+        if (!item) return;
+        applyPanelChecklistChange(id => deleteCriterion(id, item));
+    });
+
+    const addChecklistItem = () => {
+        const text = checklistInput?.value.trim();
+        if (!text) return;
+        checklistInput.value = '';
+        applyPanelChecklistChange(id => addCriterion(id, text));
+    };
+
+    checklistAddBtn?.addEventListener('click', addChecklistItem);
+    checklistInput?.addEventListener('keydown', (e) => {
+        if (e.key === 'Enter') {
+            // Don't submit the panel form
+            e.preventDefault();
+            addChecklistItem();
+        }
+    });
+
     // Change detection for form inputs
     const criteriaInput = document.getElementById('panel-criteria-input');
+    const descriptionInput = document.getElementById('panel-description-input');
     const priorityRadios = panelTaskForm?.querySelectorAll('input[name="panel-priority"]');
 
     criteriaInput?.addEventListener('input', updatePanelSaveButton);
+    descriptionInput?.addEventListener('input', updatePanelSaveButton);
+    document.getElementById('panel-blocked-by-input')?.addEventListener('input', updatePanelSaveButton);
+    document.getElementById('panel-parent-input')?.addEventListener('input', updatePanelSaveButton);
     priorityRadios?.forEach(radio => {
         radio.addEventListener('change', () => {
             // Hide custom priority display when a known priority is selected
@@ -3928,6 +4286,7 @@ async function handleFormSubmit(e) {
     const data = {
         title: formData.get('title'),
         acceptance_criteria: formData.get('acceptance_criteria'),
+        description: formData.get('description'),
         priority: formData.get('priority'),
         tags: modalTags.length > 0 ? modalTags : undefined,
         requires_test: requiresTestCheckbox ? requiresTestCheckbox.checked : false
@@ -3969,14 +4328,17 @@ async function handleRunTest(taskId, button) {
     }
 
     try {
-        const result = await runTest(taskId);
+        const job = await waitForTestJob(await runTest(taskId));
 
         // Reload tasks to get updated state
         await loadTasks();
 
         // Show output if there's an error
-        if (!result.results.all_passed) {
-            showOutput(result.task, result.results);
+        if (job.status === 'canceled') {
+            showNotification('Test run was canceled', 'info');
+        } else if (job.results && !job.results.all_passed) {
+            const updated = tasks.find(t => t.id === taskId);
+            if (updated) showOutput(updated, job.results);
         }
     } catch (error) {
         console.error('Failed to run test:', error);
@@ -4253,6 +4615,10 @@ async function handleDrop(e) {
         // Rollback: reload tasks from server
         await loadTasks();
 
+        if (error.blocked) {
+            showNotification(`Can't start this task yet: ${error.message}`, 'error');
+            return;
+        }
         showNotification('Failed to move task. Please try again.', 'error');
     }
 }
@@ -4314,7 +4680,10 @@ async function reorderTask(taskId, column, position) {
 
     if (!response.ok) {
         const err = await response.json();
-        throw new Error(err.error || 'Failed to reorder task');
+        const error = new Error(err.error || 'Failed to reorder task');
+        // 409: the task is waiting on unfinished blocking tasks
+        error.blocked = response.status === 409;
+        throw error;
     }
 
     return response.json();